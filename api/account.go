@@ -2,15 +2,29 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
 )
 
+// Returned when a depositor tries to use an account that isn't theirs.
+var errAccountNotOwned = errors.New("account doesn't belong to the authenticated user")
+
+// Returned when a request names one of the bank's internal accounts, like a suspense account.
+var errInternalAccount = errors.New("account is an internal account")
+
+// Returned when an account can't be deleted because its history refers to it.
+var errAccountHasHistory = errors.New("account has entries or transfers and can't be deleted")
+
 // The data type for the object being created.
 type createAccountRequest struct {
-	Owner string `json:"owner" binding:"required"`
+	// Defaults to the logged in user. Only bankers and admins can open accounts for someone else.
+	Owner string `json:"owner"`
 	// Go to gin and look up binding for more on how to be more specific. -> binding to JSON data
 	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
 }
@@ -23,6 +37,16 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		// The context should be outputted to the screen as JSON.
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.Owner == "" {
+		req.Owner = authPayload.Username
+	}
+	if req.Owner != authPayload.Username && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountNotOwned))
+		return
 	}
 
 	// Passing in params from the req body.
@@ -32,12 +56,18 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Balance:  0,
 	}
 
-	// Creating the account in the database.
-	account, err := server.store.CreateAccount(ctx, arg)
+	// Creating the account in the database, together with its audit event.
+	account, err := server.store.CreateAccountTx(ctx, db.CreateAccountTxParams{
+		CreateAccountParams: arg,
+		Actor:               authPayload.Username,
+		RequestID:           ctx.GetString(requestIDKey),
+		IP:                  ctx.ClientIP(),
+	})
 
 	// Sending JSON data and output to the client.
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse((err)))
+		return
 	}
 
 	// If there is no error, send status ok JSON to the terminal and account to the client.
@@ -57,15 +87,9 @@ func (server *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx, req.ID)
-
-	if err != nil {
-		// If the ID doesnt exist
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse((err)))
-		}
-		// General error
-		ctx.JSON(http.StatusInternalServerError, errorResponse((err)))
+	account, ok := server.getOwnedAccount(ctx, req.ID)
+	if !ok {
+		return
 	}
 
 	ctx.JSON(http.StatusOK, account)
@@ -79,6 +103,7 @@ type listAccountRequest struct {
 	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
 }
 
+// listAccount lists the user's own accounts. Bankers and admins see every account.
 func (server *Server) listAccount(ctx *gin.Context) {
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
@@ -86,15 +111,25 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		return
 	}
 
-	// We need to declare limit and offset params.ctx
-	arg := db.ListAccountsParams{
-		// The limit is the page size.
-		Limit: req.PageSize,
-		// Off set needs to be calculated.
-		Offset: (req.PageID - 1) * req.PageSize,
-	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	accounts, err := server.store.ListAccounts(ctx, arg)
+	var accounts []db.Account
+	var err error
+	if util.IsPrivilegedRole(authPayload.Role) {
+		// We need to declare limit and offset params.ctx
+		accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+			// The limit is the page size.
+			Limit: req.PageSize,
+			// Off set needs to be calculated.
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	} else {
+		accounts, err = server.store.ListAccountsByOwner(ctx, db.ListAccountsByOwnerParams{
+			Owner:  authPayload.Username,
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	}
 	// Error responses
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -105,30 +140,54 @@ func (server *Server) listAccount(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, accounts)
 }
 
+type updateAccountURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// Only the descriptive fields can be changed. Balances change through transfers and adjustments.
 type updateAccountRequest struct {
-	ID      int64 `uri:"id" binding:"required,min=1"`
-	Balance int64 `json:"balance"`
+	Nickname         string `json:"nickname" binding:"max=50"`
+	OwnerDisplayName string `json:"owner_display_name" binding:"max=100"`
 }
 
+// updateAccount changes the nickname and display name of one of the user's accounts.
 func (server *Server) updateAccount(ctx *gin.Context) {
+	var uri updateAccountURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 	var req updateAccountRequest
-	if err := ctx.ShouldBindUri(&req); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
+	if _, ok := server.getOwnedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
 	arg := db.UpdateAccountParams{
-		ID:      req.ID,
-		Balance: req.Balance,
+		ID:               uri.ID,
+		Nickname:         req.Nickname,
+		OwnerDisplayName: req.OwnerDisplayName,
 	}
-	account, err := server.store.UpdateAccount(ctx, arg)
+	account, err := server.store.UpdateAccountTx(ctx, db.UpdateAccountTxParams{
+		UpdateAccountParams: arg,
+		Actor:               authPayload.Username,
+		RequestID:           ctx.GetString(requestIDKey),
+		IP:                  ctx.ClientIP(),
+	})
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-
+		return
 	}
 
 	ctx.JSON(http.StatusOK, account)
@@ -145,14 +204,96 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
-	err := server.store.DeleteAccount(ctx, req.ID)
+	account, ok := server.getOwnedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+	if account.Kind != db.AccountKindCustomer {
+		ctx.JSON(http.StatusForbidden, errorResponse(errInternalAccount))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.store.DeleteAccountTx(ctx, db.DeleteAccountTxParams{
+		ID:        req.ID,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+
+	if err != nil {
+		// Entries, transfers and the like keep the account's history, and their
+		// foreign keys stop it from being deleted once it has any.
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+			ctx.JSON(http.StatusConflict, errorResponse(errAccountHasHistory))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"response": "status Ok"})
+}
+
+type freezeAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// setAccountFrozen returns the handler bankers use to freeze or unfreeze an account.
+func (server *Server) setAccountFrozen(frozen bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req freezeAccountRequest
+		if err := ctx.ShouldBindUri(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		account, err := server.store.SetAccountFrozenTx(ctx, db.SetAccountFrozenTxParams{
+			AccountID: req.ID,
+			Frozen:    frozen,
+			Actor:     authPayload.Username,
+			RequestID: ctx.GetString(requestIDKey),
+			IP:        ctx.ClientIP(),
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, account)
+	}
+}
 
+// getOwnedAccount loads an account the logged in user may act on: their own, or any
+// account for bankers and admins. It writes the error response itself.
+func (server *Server) getOwnedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
+		// If the ID doesnt exist
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
 		}
+		// General error
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"response": "status Ok"})
+	// The bank's internal accounts are nobody's, whatever their owner column says.
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	owned := account.Owner == authPayload.Username && account.Kind == db.AccountKindCustomer
+	if !owned && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountNotOwned))
+		return account, false
+	}
+
+	return account, true
 }
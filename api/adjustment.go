@@ -0,0 +1,109 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/jobs"
+	"github.com/techschool/simplebank/token"
+)
+
+type adjustmentURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// Amount is added to the balance, so it is negative to take money off. Zero is refused.
+type createAdjustmentRequest struct {
+	Amount     int64  `json:"amount" binding:"required"`
+	ReasonCode string `json:"reason_code" binding:"required,oneof=correction fee fee_refund goodwill chargeback write_off"`
+	Memo       string `json:"memo" binding:"max=500"`
+}
+
+// createAdjustment corrects an account's balance against the suspense account of its
+// currency, recording the entries, the reason and the banker who made it.
+func (server *Server) createAdjustment(ctx *gin.Context) {
+	var uri adjustmentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req createAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if account.Kind != db.AccountKindCustomer {
+		ctx.JSON(http.StatusForbidden, errorResponse(errInternalAccount))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	result, err := server.store.AdjustBalanceTx(ctx, db.AdjustBalanceTxParams{
+		AccountID:  uri.ID,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Memo:       req.Memo,
+		Operator:   authPayload.Username,
+		RequestID:  ctx.GetString(requestIDKey),
+		IP:         ctx.ClientIP(),
+		// Adjustments are entries made by hand, so check the chain still holds afterwards.
+		Jobs: []db.JobRequest{
+			jobs.VerifyEntryChain.New(jobs.VerifyEntryChainPayload{AccountID: uri.ID}),
+		},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type listAdjustmentsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listAdjustments lists an account's adjustments, newest first.
+func (server *Server) listAdjustments(ctx *gin.Context) {
+	var uri adjustmentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listAdjustmentsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	adjustments, err := server.store.ListAdjustmentsByAccount(ctx, db.ListAdjustmentsByAccountParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adjustments)
+}
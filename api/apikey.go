@@ -0,0 +1,231 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/apikey"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// authenticateAPIKey checks an X-API-Key header and builds a payload for the key's
+// owner, so handlers treat it like a bearer token. On failure it returns the status to answer with.
+func authenticateAPIKey(ctx *gin.Context, store db.Store, plaintext string) (*token.Payload, int, error) {
+	prefix, err := apikey.Parse(plaintext)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	key, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusUnauthorized, apikey.ErrInvalidKey
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if !apikey.Verify(plaintext, key.HashedSecret) {
+		return nil, http.StatusUnauthorized, apikey.ErrInvalidKey
+	}
+	if key.RevokedAt.Valid {
+		return nil, http.StatusUnauthorized, errors.New("api key has been revoked")
+	}
+	if key.ExpiresAt.Valid && time.Now().After(key.ExpiresAt.Time) {
+		return nil, http.StatusUnauthorized, errors.New("api key has expired")
+	}
+
+	if status, err := checkRouteScope(ctx, key.Scopes); err != nil {
+		return nil, status, err
+	}
+
+	// The key acts with its owner's current role, so a demoted owner's keys lose access too.
+	owner, err := store.GetUser(ctx, key.Owner)
+	if err != nil {
+		// A key whose owner was deleted is as good as revoked.
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, http.StatusUnauthorized, errors.New("api key owner no longer exists")
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// Bookkeeping only, so a failure here shouldn't fail the request.
+	if err := store.TouchAPIKey(ctx, key.ID); err != nil {
+		util.LoggerFromContext(ctx).WarnContext(ctx, "cannot update api key last_used_at",
+			slog.Int64("api_key_id", key.ID),
+			slog.Any("error", err),
+		)
+	}
+
+	return &token.Payload{
+		Type:      token.TypeAPIKey,
+		Username:  owner.Username,
+		Role:      owner.Role,
+		IssuedAt:  key.CreatedAt,
+		ExpiredAt: key.ExpiresAt.Time,
+	}, http.StatusOK, nil
+}
+
+// apiKeyResponse leaves out the hash. The key itself is only in createAPIKeyResponse.
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  nullTimePtr(key.ExpiresAt),
+		LastUsedAt: nullTimePtr(key.LastUsedAt),
+		RevokedAt:  nullTimePtr(key.RevokedAt),
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// nullTimePtr turns a NULL timestamp into a JSON null instead of the zero time.
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:write"`
+	// Optional. Keys without an expiry work until they are revoked.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	// Shown once. Only its hash is stored.
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+// createAPIKey gives the logged in user a new key acting on their behalf.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("expires_at must be in the future")))
+			return
+		}
+		expiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	generated, err := apikey.Generate()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	key, err := server.store.CreateAPIKeyTx(ctx, db.CreateAPIKeyTxParams{
+		CreateAPIKeyParams: db.CreateAPIKeyParams{
+			Owner:        authPayload.Username,
+			Name:         req.Name,
+			Prefix:       generated.Prefix,
+			HashedSecret: generated.Hash,
+			Scopes:       slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+			ExpiresAt:    expiresAt,
+		},
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createAPIKeyResponse{
+		Key:    generated.Plaintext,
+		APIKey: newAPIKeyResponse(key),
+	})
+}
+
+type listAPIKeysRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listAPIKeys lists the logged in user's keys, revoked ones included.
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var req listAPIKeysRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	keys, err := server.store.ListAPIKeysByOwner(ctx, db.ListAPIKeysByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		rsp[i] = newAPIKeyResponse(key)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKey stops one of the logged in user's keys from working.
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	key, err := server.store.RevokeAPIKeyTx(ctx, db.RevokeAPIKeyTxParams{
+		ID:        req.ID,
+		Owner:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("api key not found or already revoked")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(key))
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Every filter is optional. from and to are RFC 3339 times, e.g. 2024-01-31T00:00:00Z,
+// and select events created at or after from and before to.
+type listAuditEventsRequest struct {
+	Actor      string    `form:"actor"`
+	Action     string    `form:"action"`
+	EntityType string    `form:"entity_type"`
+	EntityID   string    `form:"entity_id"`
+	RequestID  string    `form:"request_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	PageID     int32     `form:"page_id" binding:"required,min=1"`
+	PageSize   int32     `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listAuditEvents lets admins search the audit log, newest events first.
+func (server *Server) listAuditEvents(ctx *gin.Context) {
+	var req listAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		Actor:       nullString(req.Actor),
+		Action:      nullString(req.Action),
+		EntityType:  nullString(req.EntityType),
+		EntityID:    nullString(req.EntityID),
+		RequestID:   nullString(req.RequestID),
+		CreatedFrom: sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		CreatedTo:   sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		Limit:       req.PageSize,
+		Offset:      (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
+
+// nullString turns an empty query parameter into a NULL, which the query treats as "any".
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/apikey"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	apiKeyHeaderKey         = "X-API-Key"
+	authorizationTypeBearer = "bearer"
+	// The key the verified token payload is stored under in the gin context.
+	authorizationPayloadKey = "authorization_payload"
+)
+
+// authMiddleware requires a valid "Authorization: Bearer <token>" or "X-API-Key"
+// header and stores the token payload in the context for the handlers.
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if key := ctx.GetHeader(apiKeyHeaderKey); key != "" {
+			payload, status, err := authenticateAPIKey(ctx, store, key)
+			if err != nil {
+				ctx.AbortWithStatusJSON(status, errorResponse(err))
+				return
+			}
+			ctx.Set(authorizationPayloadKey, payload)
+			ctx.Next()
+			return
+		}
+
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		accessToken, tokenType := "", token.TypeAccess
+
+		// Browsers can't set headers on a WebSocket handshake, so /ws also takes a
+		// ticket from POST /ws/ticket in the query string. No other route does.
+		ticket := ctx.Query(webSocketTicketQueryKey)
+		if len(authorizationHeader) == 0 && ticket != "" && ctx.FullPath() == "/ws" {
+			accessToken, tokenType = ticket, token.TypeWebSocketTicket
+		} else {
+			if len(authorizationHeader) == 0 {
+				err := errors.New("authorization header is not provided")
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			fields := strings.Fields(authorizationHeader)
+			if len(fields) < 2 {
+				err := errors.New("invalid authorization header format")
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			authorizationType := strings.ToLower(fields[0])
+			if authorizationType != authorizationTypeBearer {
+				err := fmt.Errorf("unsupported authorization type %s", authorizationType)
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			accessToken = fields[1]
+		}
+
+		payload, err := tokenMaker.VerifyToken(accessToken)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		// A TOTP challenge token or WebSocket ticket must not work as an access token,
+		// and only a ticket works as a ticket.
+		if payload.Type != tokenType {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+			return
+		}
+
+		// Tokens issued to OAuth2 clients are limited to their scopes, like API keys, and
+		// stop working as soon as the client is revoked, as introspection reports.
+		if payload.ClientID != "" {
+			if status, err := checkRouteScope(ctx, payload.Scopes); err != nil {
+				ctx.AbortWithStatusJSON(status, errorResponse(err))
+				return
+			}
+			if status, err := checkOAuthClient(ctx, store, payload.ClientID); err != nil {
+				ctx.AbortWithStatusJSON(status, errorResponse(err))
+				return
+			}
+		}
+
+		// The role in the token is the one the user had at login. Like API keys, the
+		// token acts with the user's current role, so a demoted banker or admin loses
+		// access right away instead of when the token expires.
+		user, err := store.GetUser(ctx, payload.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				err = errors.New("user no longer exists")
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		payload.Role = user.Role
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// checkOAuthClient makes sure the client a token was issued to still exists and
+// hasn't been revoked.
+func checkOAuthClient(ctx *gin.Context, store db.Store, clientID string) (int, error) {
+	client, err := store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return http.StatusUnauthorized, errors.New("oauth client no longer exists")
+		}
+		return http.StatusInternalServerError, err
+	}
+	if client.RevokedAt.Valid {
+		return http.StatusUnauthorized, errors.New("oauth client has been revoked")
+	}
+	return http.StatusOK, nil
+}
+
+// routeScopes lists the routes API keys and OAuth2 client tokens can call, keyed like
+// RATE_LIMIT_ROUTES, with the scope each one needs. Anything else, like managing keys,
+// needs a user's own bearer token.
+var routeScopes = map[string]string{
+	"GET /accounts":                       apikey.ScopeAccountsRead,
+	"GET /accounts/:id":                   apikey.ScopeAccountsRead,
+	"GET /accounts/:id/checkpoint":        apikey.ScopeAccountsRead,
+	"GET /accounts/:id/cash_transactions": apikey.ScopeAccountsRead,
+	"GET /accounts/:id/stream":            apikey.ScopeAccountsRead,
+	"GET /accounts/:id/statement":         apikey.ScopeAccountsRead,
+	"GET /accounts/:id/statements":        apikey.ScopeAccountsRead,
+	"GET /statements/:id/download":        apikey.ScopeAccountsRead,
+	"GET /ws":                             apikey.ScopeAccountsRead,
+	"POST /ws/ticket":                     apikey.ScopeAccountsRead,
+	"POST /accounts":                      apikey.ScopeAccountsWrite,
+	"PUT /accounts/:id":                   apikey.ScopeAccountsWrite,
+	"DELETE /accounts/:id":                apikey.ScopeAccountsWrite,
+	"POST /transfers":                     apikey.ScopeTransfersWrite,
+	"POST /accounts/:id/withdrawals":      apikey.ScopeTransfersWrite,
+	"GET /scheduled_transfers":            apikey.ScopeAccountsRead,
+	"GET /scheduled_transfers/:id":        apikey.ScopeAccountsRead,
+	"GET /scheduled_transfers/:id/runs":   apikey.ScopeAccountsRead,
+	"POST /scheduled_transfers":           apikey.ScopeTransfersWrite,
+	"PATCH /scheduled_transfers/:id":      apikey.ScopeTransfersWrite,
+	"DELETE /scheduled_transfers/:id":     apikey.ScopeTransfersWrite,
+	"GET /batches/:id/report":             apikey.ScopeAccountsRead,
+	"POST /batches":                       apikey.ScopeTransfersWrite,
+}
+
+// checkRouteScope makes sure the matched route is open to scoped credentials and
+// that the granted scopes include the one it needs.
+func checkRouteScope(ctx *gin.Context, scopes []string) (int, error) {
+	route := ctx.Request.Method + " " + ctx.FullPath()
+	scope, ok := routeScopes[route]
+	if !ok {
+		return http.StatusForbidden, fmt.Errorf("%s can't be called with scoped credentials", route)
+	}
+	if !slices.Contains(scopes, scope) {
+		return http.StatusForbidden, fmt.Errorf("missing the %s scope", scope)
+	}
+	return http.StatusOK, nil
+}
+
+// requireRoles only lets users with one of the given roles through. It goes after
+// authMiddleware on the route group it protects, which has already swapped the
+// token's role for the user's current one.
+func requireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !slices.Contains(roles, payload.Role) {
+			err := fmt.Errorf("role %s is not allowed to do this", payload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// keyByUser counts requests per authenticated user, so one user can't dodge the
+// limit by switching IPs. It falls back to the client IP without a token payload.
+func keyByUser(ctx *gin.Context) string {
+	if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+		return "user:" + payload.(*token.Payload).Username
+	}
+	return keyByClientIP(ctx)
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/batch"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// The largest payment file that can be uploaded, plenty for batch.MaxItems lines.
+const maxBatchFileSize = 5 << 20
+
+var (
+	// Returned when a user asks for someone else's batch.
+	errBatchNotOwned = errors.New("batch doesn't belong to the authenticated user")
+	errInvalidBatch  = errors.New("the payment file has invalid lines, nothing was stored")
+)
+
+// The file comes as the multipart field file, next to these fields. A batch with any
+// amount above TOTP_TRANSFER_THRESHOLD needs a code, once for the whole batch.
+type createBatchRequest struct {
+	Mode     string `form:"mode" binding:"required,oneof=all_or_nothing best_effort"`
+	TOTPCode string `form:"totp_code" binding:"omitempty,numeric,len=6"`
+}
+
+// createBatch reads a CSV or pain.001 payment file, checks every payment in it, and
+// stores them as a batch that a background job then runs. If any payment is wrong,
+// nothing is stored and every wrong line is listed, so the file can be fixed in one go.
+func (server *Server) createBatch(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBatchFileSize)
+
+	var req createBatchRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	data, err := readBatchFile(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	format, items, lineErrors, err := batch.Parse(data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Lines that can't be read aren't checked against the accounts, but the rest are,
+	// so every mistake in the file is reported at once.
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	invalid, err := batch.Validate(ctx, server.store, authPayload.Username, items)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	lineErrors = append(lineErrors, invalid...)
+	if len(lineErrors) > 0 {
+		slices.SortStableFunc(lineErrors, func(a, b batch.LineError) int {
+			return cmp.Compare(a.Line, b.Line)
+		})
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":       errInvalidBatch.Error(),
+			"line_errors": lineErrors,
+		})
+		return
+	}
+
+	if threshold := server.config.TOTPTransferThreshold; threshold > 0 {
+		if slices.ContainsFunc(items, func(item batch.Item) bool { return item.Amount > threshold }) {
+			if !server.checkTransferTOTP(ctx, authPayload.Username, req.TOTPCode) {
+				return
+			}
+		}
+	}
+
+	arg := db.CreateBatchTxParams{
+		CreateBatchParams: db.CreateBatchParams{
+			Owner:  authPayload.Username,
+			Mode:   req.Mode,
+			Format: string(format),
+		},
+		Items:     make([]db.CreateBatchItemParams, len(items)),
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+		Job: func(b db.Batch) db.JobRequest {
+			return batch.Execute.New(batch.ExecutePayload{BatchID: b.ID})
+		},
+	}
+	for i, item := range items {
+		arg.Items[i] = db.CreateBatchItemParams{
+			Line:          item.Line,
+			FromAccountID: item.FromAccountID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			Currency:      item.Currency,
+			Reference:     item.Reference,
+		}
+	}
+
+	result, err := server.store.CreateBatchTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newBatchResponse(result.Batch))
+}
+
+// readBatchFile reads the uploaded file whole. Files are small enough for that, and
+// both formats need all of it before anything can be checked.
+func readBatchFile(ctx *gin.Context) ([]byte, error) {
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("file: %w", err)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// batchResponse is a batch with its times as JSON times.
+type batchResponse struct {
+	ID          int64      `json:"id"`
+	Owner       string     `json:"owner"`
+	Mode        string     `json:"mode"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	ItemCount   int32      `json:"item_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func newBatchResponse(b db.Batch) batchResponse {
+	response := batchResponse{
+		ID:        b.ID,
+		Owner:     b.Owner,
+		Mode:      b.Mode,
+		Format:    b.Format,
+		Status:    b.Status,
+		ItemCount: b.ItemCount,
+		CreatedAt: b.CreatedAt,
+	}
+	if b.CompletedAt.Valid {
+		response.CompletedAt = &b.CompletedAt.Time
+	}
+	return response
+}
+
+type batchItemResponse struct {
+	Line          int32  `json:"line"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Reference     string `json:"reference"`
+	Status        string `json:"status"`
+	TransferID    *int64 `json:"transfer_id"`
+	Error         string `json:"error,omitempty"`
+}
+
+// batchReportResponse is how a batch went: how many items ended up in each status, how
+// much was paid and not paid in each currency, and every item in the order of the file.
+type batchReportResponse struct {
+	Batch        batchResponse       `json:"batch"`
+	StatusCounts map[string]int      `json:"status_counts"`
+	AmountPaid   map[string]int64    `json:"amount_paid"`
+	AmountUnpaid map[string]int64    `json:"amount_unpaid"`
+	Items        []batchItemResponse `json:"items"`
+}
+
+type getBatchRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getBatchReport reports on a batch, while it is pending or after it has run. Bankers
+// and admins can see anyone's.
+func (server *Server) getBatchReport(ctx *gin.Context) {
+	var req getBatchRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	b, err := server.store.GetBatch(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if b.Owner != authPayload.Username && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errBatchNotOwned))
+		return
+	}
+
+	items, err := server.store.ListBatchItems(ctx, b.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	report := batchReportResponse{
+		Batch: newBatchResponse(b),
+		StatusCounts: map[string]int{
+			db.BatchItemPending:   0,
+			db.BatchItemSucceeded: 0,
+			db.BatchItemFailed:    0,
+			db.BatchItemSkipped:   0,
+		},
+		AmountPaid:   map[string]int64{},
+		AmountUnpaid: map[string]int64{},
+		Items:        make([]batchItemResponse, len(items)),
+	}
+	for i, item := range items {
+		report.StatusCounts[item.Status]++
+		if item.Status == db.BatchItemSucceeded {
+			report.AmountPaid[item.Currency] += item.Amount
+		} else {
+			report.AmountUnpaid[item.Currency] += item.Amount
+		}
+
+		report.Items[i] = batchItemResponse{
+			Line:          item.Line,
+			FromAccountID: item.FromAccountID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			Currency:      item.Currency,
+			Reference:     item.Reference,
+			Status:        item.Status,
+			Error:         item.Error,
+		}
+		if item.TransferID.Valid {
+			report.Items[i].TransferID = &item.TransferID.Int64
+		}
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
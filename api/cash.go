@@ -0,0 +1,163 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+type cashURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// The currency must match the account's, like for transfers.
+type depositRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+}
+
+// createDeposit pays cash into an account. Only bankers take cash in.
+func (server *Server) createDeposit(ctx *gin.Context) {
+	var uri cashURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req depositRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, uri.ID, req.Currency); !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	result, err := server.store.DepositTx(ctx, db.DepositTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		writeCashError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Amounts above TOTP_TRANSFER_THRESHOLD need a code, the same as transfers.
+type withdrawalRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,oneof=USD EUR"`
+	TOTPCode string `json:"totp_code" binding:"omitempty,numeric,len=6"`
+}
+
+// createWithdrawal pays cash out of one of the user's accounts, within
+// WITHDRAWAL_MAX_AMOUNT and WITHDRAWAL_DAILY_LIMIT. Bankers can pay out of any account.
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	var uri cashURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req withdrawalRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountNotOwned))
+		return
+	}
+
+	// A banker paying out at the counter has checked who they are paying already.
+	if account.Owner == authPayload.Username {
+		if threshold := server.config.TOTPTransferThreshold; threshold > 0 && req.Amount > threshold {
+			if !server.checkTransferTOTP(ctx, authPayload.Username, req.TOTPCode) {
+				return
+			}
+		}
+	}
+
+	result, err := server.store.WithdrawTx(ctx, db.WithdrawTxParams{
+		AccountID:  uri.ID,
+		Amount:     req.Amount,
+		MaxAmount:  server.config.WithdrawalMaxAmount,
+		DailyLimit: server.config.WithdrawalDailyLimit,
+		Actor:      authPayload.Username,
+		RequestID:  ctx.GetString(requestIDKey),
+		IP:         ctx.ClientIP(),
+	})
+	if err != nil {
+		writeCashError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// writeCashError answers with the status that fits a DepositTx or WithdrawTx error.
+func writeCashError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, db.ErrAccountFrozen), errors.Is(err, db.ErrWithdrawalLimitExceeded):
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	case errors.Is(err, db.ErrInsufficientFunds):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
+
+type listCashTransactionsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listCashTransactions lists the deposits and withdrawals of one of the user's accounts, newest first.
+func (server *Server) listCashTransactions(ctx *gin.Context) {
+	var uri cashURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listCashTransactionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	transactions, err := server.store.ListCashTransactionsByAccount(ctx, db.ListCashTransactionsByAccountParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transactions)
+}
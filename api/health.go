@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/db/migration"
+)
+
+// How long the readiness checks get before the instance is reported as not ready.
+const readinessTimeout = 2 * time.Second
+
+// healthz is the liveness probe. It only says the process is up and serving,
+// so it never touches the database.
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz is the readiness probe. The instance is ready when it is not shutting down,
+// the database answers a ping and the schema is at the version this build expects.
+func (server *Server) readyz(ctx *gin.Context) {
+	if server.shuttingDown.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := server.store.Ping(checkCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "database unreachable", "error": err.Error()})
+		return
+	}
+
+	if err := server.checkMigrationVersion(checkCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "schema not ready", "error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// checkMigrationVersion fails if the database is dirty or behind the migrations bundled in this build.
+func (server *Server) checkMigrationVersion(ctx context.Context) error {
+	expected, err := migration.LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	current, err := server.store.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current.Dirty {
+		return fmt.Errorf("migration version %d is dirty", current.Version)
+	}
+	if current.Version < expected {
+		return fmt.Errorf("migration version %d is behind expected version %d", current.Version, expected)
+	}
+	return nil
+}
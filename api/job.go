@@ -0,0 +1,67 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Status defaults to dead, the jobs that need someone to look at them.
+type listJobsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=queued running succeeded dead"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listJobs lets admins look through the background job queue, newest jobs first.
+func (server *Server) listJobs(ctx *gin.Context) {
+	var req listJobsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.Status == "" {
+		req.Status = db.JobDead
+	}
+
+	jobs, err := server.store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, jobs)
+}
+
+type requeueJobRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// requeueJob gives a dead job another full set of attempts, e.g. once the bug that
+// killed it has been fixed.
+func (server *Server) requeueJob(ctx *gin.Context) {
+	var req requeueJobRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	job, err := server.store.RequeueDeadJob(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("job not found or not dead")))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
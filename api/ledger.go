@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/ledger"
+)
+
+// Returned instead of a checkpoint when the account's entries fail verification.
+var errEntryChainBroken = errors.New("entry hash chain is broken")
+
+type accountChainRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// verifyAccountChain walks the account's entry hash chain and reports the first broken link.
+func (server *Server) verifyAccountChain(ctx *gin.Context) {
+	var req accountChainRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, req.ID); !ok {
+		return
+	}
+
+	result, err := server.store.VerifyEntryChain(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// getAccountCheckpoint signs the current head of the account's chain. The chain is
+// verified first, so a checkpoint is never issued for entries that were tampered with.
+func (server *Server) getAccountCheckpoint(ctx *gin.Context) {
+	var req accountChainRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, req.ID); !ok {
+		return
+	}
+
+	result, err := server.store.VerifyEntryChain(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !result.Valid {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":        errEntryChainBroken.Error(),
+			"verification": result,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.checkpointSigner.Sign(ledger.Checkpoint{
+		AccountID:  req.ID,
+		EntryID:    result.HeadEntryID,
+		Hash:       result.HeadHash,
+		EntryCount: result.EntriesChecked,
+		SignedAt:   time.Now(),
+	}))
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// The header used to pass a request ID in and echo it back out.
+	requestIDHeader = "X-Request-ID"
+	// The key the request ID is stored under in the gin context.
+	requestIDKey = "request_id"
+	// Incoming IDs longer than this are ignored and a new one is generated.
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware reuses the caller's X-Request-ID or generates a new one,
+// then stores it in the request context and sends it back on the response.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeader, requestID)
+
+		// The request context is what gets passed down to the store, so the ID
+		// and a logger tagged with it (and the trace ID, if traced) are attached there too.
+		logger := slog.Default().With(slog.String("request_id", requestID))
+		if spanCtx := trace.SpanContextFromContext(ctx.Request.Context()); spanCtx.HasTraceID() {
+			logger = logger.With(slog.String("trace_id", spanCtx.TraceID().String()))
+		}
+		reqCtx := util.ContextWithRequestID(ctx.Request.Context(), requestID)
+		reqCtx = util.ContextWithLogger(reqCtx, logger)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+	}
+}
+
+// loggerMiddleware writes one structured log line per request once the handler has finished.
+func loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		path := ctx.Request.URL.Path
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.Int("size", ctx.Writer.Size()),
+		}
+		// Set by authMiddleware on authenticated routes.
+		if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+			attrs = append(attrs, slog.String("user", payload.(*token.Payload).Username))
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", ctx.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		logger := util.LoggerFromContext(ctx.Request.Context())
+		logger.LogAttrs(ctx.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// newRequestID returns 16 random bytes hex encoded.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand should never fail, but a timestamp is better than no ID.
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(b)
+}
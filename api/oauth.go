@@ -0,0 +1,372 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// Error codes from RFC 6749 section 5.2.
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthInvalidScope         = "invalid_scope"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthServerError          = "server_error"
+)
+
+// The only grant a client can use. There are no end users redirecting through here.
+const grantTypeClientCredentials = "client_credentials"
+
+// oauthError answers in the RFC 6749 error format instead of the usual {"error": "..."}.
+func oauthError(ctx *gin.Context, status int, code string, description string) {
+	if status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Basic realm="simplebank"`)
+	}
+	ctx.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// authenticateClient checks the client's credentials, sent either with HTTP Basic
+// auth (client_secret_basic) or as form fields (client_secret_post). It writes the
+// error response itself, so the handler only has to return when it is false.
+func (server *Server) authenticateClient(ctx *gin.Context) (db.OauthClient, bool) {
+	clientID, clientSecret, ok := ctx.Request.BasicAuth()
+	if ok {
+		// RFC 6749 section 2.3.1: both parts are form encoded before going into the header.
+		var errID, errSecret error
+		clientID, errID = url.QueryUnescape(clientID)
+		clientSecret, errSecret = url.QueryUnescape(clientSecret)
+		if errID != nil || errSecret != nil {
+			oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, "malformed client credentials")
+			return db.OauthClient{}, false
+		}
+	} else {
+		clientID = ctx.PostForm("client_id")
+		clientSecret = ctx.PostForm("client_secret")
+	}
+
+	if clientID == "" || clientSecret == "" {
+		oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, "client authentication is required")
+		return db.OauthClient{}, false
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Same work as a wrong secret, so unknown client IDs can't be told apart by timing.
+			util.CheckPassword(clientSecret, dummyPasswordHash)
+			oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, "unknown client or wrong secret")
+			return client, false
+		}
+		oauthError(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+		return client, false
+	}
+
+	if err := util.CheckPassword(clientSecret, client.HashedSecret); err != nil {
+		oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, "unknown client or wrong secret")
+		return client, false
+	}
+	if client.RevokedAt.Valid {
+		oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, "client has been revoked")
+		return client, false
+	}
+
+	return client, true
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// oauthToken implements the client_credentials grant (RFC 6749 section 4.4). The
+// token comes from the same token maker as user logins, acts as the client's owner
+// and is limited to the granted scopes.
+func (server *Server) oauthToken(ctx *gin.Context) {
+	// Tokens must never be cached (RFC 6749 section 5.1).
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	grantType := ctx.PostForm("grant_type")
+	if grantType == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, "grant_type is required")
+		return
+	}
+	if grantType != grantTypeClientCredentials {
+		oauthError(ctx, http.StatusBadRequest, oauthUnsupportedGrantType, "only client_credentials is supported")
+		return
+	}
+
+	client, ok := server.authenticateClient(ctx)
+	if !ok {
+		return
+	}
+
+	// No scope asks for everything the client is registered for.
+	scopes := client.Scopes
+	if requested := strings.Fields(ctx.PostForm("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(client.Scopes, scope) {
+				oauthError(ctx, http.StatusBadRequest, oauthInvalidScope, "scope "+scope+" is not allowed for this client")
+				return
+			}
+		}
+		scopes = slices.Compact(slices.Sorted(slices.Values(requested)))
+	}
+
+	owner, err := server.store.GetUser(ctx, client.Owner)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+		return
+	}
+
+	accessToken, payload, err := server.tokenMaker.CreateToken(
+		owner.Username,
+		owner.Role,
+		server.config.OAuthAccessTokenDuration,
+		token.WithClient(client.ClientID, scopes),
+	)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(payload.ExpiredAt).Round(time.Second).Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// introspectionResponse follows RFC 7662 section 2.2. Only Active is set for a token
+// that isn't active, so nothing is leaked about it.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// oauthIntrospect tells a registered client whether a token is active (RFC 7662).
+// Tokens are not stored, so a client's tokens only go inactive early when the client is revoked.
+func (server *Server) oauthIntrospect(ctx *gin.Context) {
+	if _, ok := server.authenticateClient(ctx); !ok {
+		return
+	}
+
+	tokenString := ctx.PostForm("token")
+	if tokenString == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(tokenString)
+	if err != nil || payload.Type != token.TypeAccess {
+		ctx.JSON(http.StatusOK, introspectionResponse{Active: false})
+		return
+	}
+
+	if payload.ClientID != "" {
+		client, err := server.store.GetOAuthClient(ctx, payload.ClientID)
+		if err != nil && err != sql.ErrNoRows {
+			oauthError(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+			return
+		}
+		if err == sql.ErrNoRows || client.RevokedAt.Valid {
+			ctx.JSON(http.StatusOK, introspectionResponse{Active: false})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(payload.Scopes, " "),
+		ClientID:  payload.ClientID,
+		Username:  payload.Username,
+		TokenType: "Bearer",
+		Exp:       payload.ExpiredAt.Unix(),
+		Iat:       payload.IssuedAt.Unix(),
+		Sub:       payload.Username,
+		Jti:       payload.ID.String(),
+	})
+}
+
+// oauthClientResponse leaves out the secret hash.
+type oauthClientResponse struct {
+	ClientID  string     `json:"client_id"`
+	Name      string     `json:"name"`
+	Owner     string     `json:"owner"`
+	Scopes    []string   `json:"scopes"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:  client.ClientID,
+		Name:      client.Name,
+		Owner:     client.Owner,
+		Scopes:    client.Scopes,
+		RevokedAt: nullTimePtr(client.RevokedAt),
+		CreatedAt: client.CreatedAt,
+	}
+}
+
+type createOAuthClientRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// The user the client acts as.
+	Owner  string   `json:"owner" binding:"required,alphanum"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:write"`
+}
+
+type createOAuthClientResponse struct {
+	// Shown once. Only its hash is stored.
+	ClientSecret string              `json:"client_secret"`
+	Client       oauthClientResponse `json:"client"`
+}
+
+// createOAuthClient lets an admin register a partner's client.
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, err := server.store.GetUser(ctx, req.Owner); err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	clientSecret, err := randomHex(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	hashedSecret, err := util.HashPassword(clientSecret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	client, err := server.store.CreateOAuthClientTx(ctx, db.CreateOAuthClientTxParams{
+		CreateOAuthClientParams: db.CreateOAuthClientParams{
+			ClientID:     "client_" + clientID,
+			Name:         req.Name,
+			Owner:        req.Owner,
+			HashedSecret: hashedSecret,
+			Scopes:       slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		},
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createOAuthClientResponse{
+		ClientSecret: clientSecret,
+		Client:       newOAuthClientResponse(client),
+	})
+}
+
+type listOAuthClientsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listOAuthClients(ctx *gin.Context) {
+	var req listOAuthClientsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	clients, err := server.store.ListOAuthClients(ctx, db.ListOAuthClientsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		rsp[i] = newOAuthClientResponse(client)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeOAuthClientRequest struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// revokeOAuthClient stops a client from getting new tokens, and its existing tokens
+// from passing introspection.
+func (server *Server) revokeOAuthClient(ctx *gin.Context) {
+	var req revokeOAuthClientRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	client, err := server.store.RevokeOAuthClientTx(ctx, db.RevokeOAuthClientTxParams{
+		ClientID:  req.ClientID,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOAuthClientResponse(client))
+}
+
+// randomHex returns size random bytes hex encoded.
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/ratelimit"
+	"github.com/techschool/simplebank/util"
+)
+
+// rateLimitKeyFunc picks who a request is counted against.
+type rateLimitKeyFunc func(ctx *gin.Context) string
+
+// keyByClientIP counts requests per client IP address.
+func keyByClientIP(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// rateLimiter applies a default limit plus per-route overrides, keyed per client.
+type rateLimiter struct {
+	backend      ratelimit.Backend
+	defaultLimit ratelimit.Limit
+	// Keyed by "<METHOD> <route template>", e.g. "POST /transfers".
+	routeLimits map[string]ratelimit.Limit
+}
+
+// newRateLimiter builds the limiter from the RATE_LIMIT_* configs.
+func newRateLimiter(config util.Config, store db.Store) (*rateLimiter, error) {
+	var backend ratelimit.Backend
+	switch config.RateLimitBackend {
+	case "", "memory":
+		backend = ratelimit.NewMemoryBackend()
+	case "postgres":
+		backend = ratelimit.NewPostgresBackend(store)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", config.RateLimitBackend)
+	}
+
+	defaultLimit, err := ratelimit.ParseLimit(config.RateLimitDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	routeLimits, err := ratelimit.ParseRouteLimits(config.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rateLimiter{
+		backend:      backend,
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+	}, nil
+}
+
+// middleware rejects requests over the limit with 429 Too Many Requests. Every
+// response gets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (limiter *rateLimiter) middleware(keyFunc rateLimitKeyFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		limit, ok := limiter.routeLimits[route]
+		if !ok {
+			limit = limiter.defaultLimit
+		}
+
+		// Each route has its own bucket per client.
+		key := route + "|" + keyFunc(ctx)
+
+		result, err := limiter.backend.Allow(ctx, key, limit)
+		if err != nil {
+			// Fail open: a broken limiter backend should not take the API down with it.
+			util.LoggerFromContext(ctx).ErrorContext(ctx, "rate limiter failed", slog.Any("error", err))
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(errors.New("rate limit exceeded")))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ceilSeconds rounds up so clients never retry too early.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/schedule"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// Returned when a user asks for someone else's scheduled transfer.
+var errScheduledTransferNotOwned = errors.New("scheduled transfer doesn't belong to the authenticated user")
+
+// Schedule is a 5 field cron expression ("0 9 1 * *") or an RRULE
+// ("FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9"), in UTC. StartAt defaults to now. Amounts above
+// TOTP_TRANSFER_THRESHOLD need a code when the schedule is set up, not on every run.
+type createScheduledTransferRequest struct {
+	FromAccountID int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64      `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64      `json:"amount" binding:"required,gt=0"`
+	Currency      string     `json:"currency" binding:"required,oneof=USD EUR"`
+	Schedule      string     `json:"schedule" binding:"required,max=200"`
+	StartAt       *time.Time `json:"start_at"`
+	EndAt         *time.Time `json:"end_at"`
+	TOTPCode      string     `json:"totp_code" binding:"omitempty,numeric,len=6"`
+}
+
+// createScheduledTransfer sets up a recurring transfer out of one of the user's accounts.
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	var endAt sql.NullTime
+	if req.EndAt != nil {
+		if !req.EndAt.After(startAt) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("end_at must be after start_at")))
+			return
+		}
+		endAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+	if _, err := schedule.Parse(req.Schedule, startAt); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// Only the owner can set up transfers out of an account, like one-off transfers.
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountNotOwned))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	if threshold := server.config.TOTPTransferThreshold; threshold > 0 && req.Amount > threshold {
+		if !server.checkTransferTOTP(ctx, authPayload.Username, req.TOTPCode) {
+			return
+		}
+	}
+
+	scheduled, err := server.store.CreateScheduledTransferTx(ctx, db.CreateScheduledTransferTxParams{
+		CreateScheduledTransferParams: db.CreateScheduledTransferParams{
+			Owner:         authPayload.Username,
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+			Schedule:      req.Schedule,
+			StartAt:       startAt,
+			EndAt:         endAt,
+		},
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		writeScheduledTransferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransfers lists the logged in user's scheduled transfers, cancelled ones included.
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	scheduled, err := server.store.ListScheduledTransfersByOwner(ctx, db.ListScheduledTransfersByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type scheduledTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, ok := server.getOwnedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// Fields left out stay as they are. Status pauses (paused) or resumes (active) the
+// schedule. Raising the amount above TOTP_TRANSFER_THRESHOLD needs a code.
+type updateScheduledTransferRequest struct {
+	Amount   *int64     `json:"amount" binding:"omitempty,gt=0"`
+	Schedule *string    `json:"schedule" binding:"omitempty,max=200"`
+	EndAt    *time.Time `json:"end_at"`
+	Status   *string    `json:"status" binding:"omitempty,oneof=active paused"`
+	TOTPCode string     `json:"totp_code" binding:"omitempty,numeric,len=6"`
+}
+
+// updateScheduledTransfer changes, pauses or resumes one of the user's scheduled transfers.
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, ok := server.getOwnedScheduledTransfer(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateScheduledTransferTxParams{
+		ID:        uri.ID,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	}
+
+	if req.Amount != nil {
+		threshold := server.config.TOTPTransferThreshold
+		if threshold > 0 && *req.Amount > threshold && *req.Amount > scheduled.Amount {
+			if !server.checkTransferTOTP(ctx, authPayload.Username, req.TOTPCode) {
+				return
+			}
+		}
+		arg.Amount = sql.NullInt64{Int64: *req.Amount, Valid: true}
+	}
+	if req.Schedule != nil {
+		if _, err := schedule.Parse(*req.Schedule, scheduled.StartAt); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Schedule = sql.NullString{String: *req.Schedule, Valid: true}
+	}
+	if req.EndAt != nil {
+		arg.EndAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+	if req.Status != nil {
+		arg.Status = sql.NullString{String: *req.Status, Valid: true}
+	}
+
+	updated, err := server.store.UpdateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		writeScheduledTransferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// cancelScheduledTransfer stops one of the user's scheduled transfers for good. Its
+// runs are kept.
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	scheduled, err := server.store.UpdateScheduledTransferTx(ctx, db.UpdateScheduledTransferTxParams{
+		ID:        uri.ID,
+		Status:    sql.NullString{String: db.ScheduledTransferCancelled, Valid: true},
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		writeScheduledTransferError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransferRuns lists the outcome of each run of a scheduled transfer, newest first.
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedScheduledTransfer(ctx, uri.ID); !ok {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: uri.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+// getOwnedScheduledTransfer loads a scheduled transfer the logged in user may see: their
+// own, or any for bankers and admins. It writes the error response itself.
+func (server *Server) getOwnedScheduledTransfer(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduled, err := server.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errScheduledTransferNotOwned))
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+// writeScheduledTransferError answers with the status that fits a scheduled transfer Tx error.
+func writeScheduledTransferError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, schedule.ErrNeverRuns):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	case errors.Is(err, db.ErrScheduledTransferClosed):
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -1,42 +1,294 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/techschool/simplebank/blob"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/ledger"
+	"github.com/techschool/simplebank/metrics"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // HTTP service data structure.
 type Server struct {
+	config util.Config
 	// This is bases on it on the db connect made in the store.go
 	store db.Store
+	// Creates the access tokens handed out at login and verifies them on each request.
+	tokenMaker token.Maker
+	// Signs the ledger checkpoints handed to auditors.
+	checkpointSigner *ledger.Signer
 	// Router and handler standard method in Gin.
 	router *gin.Engine
+	// The underlying HTTP server, kept so it can be shut down gracefully.
+	httpServer *http.Server
+	// Set once Shutdown starts so /readyz takes the instance out of rotation.
+	shuttingDown atomic.Bool
+	// Closed when Shutdown starts, so long lived streams end instead of holding it up.
+	closing chan struct{}
+	// Wakes the account streams when entries are posted. Streams are off without it.
+	entryStream EntrySubscriber
+	// Pushes events to WebSocket clients. /ws is off without it.
+	notificationHub NotificationHub
+	// Upgrades /ws requests from allowed origins.
+	webSocketUpgrader *websocket.Upgrader
+	// Where the monthly statements are kept. Downloading them is off without it.
+	statementStorage blob.Store
+}
+
+// ServerOption configures optional parts of the Server.
+type ServerOption func(*Server)
+
+// EntrySubscriber tells a caller when entries were posted to an account, see stream.Listener.
+type EntrySubscriber interface {
+	Subscribe(accountID int64) (<-chan struct{}, func())
+}
+
+// WithEntryStream turns on GET /accounts/:id/stream.
+func WithEntryStream(subscriber EntrySubscriber) ServerOption {
+	return func(server *Server) {
+		server.entryStream = subscriber
+	}
 }
 
 // New server creates a new instance of a server object where The
 // routing and HTTP verbs are defined.
-func NewServer(store db.Store) *Server {
+func NewServer(config util.Config, store db.Store, opts ...ServerOption) (*Server, error) {
+	tokenMaker, err := token.NewJWTMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	checkpointSigner, err := ledger.NewSigner(config.LedgerSigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create checkpoint signer: %w", err)
+	}
+
 	// store is the input.
-	server := &Server{store: store}
-	router := gin.Default()
+	server := &Server{
+		config:            config,
+		store:             store,
+		tokenMaker:        tokenMaker,
+		checkpointSigner:  checkpointSigner,
+		closing:           make(chan struct{}),
+		webSocketUpgrader: newWebSocketUpgrader(config.WebSocketAllowedOrigins),
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	limiter, err := newRateLimiter(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	// gin.New is used instead of gin.Default so the unstructured gin logger is
+	// replaced by our own slog based one.
+	router := gin.New()
+	// Lets handlers pass the gin context straight to the store while still
+	// carrying the request ID and logger from the request context.
+	router.ContextWithFallback = true
+	// otelgin goes first so the request span (continued from any incoming traceparent
+	// header) is in the context before the logger picks up the trace ID.
+	router.Use(
+		otelgin.Middleware(config.OTelServiceName, otelgin.WithFilter(skipTracing)),
+		requestIDMiddleware(),
+		loggerMiddleware(),
+		metrics.GinMiddleware(),
+		gin.Recovery(),
+	)
+
+	// Prometheus scrape endpoint.
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Liveness and readiness probes.
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+
+	// Everything below the probes is rate limited per client IP.
+	apiRoutes := router.Group("/", limiter.middleware(keyByClientIP))
+
+	apiRoutes.POST("/users", server.createUser)
+
+	apiRoutes.POST("/users/login", server.loginUser)
+
+	// Second login step for users with TOTP enabled. It takes the challenge token from /users/login.
+	apiRoutes.POST("/users/login/totp", server.loginTOTP)
+
+	// OAuth2 endpoints for registered clients. They authenticate with their client credentials.
+	apiRoutes.POST("/oauth/token", server.oauthToken)
+
+	apiRoutes.POST("/oauth/introspect", server.oauthIntrospect)
+
+	// Routes below need an access token, or an API key or client token with the scope
+	// listed in routeScopes. They are also limited per user, on top of per IP.
+	authRoutes := apiRoutes.Group("/", authMiddleware(server.tokenMaker, server.store), limiter.middleware(keyByUser))
+
+	// Depositors can only use their own accounts. Bankers and admins skip the owner check.
+	authRoutes.POST("/accounts", server.createAccount)
+
+	authRoutes.GET("/accounts/:id", server.getAccount)
+
+	authRoutes.GET("/accounts", server.listAccount)
+
+	authRoutes.PUT("/accounts/:id", server.updateAccount)
+
+	authRoutes.DELETE("/accounts/:id", server.deleteAccount)
+
+	authRoutes.POST("/accounts/:id/withdrawals", server.createWithdrawal)
+
+	authRoutes.GET("/accounts/:id/cash_transactions", server.listCashTransactions)
+
+	// A signed copy of the head of the account's entry hash chain.
+	authRoutes.GET("/accounts/:id/checkpoint", server.getAccountCheckpoint)
+
+	authRoutes.GET("/accounts/:id/stream", server.streamAccount)
+
+	// ?from=&to=&format=csv|jsonl|pdf
+	authRoutes.GET("/accounts/:id/statement", server.getStatement)
+
+	// The monthly statements kept in blob storage.
+	authRoutes.GET("/accounts/:id/statements", server.listStatements)
+
+	authRoutes.GET("/statements/:id/download", server.downloadStatement)
+
+	// Browsers open /ws with a ticket, see createWebSocketTicket.
+	authRoutes.POST("/ws/ticket", server.createWebSocketTicket)
+
+	authRoutes.GET("/ws", server.serveWebSocket)
+
+	authRoutes.POST("/transfers", server.createTransfer)
+
+	// Standing orders, run by the scheduler worker.
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
 
-	router.POST("/accounts", server.createAccount)
+	authRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
 
-	router.GET("/accounts/:id", server.getAccount)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
 
-	router.GET("/accounts", server.listAccount)
+	authRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
 
-	router.PUT("/accounts/:id", server.updateAccount)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.cancelScheduledTransfer)
 
-	router.DELETE("/accounts/:id", server.deleteAccount)
+	authRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+
+	// Bulk payment files, run by a job.
+	authRoutes.POST("/batches", server.createBatch)
+
+	authRoutes.GET("/batches/:id/report", server.getBatchReport)
+
+	authRoutes.POST("/webhooks", server.createWebhook)
+
+	authRoutes.GET("/webhooks", server.listWebhooks)
+
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhook)
+
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+
+	authRoutes.GET("/webhook_deliveries/:id", server.getWebhookDelivery)
+
+	authRoutes.POST("/webhook_deliveries/:id/replay", server.replayWebhookDelivery)
+
+	authRoutes.POST("/users/totp/enroll", server.enrollTOTP)
+
+	authRoutes.POST("/users/totp/confirm", server.confirmTOTP)
+
+	authRoutes.POST("/api_keys", server.createAPIKey)
+
+	authRoutes.GET("/api_keys", server.listAPIKeys)
+
+	authRoutes.DELETE("/api_keys/:id", server.revokeAPIKey)
+
+	// Bankers (and admins) can adjust balances and freeze any account.
+	bankerRoutes := authRoutes.Group("/", requireRoles(util.BankerRole, util.AdminRole))
+
+	bankerRoutes.POST("/accounts/:id/adjustments", server.createAdjustment)
+
+	bankerRoutes.GET("/accounts/:id/adjustments", server.listAdjustments)
+
+	bankerRoutes.POST("/accounts/:id/deposits", server.createDeposit)
+
+	bankerRoutes.POST("/accounts/:id/freeze", server.setAccountFrozen(true))
+
+	bankerRoutes.POST("/accounts/:id/unfreeze", server.setAccountFrozen(false))
+
+	bankerRoutes.GET("/accounts/:id/verify", server.verifyAccountChain)
+
+	// Only admins manage users.
+	adminRoutes := authRoutes.Group("/", requireRoles(util.AdminRole))
+
+	adminRoutes.GET("/users", server.listUsers)
+
+	adminRoutes.PUT("/users/:username/role", server.updateUserRole)
+
+	adminRoutes.POST("/users/:username/unlock", server.unlockUser)
+
+	adminRoutes.GET("/audit", server.listAuditEvents)
+
+	adminRoutes.GET("/jobs", server.listJobs)
+
+	adminRoutes.POST("/jobs/:id/requeue", server.requeueJob)
+
+	adminRoutes.POST("/oauth/clients", server.createOAuthClient)
+
+	adminRoutes.GET("/oauth/clients", server.listOAuthClients)
+
+	adminRoutes.DELETE("/oauth/clients/:client_id", server.revokeOAuthClient)
 
 	server.router = router
-	return server
+	// Made here rather than in Start, so Shutdown, called from another goroutine, never
+	// sees it half set, and a Shutdown that comes before Start still stops it.
+	server.httpServer = &http.Server{
+		Handler:           router,
+		ReadTimeout:       config.HTTPReadTimeout,
+		ReadHeaderTimeout: config.HTTPReadTimeout,
+		WriteTimeout:      config.HTTPWriteTimeout,
+		IdleTimeout:       config.HTTPIdleTimeout,
+	}
+	return server, nil
 }
 
-// Start runs the HTTP server on a specefied address.
+// Start runs the HTTP server on a specefied address. It blocks until the server
+// stops and returns nil when that was caused by Shutdown, even one made before Start.
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	err = server.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests,
+// such as transfers, to finish or for ctx to expire, whichever comes first.
+func (server *Server) Shutdown(ctx context.Context) error {
+	if server.shuttingDown.CompareAndSwap(false, true) {
+		close(server.closing)
+	}
+	return server.httpServer.Shutdown(ctx)
+}
+
+// skipTracing keeps Prometheus scrapes and probes out of the traces.
+func skipTracing(req *http.Request) bool {
+	switch req.URL.Path {
+	case "/metrics", "/healthz", "/readyz":
+		return false
+	}
+	return true
 }
 
 // It will take in a gin object -> map string interface
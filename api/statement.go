@@ -0,0 +1,240 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techschool/simplebank/blob"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/statement"
+)
+
+type getStatementURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// from and to are dates (2024-01-31) or RFC 3339 times. A date for to includes that
+// whole day. to defaults to now. camt053 and mt940 are the bank formats ERP systems import.
+type getStatementRequest struct {
+	From   string `form:"from" binding:"required"`
+	To     string `form:"to"`
+	Format string `form:"format" binding:"omitempty,oneof=csv jsonl pdf camt053 mt940"`
+}
+
+// getStatement sends the account's statement for a period: the opening balance, every
+// entry with who it was to or from and the balance after it, then the closing balance.
+// The file is streamed as the entries are read, so large statements start at once.
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri getStatementURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req getStatementRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	periodStart, periodEnd, err := statement.ParsePeriod(req.From, req.To, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	format := statement.FormatCSV
+	if req.Format != "" {
+		format = statement.Format(req.Format)
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	// A big statement can take longer to send than HTTP_WRITE_TIMEOUT allows.
+	if err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := statement.Params{
+		AccountID:   uri.ID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+	}
+	writer, err := statement.NewWriter(format, ctx.Writer)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Nothing is sent until the writer's first write, so these can still be replaced
+	// by an error response if the statement fails before then.
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", format.ContentType())
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", statement.Filename(arg, format)))
+
+	err = statement.Write(ctx, server.store, arg, writer)
+	if err == nil {
+		return
+	}
+
+	if !ctx.Writer.Written() {
+		header.Del("Content-Type")
+		header.Del("Content-Disposition")
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The response has started, so all that is left is to log it. The client sees
+	// the file cut short.
+	ctx.Error(err)
+}
+
+var (
+	errStatementStorageDisabled = errors.New("stored statements are not available")
+	errStatementNotReady        = errors.New("statement is still being generated")
+)
+
+// WithStatementStorage turns on downloading the stored monthly statements.
+func WithStatementStorage(blobs blob.Store) ServerOption {
+	return func(server *Server) {
+		server.statementStorage = blobs
+	}
+}
+
+// statementResponse is a stored statement without where it is kept.
+type statementResponse struct {
+	ID          int64      `json:"id"`
+	AccountID   int64      `json:"account_id"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	Checksum    string     `json:"checksum"`
+	SizeBytes   int64      `json:"size_bytes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func newStatementResponse(s db.Statement) statementResponse {
+	response := statementResponse{
+		ID:          s.ID,
+		AccountID:   s.AccountID,
+		PeriodStart: s.PeriodStart,
+		PeriodEnd:   s.PeriodEnd,
+		Format:      s.Format,
+		Status:      s.Status,
+		Checksum:    s.Checksum,
+		SizeBytes:   s.SizeBytes,
+		CreatedAt:   s.CreatedAt,
+	}
+	if s.CompletedAt.Valid {
+		response.CompletedAt = &s.CompletedAt.Time
+	}
+	return response
+}
+
+type listStatementsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listStatements lists the account's stored statements, newest period first.
+func (server *Server) listStatements(ctx *gin.Context) {
+	var uri getStatementURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listStatementsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	statements, err := server.store.ListStatementsByAccount(ctx, db.ListStatementsByAccountParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]statementResponse, len(statements))
+	for i, s := range statements {
+		response[i] = newStatementResponse(s)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type downloadStatementRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// downloadStatement sends a stored statement's file. The ETag is its SHA-256, the same
+// checksum the list shows, so the download can be checked against it.
+func (server *Server) downloadStatement(ctx *gin.Context) {
+	if server.statementStorage == nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(errStatementStorageDisabled))
+		return
+	}
+
+	var req downloadStatementRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	stored, err := server.store.GetStatement(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedAccount(ctx, stored.AccountID); !ok {
+		return
+	}
+
+	if stored.Status != db.StatementStatusReady {
+		ctx.JSON(http.StatusConflict, errorResponse(errStatementNotReady))
+		return
+	}
+
+	file, err := server.statementStorage.Open(ctx, stored.StoragePath)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer file.Close()
+
+	format := statement.Format(stored.Format)
+	filename := statement.Filename(statement.Params{
+		AccountID:   stored.AccountID,
+		PeriodStart: stored.PeriodStart,
+		PeriodEnd:   stored.PeriodEnd,
+	}, format)
+
+	ctx.DataFromReader(http.StatusOK, stored.SizeBytes, format.ContentType(), file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+		"ETag":                fmt.Sprintf("%q", stored.Checksum),
+	})
+}
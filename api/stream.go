@@ -0,0 +1,157 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+const (
+	// Comment lines sent this often keep proxies from closing an idle stream.
+	streamKeepAliveInterval = 15 * time.Second
+	// How many entries are read per query when catching up.
+	streamPageSize = 100
+	// How long browsers wait before reconnecting, in milliseconds.
+	streamRetryMillis = 3000
+)
+
+var errStreamsDisabled = errors.New("account streams are not available")
+
+type streamAccountURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// A client picking up where it left off sends the id of the last event it got, in the
+// Last-Event-ID header browsers set when they reconnect, or as last_event_id.
+type streamAccountRequest struct {
+	LastEventID *int64 `form:"last_event_id" binding:"omitempty,min=0"`
+}
+
+// streamAccount sends an "entry" server-sent event for every entry posted to one of the
+// user's accounts. The event id is the entry id, so a client that reconnects with it
+// gets the entries it missed first. A new client only gets entries posted from now on.
+func (server *Server) streamAccount(ctx *gin.Context) {
+	var uri streamAccountURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req streamAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("invalid Last-Event-ID %q", header)))
+			return
+		}
+		req.LastEventID = &id
+	}
+
+	if server.entryStream == nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(errStreamsDisabled))
+		return
+	}
+
+	account, ok := server.getOwnedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	// Subscribe before reading where to start, so an entry posted in between isn't missed.
+	wake, unsubscribe := server.entryStream.Subscribe(account.ID)
+	defer unsubscribe()
+
+	var lastID int64
+	if req.LastEventID != nil {
+		lastID = *req.LastEventID
+	} else {
+		head, err := server.store.GetEntryChainHead(ctx, account.ID)
+		if err != nil && err != sql.ErrNoRows {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		lastID = head.ID
+	}
+
+	// The stream outlives HTTP_WRITE_TIMEOUT, which is meant for ordinary requests.
+	if err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Stops nginx from buffering the events.
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", streamRetryMillis)
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		lastID, err = server.sendNewEntries(ctx, account.ID, lastID)
+		if err != nil {
+			// The response has started, so all that is left is to log it and hang up.
+			ctx.Error(err)
+			return
+		}
+		ctx.Writer.Flush()
+
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-server.closing:
+			return
+		case <-wake:
+		case <-keepAlive.C:
+			io.WriteString(ctx.Writer, ": keep-alive\n\n")
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+// sendNewEntries writes an event for each of the account's entries after lastID and
+// returns the id of the last one sent.
+func (server *Server) sendNewEntries(ctx *gin.Context, accountID int64, lastID int64) (int64, error) {
+	for {
+		entries, err := server.store.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+			AccountID: accountID,
+			AfterID:   lastID,
+			Limit:     streamPageSize,
+		})
+		if err != nil {
+			return lastID, err
+		}
+
+		for _, entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return lastID, err
+			}
+			if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: entry\ndata: %s\n\n", entry.ID, data); err != nil {
+				return lastID, err
+			}
+			lastID = entry.ID
+		}
+
+		if len(entries) < streamPageSize {
+			return lastID, nil
+		}
+	}
+}
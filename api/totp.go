@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/totp"
+)
+
+var (
+	errTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	// Codes are single use, so a replayed request is rejected the same as a wrong code.
+	errTOTPRequired = errors.New("a valid, unused two-factor code is required for this transfer")
+	errTOTPLocked   = errors.New("too many wrong two-factor codes or logins, try again later")
+)
+
+type enrollTOTPResponse struct {
+	// The base32 secret, for apps that can't scan the QR code.
+	Secret string `json:"secret"`
+	// otpauth:// URI to render as a QR code.
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// enrollTOTP starts TOTP enrollment for the logged in user by storing a new secret.
+// TOTP stays off until a code generated from the secret is confirmed.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTOTPAlreadyEnabled))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(server.config.TOTPIssuer, user.Username, secret),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type confirmTOTPResponse struct {
+	// Shown once. Only their hashes are stored.
+	RecoveryCodes []string     `json:"recovery_codes"`
+	User          userResponse `json:"user"`
+}
+
+// confirmTOTP turns TOTP on once the user proves their app generates the right codes,
+// and hands out a fresh set of recovery codes.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusConflict, errorResponse(errTOTPAlreadyEnabled))
+		return
+	}
+	if user.TotpSecret == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTOTPNotEnrolled))
+		return
+	}
+
+	step, err := totp.Validate(user.TotpSecret, req.Code, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	hashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+
+	user, err = server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+		RequestID:          ctx.GetString(requestIDKey),
+		IP:                 ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+		User:          newUserResponse(user),
+	})
+}
+
+type totpChallengeResponse struct {
+	TOTPRequired            bool      `json:"totp_required"`
+	ChallengeToken          string    `json:"challenge_token"`
+	ChallengeTokenExpiresAt time.Time `json:"challenge_token_expires_at"`
+}
+
+// sendTOTPChallenge answers a good password with a challenge token, which is only
+// accepted by the second login step.
+func (server *Server) sendTOTPChallenge(ctx *gin.Context, user db.User) {
+	challengeToken, payload, err := server.tokenMaker.CreateToken(
+		user.Username,
+		user.Role,
+		server.config.TOTPChallengeDuration,
+		token.WithType(token.TypeTOTPChallenge),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, totpChallengeResponse{
+		TOTPRequired:            true,
+		ChallengeToken:          challengeToken,
+		ChallengeTokenExpiresAt: payload.ExpiredAt,
+	})
+}
+
+// Either a code from the authenticator app or one of the recovery codes.
+type loginTOTPRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// loginTOTP is the second login step. Wrong codes count as failed logins, so the
+// usual backoff and lockout apply to guessing them.
+func (server *Server) loginTOTP(ctx *gin.Context) {
+	var req loginTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if payload.Type != token.TypeTOTPChallenge {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+		return
+	}
+
+	result, err := server.store.LoginAttemptTx(ctx, db.LoginAttemptTxParams{
+		FailedLoginTxParams: server.failedLoginParams(ctx, payload.Username),
+		Check: func(q db.Querier, user db.User) (bool, error) {
+			if req.Code != "" {
+				return useTOTPCode(ctx, q, user, req.Code)
+			}
+			_, err := q.UseTOTPRecoveryCode(ctx, db.UseTOTPRecoveryCodeParams{
+				Username: user.Username,
+				CodeHash: totp.HashRecoveryCode(req.RecoveryCode),
+			})
+			if err == sql.ErrNoRows {
+				return false, nil
+			}
+			return err == nil, err
+		},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !server.loginAttemptSucceeded(ctx, result) {
+		return
+	}
+
+	server.finishLogin(ctx, result.User)
+}
+
+// useTOTPCode checks a code and marks its time step as used through q, so each code
+// only works once. It returns false for a wrong or already used code.
+func useTOTPCode(ctx context.Context, q db.Querier, user db.User, code string) (bool, error) {
+	if !user.TotpEnabled {
+		return false, nil
+	}
+
+	step, err := totp.Validate(user.TotpSecret, code, time.Now())
+	if err != nil {
+		return false, nil
+	}
+
+	_, err = q.UpdateUserTOTPLastUsedStep(ctx, db.UpdateUserTOTPLastUsedStepParams{
+		Username: user.Username,
+		Step:     step,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// Both accounts must exist and hold the currency of the transfer. Amounts above
+// TOTP_TRANSFER_THRESHOLD also need a fresh code from the user's authenticator app.
+type transferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,oneof=USD EUR"`
+	TOTPCode      string `json:"totp_code" binding:"omitempty,numeric,len=6"`
+}
+
+func (server *Server) createTransfer(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	// Only the owner can move money out of an account.
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errAccountNotOwned))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	if threshold := server.config.TOTPTransferThreshold; threshold > 0 && req.Amount > threshold {
+		if !server.checkTransferTOTP(ctx, authPayload.Username, req.TOTPCode) {
+			return
+		}
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Actor:         authPayload.Username,
+		RequestID:     ctx.GetString(requestIDKey),
+		IP:            ctx.ClientIP(),
+	}
+
+	result, err := server.store.TransferTx(ctx, arg)
+	if err != nil {
+		// An account was frozen after the checks above.
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// validAccount checks the account exists, is a customer account, isn't frozen and its currency
+// matches. It writes the error response itself, so the handler only has to return when it is false.
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	if account.Kind != db.AccountKindCustomer {
+		ctx.JSON(http.StatusForbidden, errorResponse(errInternalAccount))
+		return account, false
+	}
+
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return account, false
+	}
+
+	if account.Frozen {
+		err := fmt.Errorf("account [%d] is frozen", account.ID)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
+// checkTransferTOTP makes sure a high value transfer comes with a code that hasn't
+// been used before. Users without TOTP can't make such transfers at all. A wrong code
+// counts as a failed login, so guessing codes with a stolen token or API key runs into
+// the same backoff and lockout as guessing them at login.
+func (server *Server) checkTransferTOTP(ctx *gin.Context, username string, code string) bool {
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !user.TotpEnabled {
+		err := fmt.Errorf("transfers above %d need two-factor authentication to be enabled", server.config.TOTPTransferThreshold)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+	}
+	// No code at all is how a client finds out it needs one, not a guess.
+	if code == "" {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPRequired))
+		return false
+	}
+
+	result, err := server.store.LoginAttemptTx(ctx, db.LoginAttemptTxParams{
+		FailedLoginTxParams: server.failedLoginParams(ctx, username),
+		Check: func(q db.Querier, user db.User) (bool, error) {
+			return useTOTPCode(ctx, q, user, code)
+		},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if result.Waiting {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPLocked))
+		return false
+	}
+	if result.Failed {
+		if result.Locked {
+			util.LoggerFromContext(ctx).WarnContext(ctx, "user locked out after wrong transfer codes",
+				slog.String("username", username),
+				slog.Int("failed_login_attempts", int(result.User.FailedLoginAttempts)),
+			)
+		}
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPRequired))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+)
+
+// Returned for a wrong password and for an unknown username alike, so the
+// response doesn't tell an attacker which usernames exist.
+var errInvalidCredentials = errors.New("incorrect username or password")
+
+// A valid bcrypt hash that no password matches. Unknown usernames are checked
+// against it so they take as long to reject as wrong passwords.
+var dummyPasswordHash, _ = util.HashPassword(util.RandomString(32))
+
+type createUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+	FullName string `json:"full_name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+}
+
+// userResponse leaves out the password hash, TOTP secret and lockout bookkeeping.
+type userResponse struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	TOTPEnabled       bool      `json:"totp_enabled"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		TOTPEnabled:       user.TotpEnabled,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+func (server *Server) createUser(ctx *gin.Context) {
+	var req createUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateUserParams{
+		Username:       req.Username,
+		HashedPassword: hashedPassword,
+		FullName:       req.FullName,
+		Email:          req.Email,
+	}
+
+	user, err := server.store.CreateUser(ctx, arg)
+	if err != nil {
+		// A taken username or email breaks the primary key or unique constraint.
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type loginUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type loginUserResponse struct {
+	AccessToken          string       `json:"access_token"`
+	AccessTokenExpiresAt time.Time    `json:"access_token_expires_at"`
+	User                 userResponse `json:"user"`
+}
+
+// loginUser checks the password and hands out an access token. Every failure makes
+// the next attempt wait longer, and after LOGIN_MAX_FAILED_ATTEMPTS the user is
+// locked out for LOGIN_LOCKOUT_DURATION. The password is not even checked while the
+// user has to wait, so guesses made during that time are worthless. Unknown users,
+// wrong passwords and users who have to wait all get the same 401, in about the
+// same time.
+func (server *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.LoginAttemptTx(ctx, db.LoginAttemptTxParams{
+		FailedLoginTxParams: server.failedLoginParams(ctx, req.Username),
+		Check: func(q db.Querier, user db.User) (bool, error) {
+			return util.CheckPassword(req.Password, user.HashedPassword) == nil, nil
+		},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			util.CheckPassword(req.Password, dummyPasswordHash)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// A refused attempt skips bcrypt, which would make it quicker than a wrong one.
+	if result.Waiting {
+		util.CheckPassword(req.Password, dummyPasswordHash)
+	}
+	if !server.loginAttemptSucceeded(ctx, result) {
+		return
+	}
+
+	// With TOTP on, the password only earns a short lived challenge token. The failure
+	// counter is left alone until the code is checked too, so a known password can't be
+	// used to reset it between code guesses.
+	if result.User.TotpEnabled {
+		server.sendTOTPChallenge(ctx, result.User)
+		return
+	}
+
+	server.finishLogin(ctx, result.User)
+}
+
+// failedLoginParams is the lockout policy from the config, applied to username.
+func (server *Server) failedLoginParams(ctx *gin.Context, username string) db.FailedLoginTxParams {
+	return db.FailedLoginTxParams{
+		Username:        username,
+		MaxAttempts:     server.config.LoginMaxFailedAttempts,
+		BaseDelay:       server.config.LoginBaseDelay,
+		LockoutDuration: server.config.LoginLockoutDuration,
+		RequestID:       ctx.GetString(requestIDKey),
+		IP:              ctx.ClientIP(),
+	}
+}
+
+// loginAttemptSucceeded reports whether a login attempt was checked and was right. If
+// not, it answers 401 with errInvalidCredentials, whether the attempt was wrong or the
+// user still has to wait after failed logins. That is the same answer an unknown
+// username gets, so the lockout doesn't give away which usernames exist.
+func (server *Server) loginAttemptSucceeded(ctx *gin.Context, result db.LoginAttemptTxResult) bool {
+	if !result.Waiting && !result.Failed {
+		return true
+	}
+
+	if result.Locked {
+		util.LoggerFromContext(ctx).WarnContext(ctx, "user locked out after failed logins",
+			slog.String("username", result.User.Username),
+			slog.Int("failed_login_attempts", int(result.User.FailedLoginAttempts)),
+		)
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+	return false
+}
+
+// finishLogin clears any failed logins and hands out the access token.
+func (server *Server) finishLogin(ctx *gin.Context, user db.User) {
+	var err error
+	if user.FailedLoginAttempts > 0 || user.LockedUntil.Valid {
+		user, err = server.store.ResetFailedLogins(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	accessToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, loginUserResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: payload.ExpiredAt,
+		User:                 newUserResponse(user),
+	})
+}
+
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lets an admin lift a lockout before it runs out. It is routed for admins only.
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var req unlockUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.UnlockUserTx(ctx, db.UnlockUserTxParams{
+		Username:  req.Username,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type listUsersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listUsers lets admins page through every user.
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	users, err := server.store.ListUsers(ctx, db.ListUsersParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]userResponse, len(users))
+	for i, user := range users {
+		rsp[i] = newUserResponse(user)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateUserRoleURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=depositor banker admin"`
+}
+
+// updateUserRole lets an admin promote or demote a user. Admins can't change their
+// own role, so once there is an admin there is always at least one left. The first
+// admin is made with cmd/admin, since nobody can call this before then.
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if uri.Username == authPayload.Username {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("admins can't change their own role")))
+		return
+	}
+
+	user, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		Username:  uri.Username,
+		Role:      req.Role,
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
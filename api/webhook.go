@@ -0,0 +1,319 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/events"
+	"github.com/techschool/simplebank/token"
+	"github.com/techschool/simplebank/util"
+	"github.com/techschool/simplebank/webhooks"
+)
+
+// Returned when a user asks for someone else's webhook or delivery.
+var errWebhookNotOwned = errors.New("webhook doesn't belong to the authenticated user")
+
+// EventTypes are names like transfer.posted, see events.Names. Deliveries are
+// POSTed to URL, which must be http or https on a public address.
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,required"`
+}
+
+// webhookResponse is a subscription without its secret.
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(subscription db.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		Active:     subscription.Active,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type createWebhookResponse struct {
+	// Shown once. Deliveries are signed with it, see webhooks.Verify.
+	Secret  string          `json:"secret"`
+	Webhook webhookResponse `json:"webhook"`
+}
+
+// createWebhook subscribes a URL to events about the logged in user's accounts.
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("url must be an http or https URL")))
+		return
+	}
+	// Deliveries check the address again when they connect, after DNS.
+	if err := webhooks.CheckURL(req.URL); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	for _, eventType := range req.EventTypes {
+		if !slices.Contains(events.Names, eventType) {
+			err := fmt.Errorf("unknown event type %q, expected one of %v", eventType, events.Names)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	slices.Sort(req.EventTypes)
+	req.EventTypes = slices.Compact(req.EventTypes)
+
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscription, err := server.store.CreateWebhookSubscriptionTx(ctx, db.CreateWebhookSubscriptionTxParams{
+		CreateWebhookSubscriptionParams: db.CreateWebhookSubscriptionParams{
+			Owner:      authPayload.Username,
+			Url:        req.URL,
+			EventTypes: req.EventTypes,
+			Secret:     secret,
+		},
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		Secret:  secret,
+		Webhook: newWebhookResponse(subscription),
+	})
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listWebhooks lists the logged in user's subscriptions, inactive ones included.
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	var req listWebhooksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscriptions, err := server.store.ListWebhookSubscriptionsByOwner(ctx, db.ListWebhookSubscriptionsByOwnerParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookResponse(subscription)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebhook stops one of the user's subscriptions. Its deliveries are kept.
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscription, err := server.store.DeactivateWebhookSubscriptionTx(ctx, db.DeactivateWebhookSubscriptionTxParams{
+		ID:        uri.ID,
+		Owner:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookResponse(subscription))
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listWebhookDeliveries is the delivery log of a subscription, newest first.
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedWebhook(ctx, uri.ID); !ok {
+		return
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveriesBySubscription(ctx, db.ListWebhookDeliveriesBySubscriptionParams{
+		SubscriptionID: uri.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+type webhookDeliveryURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type webhookDeliveryResponse struct {
+	Delivery db.WebhookDelivery          `json:"delivery"`
+	Attempts []db.WebhookDeliveryAttempt `json:"attempts"`
+}
+
+// getWebhookDelivery returns a delivery with every attempt at sending it and the
+// response code each one got.
+func (server *Server) getWebhookDelivery(ctx *gin.Context) {
+	var uri webhookDeliveryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	delivery, ok := server.getOwnedWebhookDelivery(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	attempts, err := server.store.ListWebhookDeliveryAttempts(ctx, delivery.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookDeliveryResponse{Delivery: delivery, Attempts: attempts})
+}
+
+// replayWebhookDelivery sends a delivery again, with the same body and a fresh set of attempts.
+func (server *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var uri webhookDeliveryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getOwnedWebhookDelivery(ctx, uri.ID); !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	maxAttempts := server.config.WebhookMaxAttempts
+
+	delivery, err := server.store.ReplayWebhookDeliveryTx(ctx, db.ReplayWebhookDeliveryTxParams{
+		ID: uri.ID,
+		Job: func(delivery db.WebhookDelivery) db.JobRequest {
+			return webhooks.DeliveryJob(delivery, maxAttempts)
+		},
+		Actor:     authPayload.Username,
+		RequestID: ctx.GetString(requestIDKey),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrWebhookDeliveryPending):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// getOwnedWebhook loads a subscription the logged in user may see: their own, or any
+// for bankers and admins. It writes the error response itself.
+func (server *Server) getOwnedWebhook(ctx *gin.Context, id int64) (db.WebhookSubscription, bool) {
+	subscription, err := server.store.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return subscription, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return subscription, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if subscription.Owner != authPayload.Username && !util.IsPrivilegedRole(authPayload.Role) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errWebhookNotOwned))
+		return subscription, false
+	}
+
+	return subscription, true
+}
+
+// getOwnedWebhookDelivery loads a delivery of a subscription the logged in user may see.
+func (server *Server) getOwnedWebhookDelivery(ctx *gin.Context, id int64) (db.WebhookDelivery, bool) {
+	delivery, err := server.store.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return delivery, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return delivery, false
+	}
+
+	if _, ok := server.getOwnedWebhook(ctx, delivery.SubscriptionID); !ok {
+		return delivery, false
+	}
+	return delivery, true
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/techschool/simplebank/token"
+)
+
+var errWebSocketsDisabled = errors.New("websocket notifications are not available")
+
+// The query parameter /ws takes a ticket from POST /ws/ticket in.
+const webSocketTicketQueryKey = "ticket"
+
+// NotificationHub runs a user's WebSocket connection, see hub.Hub.
+type NotificationHub interface {
+	Serve(conn *websocket.Conn, username string)
+}
+
+// WithNotificationHub turns on GET /ws.
+func WithNotificationHub(hub NotificationHub) ServerOption {
+	return func(server *Server) {
+		server.notificationHub = hub
+	}
+}
+
+// newWebSocketUpgrader only lets browsers open /ws from the pages in WS_ALLOWED_ORIGINS,
+// so another site can't open a socket with a logged in user's ticket. Requests without
+// an Origin header don't come from a browser and are let through.
+func newWebSocketUpgrader(allowedOrigins string) *websocket.Upgrader {
+	var origins []string
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.ToLower(origin))
+		}
+	}
+
+	return &websocket.Upgrader{
+		HandshakeTimeout: 10 * time.Second,
+		CheckOrigin: func(req *http.Request) bool {
+			origin := req.Header.Get("Origin")
+			return origin == "" || slices.Contains(origins, strings.ToLower(origin))
+		},
+	}
+}
+
+type webSocketTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// createWebSocketTicket hands out a short lived ticket for GET /ws?ticket=, since
+// browsers can't send the Authorization or X-API-Key header on a WebSocket handshake.
+// The ticket opens /ws and nothing else.
+func (server *Server) createWebSocketTicket(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	ticket, payload, err := server.tokenMaker.CreateToken(
+		authPayload.Username,
+		authPayload.Role,
+		server.config.WebSocketTicketDuration,
+		token.WithType(token.TypeWebSocketTicket),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webSocketTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: payload.ExpiredAt,
+	})
+}
+
+// serveWebSocket upgrades the request and pushes events about all of the user's accounts
+// to it: transfers in and out, deposits, withdrawals, adjustments and status changes.
+// The client narrows them down by sending
+// {"action":"subscribe","events":["transfer.posted"],"accounts":[1]}.
+func (server *Server) serveWebSocket(ctx *gin.Context) {
+	if server.notificationHub == nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(errWebSocketsDisabled))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// Only recorded for the request log, the handshake response is written by Upgrade.
+	ctx.Status(http.StatusSwitchingProtocols)
+	conn, err := server.webSocketUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// Upgrade already answered when the handshake was wrong or the origin isn't allowed.
+		ctx.Error(err)
+		return
+	}
+
+	server.notificationHub.Serve(conn, authPayload.Username)
+}
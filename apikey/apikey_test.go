@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndVerify(t *testing.T) {
+	key, err := Generate()
+	require.NoError(t, err)
+	require.NotEmpty(t, key.Plaintext)
+
+	prefix, err := Parse(key.Plaintext)
+	require.NoError(t, err)
+	require.Equal(t, key.Prefix, prefix)
+
+	require.True(t, Verify(key.Plaintext, key.Hash))
+	require.False(t, Verify(key.Plaintext+"0", key.Hash))
+
+	// Two keys never share a prefix or secret.
+	other, err := Generate()
+	require.NoError(t, err)
+	require.NotEqual(t, key.Plaintext, other.Plaintext)
+	require.False(t, Verify(other.Plaintext, key.Hash))
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"sb",
+		"sb_1234abcd",
+		"xx_1234abcd_" + "00112233445566778899aabbccddeeff0011223344556677",
+		"sb_123_" + "00112233445566778899aabbccddeeff0011223344556677",
+		"sb_1234abcd_short",
+	} {
+		_, err := Parse(key)
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
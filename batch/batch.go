@@ -0,0 +1,89 @@
+// Package batch reads bulk payment files, like a payroll run, and checks every payment
+// in them before any is stored. Files are CSV or ISO 20022 pain.001 XML. The payments
+// are stored as a batch and made by a background job, all of them or each one that can
+// be, depending on the batch's mode.
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format is a bulk payment file format.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatPain001 Format = "pain.001"
+)
+
+// MaxItems is the most payments a file can hold, which keeps a batch's report small
+// enough to send in one response.
+const MaxItems = 1000
+
+// The currencies transfers can be made in, as for POST /transfers.
+var currencies = []string{"USD", "EUR"}
+
+var ErrInvalidFile = errors.New("invalid payment file")
+
+// Item is one payment read from a file.
+type Item struct {
+	// Line is where the payment is in the file: the line number in a CSV file, or the
+	// position of the transaction in a pain.001 file, counting from 1.
+	Line          int32  `json:"line"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Reference     string `json:"reference"`
+}
+
+// LineError says what is wrong with one payment.
+type LineError struct {
+	Line  int32  `json:"line"`
+	Error string `json:"error"`
+}
+
+// Parse reads a CSV or pain.001 file, telling them apart by whether it starts with <.
+// Payments that can't be read come back as line errors, so a file with several
+// mistakes gets them all reported at once. The error is for a file that can't be read
+// at all.
+func Parse(data []byte) (Format, []Item, []LineError, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		items, lineErrors, err := ParsePain001(data)
+		return FormatPain001, items, lineErrors, err
+	}
+	items, lineErrors, err := ParseCSV(data)
+	return FormatCSV, items, lineErrors, err
+}
+
+// parseAmount reads a positive amount in whole units of the currency. A decimal is
+// accepted as long as its fraction is zero, since files made by finance software often
+// have two decimals.
+func parseAmount(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if whole, fraction, ok := strings.Cut(value, "."); ok {
+		if strings.Trim(fraction, "0") != "" {
+			return 0, fmt.Errorf("amount %q must be a whole number", value)
+		}
+		value = whole
+	}
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("amount %q must be a positive whole number", value)
+	}
+	return amount, nil
+}
+
+// parseAccountID reads an account ID, which is how accounts are identified in both formats.
+func parseAccountID(value string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("account %q is not an account ID", value)
+	}
+	return id, nil
+}
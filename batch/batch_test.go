@@ -0,0 +1,96 @@
+package batch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := "\xef\xbb\xbfamount,Currency,from_account_id,to_account_id,reference\n" +
+		"2500,eur,7,12,Salary Bob\n" +
+		"\n" +
+		"3000.00,EUR,7,13,\"Salary, Carol\"\n"
+
+	format, items, lineErrors, err := Parse([]byte(data))
+	require.NoError(t, err)
+	require.Equal(t, FormatCSV, format)
+	require.Empty(t, lineErrors)
+	require.Equal(t, []Item{
+		{Line: 2, FromAccountID: 7, ToAccountID: 12, Amount: 2500, Currency: "EUR", Reference: "Salary Bob"},
+		{Line: 4, FromAccountID: 7, ToAccountID: 13, Amount: 3000, Currency: "EUR", Reference: "Salary, Carol"},
+	}, items)
+}
+
+func TestParseCSVLineErrors(t *testing.T) {
+	data := "from_account_id,to_account_id,amount,currency\n" +
+		"7,12,2500,EUR\n" +
+		"7,abc,-5,EUR\n" +
+		"7,12\n" +
+		"7,13,10.50,EUR\n"
+
+	_, items, lineErrors, err := Parse([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, []LineError{
+		{Line: 3, Error: `to_account_id: account "abc" is not an account ID; amount "-5" must be a positive whole number`},
+		{Line: 4, Error: "wrong number of fields"},
+		{Line: 5, Error: `amount "10.50" must be a whole number`},
+	}, lineErrors)
+}
+
+func TestParseCSVInvalidFile(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Empty", data: ""},
+		{name: "HeaderOnly", data: "from_account_id,to_account_id,amount,currency\n"},
+		{name: "MissingColumn", data: "from_account_id,to_account_id,amount\n7,12,5\n"},
+		{name: "BadQuote", data: "from_account_id,to_account_id,amount,currency\n7,12,5,\"EUR\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, _, err := Parse([]byte(tc.data))
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
+
+func TestParseCSVTooManyItems(t *testing.T) {
+	data := []byte("from_account_id,to_account_id,amount,currency\n")
+	for range MaxItems + 1 {
+		data = append(data, "7,12,5,EUR\n"...)
+	}
+
+	_, _, err := ParseCSV(data)
+	require.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestParseAmount(t *testing.T) {
+	testCases := []struct {
+		value  string
+		amount int64
+		valid  bool
+	}{
+		{value: "2500", amount: 2500, valid: true},
+		{value: " 2500.00 ", amount: 2500, valid: true},
+		{value: "7.", amount: 7, valid: true},
+		{value: "10.5", valid: false},
+		{value: "0", valid: false},
+		{value: "-3", valid: false},
+		{value: "1e3", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, tc := range testCases {
+		amount, err := parseAmount(tc.value)
+		if !tc.valid {
+			require.Error(t, err, tc.value)
+			continue
+		}
+		require.NoError(t, err, tc.value)
+		require.Equal(t, tc.amount, amount)
+	}
+}
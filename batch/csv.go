@@ -0,0 +1,104 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// The columns a CSV file must have, in any order. reference is optional.
+var csvColumns = []string{"from_account_id", "to_account_id", "amount", "currency"}
+
+// ParseCSV reads a CSV file with a header line naming its columns, e.g.
+//
+//	from_account_id,to_account_id,amount,currency,reference
+//	7,12,2500,EUR,Salary March
+func ParseCSV(data []byte) ([]Item, []LineError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: the header has no %s column", ErrInvalidFile, name)
+		}
+	}
+	reference, hasReference := columns["reference"]
+	// Every line must have as many fields as the header.
+	reader.FieldsPerRecord = len(header)
+
+	var items []Item
+	var lineErrors []LineError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			lineErrors = append(lineErrors, LineError{Line: int32(parseErr.StartLine), Error: "wrong number of fields"})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(items)+len(lineErrors) >= MaxItems {
+			return nil, nil, fmt.Errorf("%w: more than %d payments", ErrInvalidFile, MaxItems)
+		}
+
+		item := Item{Line: int32(line)}
+		var problems []string
+		if item.FromAccountID, err = parseAccountID(record[columns["from_account_id"]]); err != nil {
+			problems = append(problems, "from_account_id: "+err.Error())
+		}
+		if item.ToAccountID, err = parseAccountID(record[columns["to_account_id"]]); err != nil {
+			problems = append(problems, "to_account_id: "+err.Error())
+		}
+		if item.Amount, err = parseAmount(record[columns["amount"]]); err != nil {
+			problems = append(problems, err.Error())
+		}
+		item.Currency = strings.ToUpper(strings.TrimSpace(record[columns["currency"]]))
+		if hasReference {
+			item.Reference = strings.TrimSpace(record[reference])
+		}
+
+		if len(problems) > 0 {
+			lineErrors = append(lineErrors, LineError{Line: item.Line, Error: strings.Join(problems, "; ")})
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if len(items)+len(lineErrors) == 0 {
+		return nil, nil, fmt.Errorf("%w: the file has no payments", ErrInvalidFile)
+	}
+	return items, lineErrors, nil
+}
+
+// isBlank reports whether every field is empty, like the empty line spreadsheets often
+// leave at the end of a file.
+func isBlank(record []string) bool {
+	return !slices.ContainsFunc(record, func(field string) bool {
+		return strings.TrimSpace(field) != ""
+	})
+}
@@ -0,0 +1,31 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/jobs"
+)
+
+// ExecutePayload names the batch a job runs.
+type ExecutePayload struct {
+	BatchID int64 `json:"batch_id"`
+}
+
+// Execute makes the transfers of a batch. It is enqueued with the batch, so a batch is
+// never stored without a job to run it.
+var Execute = jobs.NewKind[ExecutePayload]("batch.execute")
+
+// ExecuteHandler runs the batch. A retry after a failure carries on where the last run
+// stopped, see Store.ExecuteBatchTx.
+func ExecuteHandler(store db.Store) func(ctx context.Context, payload ExecutePayload) error {
+	return func(ctx context.Context, payload ExecutePayload) error {
+		_, err := store.ExecuteBatchTx(ctx, payload.BatchID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// painDocument is the part of a pain.001 customer credit transfer initiation that is
+// read. Tags have no namespace, so every version of the message matches.
+type painDocument struct {
+	XMLName     xml.Name `xml:"Document"`
+	GroupHeader struct {
+		NumberOfTransactions string `xml:"NbOfTxs"`
+		ControlSum           string `xml:"CtrlSum"`
+	} `xml:"CstmrCdtTrfInitn>GrpHdr"`
+	PaymentInfos []painPaymentInfo `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+// painPaymentInfo is a group of transactions paid from one debtor account.
+type painPaymentInfo struct {
+	DebtorAccount painAccount       `xml:"DbtrAcct"`
+	Transactions  []painTransaction `xml:"CdtTrfTxInf"`
+}
+
+type painAccount struct {
+	ID   string `xml:"Id>Othr>Id"`
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type painTransaction struct {
+	EndToEndID string `xml:"PmtId>EndToEndId"`
+	Amount     struct {
+		Currency string `xml:"Ccy,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"Amt>InstdAmt"`
+	CreditorAccount painAccount `xml:"CdtrAcct"`
+	Remittance      string      `xml:"RmtInf>Ustrd"`
+}
+
+// ParsePain001 reads an ISO 20022 pain.001 credit transfer initiation. Accounts are
+// identified by their ID in Id/Othr/Id, since accounts here have no IBAN. Each
+// transaction's reference is its EndToEndId, or the remittance text without one.
+func ParsePain001(data []byte) ([]Item, []LineError, error) {
+	var document painDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	var items []Item
+	var lineErrors []LineError
+	for _, info := range document.PaymentInfos {
+		fromAccountID, fromErr := painAccountID(info.DebtorAccount)
+
+		for _, transaction := range info.Transactions {
+			line := int32(len(items) + len(lineErrors) + 1)
+			if line > MaxItems {
+				return nil, nil, fmt.Errorf("%w: more than %d payments", ErrInvalidFile, MaxItems)
+			}
+
+			item := Item{
+				Line:          line,
+				FromAccountID: fromAccountID,
+				Currency:      strings.ToUpper(strings.TrimSpace(transaction.Amount.Currency)),
+				Reference:     strings.TrimSpace(transaction.EndToEndID),
+			}
+			// NOTPROVIDED is what the standard says to send when there is no reference.
+			if item.Reference == "" || item.Reference == "NOTPROVIDED" {
+				item.Reference = strings.TrimSpace(transaction.Remittance)
+			}
+
+			var problems []string
+			if fromErr != nil {
+				problems = append(problems, "DbtrAcct: "+fromErr.Error())
+			}
+			var err error
+			if item.ToAccountID, err = painAccountID(transaction.CreditorAccount); err != nil {
+				problems = append(problems, "CdtrAcct: "+err.Error())
+			}
+			if item.Amount, err = parseAmount(transaction.Amount.Value); err != nil {
+				problems = append(problems, err.Error())
+			}
+
+			if len(problems) > 0 {
+				lineErrors = append(lineErrors, LineError{Line: item.Line, Error: strings.Join(problems, "; ")})
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+
+	count := len(items) + len(lineErrors)
+	if count == 0 {
+		return nil, nil, fmt.Errorf("%w: the file has no transactions", ErrInvalidFile)
+	}
+	if err := checkPainTotals(document, count, items, lineErrors); err != nil {
+		return nil, nil, err
+	}
+	return items, lineErrors, nil
+}
+
+// checkPainTotals compares the group header's count and control sum, when it has them,
+// with the transactions, which catches a file that was cut short or edited by hand.
+func checkPainTotals(document painDocument, count int, items []Item, lineErrors []LineError) error {
+	header := document.GroupHeader
+	if n := strings.TrimSpace(header.NumberOfTransactions); n != "" && n != fmt.Sprint(count) {
+		return fmt.Errorf("%w: NbOfTxs is %s but the file has %d transactions", ErrInvalidFile, n, count)
+	}
+
+	// Without every amount there is nothing to add up.
+	if header.ControlSum == "" || len(lineErrors) > 0 {
+		return nil
+	}
+	controlSum, err := parseAmount(header.ControlSum)
+	if err != nil {
+		return fmt.Errorf("%w: CtrlSum: %v", ErrInvalidFile, err)
+	}
+	var sum int64
+	for _, item := range items {
+		sum += item.Amount
+	}
+	if sum != controlSum {
+		return fmt.Errorf("%w: CtrlSum is %d but the transactions add up to %d", ErrInvalidFile, controlSum, sum)
+	}
+	return nil
+}
+
+func painAccountID(account painAccount) (int64, error) {
+	if account.ID == "" && account.IBAN != "" {
+		return 0, fmt.Errorf("IBAN %s is not known here, give the account ID in Othr/Id", account.IBAN)
+	}
+	return parseAccountID(account.ID)
+}
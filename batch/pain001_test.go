@@ -0,0 +1,65 @@
+package batch
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePain001(t *testing.T) {
+	data, err := os.ReadFile("testdata/payroll.pain001.xml")
+	require.NoError(t, err)
+
+	format, items, lineErrors, err := Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, FormatPain001, format)
+	require.Empty(t, lineErrors)
+	require.Equal(t, []Item{
+		{Line: 1, FromAccountID: 7, ToAccountID: 12, Amount: 2500, Currency: "EUR", Reference: "SALARY-BOB"},
+		{Line: 2, FromAccountID: 7, ToAccountID: 13, Amount: 3000, Currency: "EUR", Reference: "Salary March"},
+		{Line: 3, FromAccountID: 8, ToAccountID: 14, Amount: 200, Currency: "USD", Reference: "SALARY-DAVE"},
+	}, items)
+}
+
+func TestParsePain001LineErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/payroll.pain001.xml")
+	require.NoError(t, err)
+	// Carol's account is given by IBAN and Dave's amount has cents.
+	file := regexp.MustCompile(`<Othr>\s*<Id>13</Id>\s*</Othr>`).ReplaceAllString(string(data), "<IBAN>DE89370400440532013000</IBAN>")
+	file = strings.Replace(file, ">200<", ">200.5<", 1)
+
+	items, lineErrors, err := ParsePain001([]byte(file))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Len(t, lineErrors, 2)
+	require.Equal(t, int32(2), lineErrors[0].Line)
+	require.Contains(t, lineErrors[0].Error, "CdtrAcct: IBAN DE89370400440532013000")
+	require.Equal(t, LineError{Line: 3, Error: `amount "200.5" must be a whole number`}, lineErrors[1])
+}
+
+func TestParsePain001InvalidFile(t *testing.T) {
+	data, err := os.ReadFile("testdata/payroll.pain001.xml")
+	require.NoError(t, err)
+	file := string(data)
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Malformed", data: file[:len(file)/2]},
+		{name: "OtherMessage", data: `<Document><BkToCstmrStmt></BkToCstmrStmt></Document>`},
+		{name: "NotADocument", data: `<html></html>`},
+		{name: "NumberOfTransactions", data: strings.Replace(file, "<NbOfTxs>3<", "<NbOfTxs>4<", 1)},
+		{name: "ControlSum", data: strings.Replace(file, "<CtrlSum>5700.00<", "<CtrlSum>5800.00<", 1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := ParsePain001([]byte(tc.data))
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Validate checks every payment the way POST /transfers checks one: a known currency,
+// two different customer accounts in that currency that aren't frozen, and the sending
+// account owned by owner. Balances aren't checked here, since they can change before
+// the batch runs. The error is for a failure to look accounts up.
+func Validate(ctx context.Context, store db.Store, owner string, items []Item) ([]LineError, error) {
+	// Payroll files pay from the same account over and over, so each account is only
+	// looked up once. A nil account is one that doesn't exist.
+	accounts := map[int64]*db.Account{}
+	lookup := func(id int64) (*db.Account, error) {
+		if account, ok := accounts[id]; ok {
+			return account, nil
+		}
+		account, err := store.GetAccount(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			accounts[id] = nil
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = &account
+		return &account, nil
+	}
+
+	var lineErrors []LineError
+	for _, item := range items {
+		var problems []string
+		if !slices.Contains(currencies, item.Currency) {
+			problems = append(problems, fmt.Sprintf("currency %q is not supported", item.Currency))
+		}
+		if item.FromAccountID == item.ToAccountID {
+			problems = append(problems, "from and to account are the same")
+		}
+
+		from, err := lookup(item.FromAccountID)
+		if err != nil {
+			return nil, err
+		}
+		to, err := lookup(item.ToAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if from != nil && from.Owner != owner {
+			problems = append(problems, fmt.Sprintf("account %d doesn't belong to the authenticated user", from.ID))
+		}
+		problems = append(problems, checkAccount(item.FromAccountID, from, item.Currency)...)
+		if item.ToAccountID != item.FromAccountID {
+			problems = append(problems, checkAccount(item.ToAccountID, to, item.Currency)...)
+		}
+
+		if len(problems) > 0 {
+			lineErrors = append(lineErrors, LineError{Line: item.Line, Error: strings.Join(problems, "; ")})
+		}
+	}
+	return lineErrors, nil
+}
+
+// checkAccount lists what stops account id from taking part in a transfer in currency.
+func checkAccount(id int64, account *db.Account, currency string) []string {
+	if account == nil {
+		return []string{fmt.Sprintf("account %d not found", id)}
+	}
+	var problems []string
+	if account.Kind != db.AccountKindCustomer {
+		problems = append(problems, fmt.Sprintf("account %d is an internal account", account.ID))
+	}
+	if account.Currency != currency {
+		problems = append(problems, fmt.Sprintf("account %d currency mismatch: %s vs %s", account.ID, account.Currency, currency))
+	}
+	if account.Frozen {
+		problems = append(problems, fmt.Sprintf("account %d is frozen", account.ID))
+	}
+	return problems
+}
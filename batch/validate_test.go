@@ -0,0 +1,69 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// fakeStore has a fixed set of accounts and counts how often each is looked up.
+type fakeStore struct {
+	db.Store
+	accounts map[int64]db.Account
+	lookups  map[int64]int
+}
+
+func (store *fakeStore) GetAccount(ctx context.Context, id int64) (db.Account, error) {
+	store.lookups[id]++
+	account, ok := store.accounts[id]
+	if !ok {
+		return db.Account{}, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+func TestValidate(t *testing.T) {
+	store := &fakeStore{
+		accounts: map[int64]db.Account{
+			7:  {ID: 7, Owner: "alice", Currency: "EUR", Kind: db.AccountKindCustomer},
+			8:  {ID: 8, Owner: "alice", Currency: "USD", Kind: db.AccountKindCustomer},
+			12: {ID: 12, Owner: "bob", Currency: "EUR", Kind: db.AccountKindCustomer},
+			13: {ID: 13, Owner: "carol", Currency: "EUR", Kind: db.AccountKindCustomer, Frozen: true},
+			14: {ID: 14, Owner: "bob", Currency: "EUR", Kind: db.AccountKindCustomer},
+			1:  {ID: 1, Owner: "system", Currency: "EUR", Kind: db.AccountKindCash},
+		},
+		lookups: map[int64]int{},
+	}
+
+	items := []Item{
+		{Line: 2, FromAccountID: 7, ToAccountID: 12, Amount: 10, Currency: "EUR"},
+		{Line: 3, FromAccountID: 7, ToAccountID: 13, Amount: 10, Currency: "EUR"},
+		{Line: 4, FromAccountID: 8, ToAccountID: 12, Amount: 10, Currency: "USD"},
+		{Line: 5, FromAccountID: 12, ToAccountID: 7, Amount: 10, Currency: "EUR"},
+		{Line: 6, FromAccountID: 7, ToAccountID: 99, Amount: 10, Currency: "EUR"},
+		{Line: 7, FromAccountID: 7, ToAccountID: 7, Amount: 10, Currency: "EUR"},
+		{Line: 8, FromAccountID: 7, ToAccountID: 1, Amount: 10, Currency: "EUR"},
+		{Line: 9, FromAccountID: 7, ToAccountID: 14, Amount: 10, Currency: "GBP"},
+		{Line: 10, FromAccountID: 7, ToAccountID: 14, Amount: 10, Currency: "EUR"},
+	}
+
+	lineErrors, err := Validate(context.Background(), store, "alice", items)
+	require.NoError(t, err)
+	require.Equal(t, []LineError{
+		{Line: 3, Error: "account 13 is frozen"},
+		{Line: 4, Error: "account 12 currency mismatch: EUR vs USD"},
+		{Line: 5, Error: "account 12 doesn't belong to the authenticated user"},
+		{Line: 6, Error: "account 99 not found"},
+		{Line: 7, Error: "from and to account are the same"},
+		{Line: 8, Error: "account 1 is an internal account"},
+		{Line: 9, Error: `currency "GBP" is not supported; account 7 currency mismatch: EUR vs GBP; account 14 currency mismatch: EUR vs GBP`},
+	}, lineErrors)
+
+	// Every account is looked up once, however many lines it's on.
+	for id, n := range store.lookups {
+		require.Equal(t, 1, n, "account %d", id)
+	}
+}
@@ -0,0 +1,38 @@
+// Package blob stores files, like generated statements, outside the database. Store is
+// the interface the rest of the app uses, so the local disk can be swapped for an
+// object store without touching the callers.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps blobs under slash-separated keys like statements/7/2024-01.pdf.
+type Store interface {
+	// Put reads r to the end and stores it under key, replacing any blob already there.
+	// Nothing is stored if r returns an error: a reader can't see a half written blob.
+	// Returns the number of bytes stored.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the blob under key, or ErrNotFound. The caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether key is relative, clean and free of .. parts, so it can't
+// reach outside the store whatever the store keeps blobs in.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	return path.Clean(key) == key && key != "." && !strings.HasPrefix(key, "../") && key != ".."
+}
@@ -0,0 +1,90 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(filepath.Join(dir, "blobs"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	n, err := store.Put(ctx, "statements/7/2024-01.pdf", strings.NewReader("first"))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), n)
+
+	// Putting again replaces the blob.
+	_, err = store.Put(ctx, "statements/7/2024-01.pdf", strings.NewReader("second"))
+	require.NoError(t, err)
+
+	file, err := store.Open(ctx, "statements/7/2024-01.pdf")
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.Equal(t, "second", string(data))
+
+	require.NoError(t, store.Delete(ctx, "statements/7/2024-01.pdf"))
+	require.NoError(t, store.Delete(ctx, "statements/7/2024-01.pdf"))
+
+	_, err = store.Open(ctx, "statements/7/2024-01.pdf")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+// failingReader returns some data and then an error.
+type failingReader struct{ sent bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.sent {
+		return 0, errors.New("generator failed")
+	}
+	r.sent = true
+	return copy(p, "partial"), nil
+}
+
+func TestFileStorePutFails(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Put(ctx, "a/b.txt", strings.NewReader("old"))
+	require.NoError(t, err)
+
+	_, err = store.Put(ctx, "a/b.txt", &failingReader{})
+	require.Error(t, err)
+
+	// The old blob is untouched and no temporary file is left behind.
+	file, err := store.Open(ctx, "a/b.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	require.Equal(t, "old", string(data))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "a"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"a", "a/b.pdf", "statements/7/2024-01.pdf"} {
+		require.True(t, ValidKey(key), key)
+	}
+	for _, key := range []string{"", ".", "..", "../a", "a/../../b", "/etc/passwd", "a//b", "a/", `a\b`} {
+		require.False(t, ValidKey(key), key)
+	}
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	_, err = store.Put(context.Background(), "../escape", strings.NewReader("x"))
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
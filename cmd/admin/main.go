@@ -0,0 +1,84 @@
+// Command admin gives an existing user a role straight in the database. Only admins
+// can change roles over the API, so this is how the first admin of a new deployment
+// is made: register the user with POST /users, then run
+//
+//	go run ./cmd/admin -username alice
+//
+// It reads the same app.env as the server, and the change is written to the audit log
+// with cmd/admin as the actor.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/lib/pq"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// The audit log actor for role changes made by this command.
+const actor = "cmd/admin"
+
+func main() {
+	configPath := flag.String("config", ".", "directory holding app.env")
+	username := flag.String("username", "", "user to give the role to")
+	role := flag.String("role", util.AdminRole, "one of depositor, banker or admin")
+	flag.Parse()
+
+	if *username == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*configPath, *username, *role); err != nil {
+		slog.Error("cannot change role", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(configPath, username, role string) error {
+	if role != util.DepositorRole && role != util.BankerRole && role != util.AdminRole {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	// The internal accounts' owner can't log in, and must never act as anyone.
+	if username == db.SystemAccountOwner {
+		return fmt.Errorf("%s is reserved for the internal accounts", username)
+	}
+
+	config, err := util.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := db.Connect(ctx, config)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	defer conn.Close()
+
+	user, err := db.NewStore(conn).UpdateUserRoleTx(ctx, db.UpdateUserRoleTxParams{
+		Username: username,
+		Role:     role,
+		Actor:    actor,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("user %s doesn't exist, register it with POST /users first", username)
+		}
+		return err
+	}
+
+	slog.Info("role changed", slog.String("username", user.Username), slog.String("role", user.Role))
+	return nil
+}
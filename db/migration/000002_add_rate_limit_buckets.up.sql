@@ -0,0 +1,8 @@
+-- One row per rate limit key (route + client IP or user). tat is the GCRA
+-- "theoretical arrival time": the bucket is full again once now() passes it.
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tat" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "rate_limit_buckets" ("tat");
@@ -0,0 +1,32 @@
+CREATE TABLE "users" (
+  "username" varchar PRIMARY KEY,
+  "hashed_password" varchar NOT NULL,
+  "full_name" varchar NOT NULL,
+  "email" varchar UNIQUE NOT NULL,
+  "role" varchar NOT NULL DEFAULT 'depositor',
+  "failed_login_attempts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz,
+  "password_changed_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Append only. Rows are never updated or deleted.
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "entity_type" varchar NOT NULL,
+  "entity_id" varchar NOT NULL,
+  "request_id" varchar NOT NULL DEFAULT '',
+  "ip" varchar NOT NULL DEFAULT '',
+  "metadata" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_events" ("entity_type", "entity_id");
+
+CREATE INDEX ON "audit_events" ("actor");
+
+COMMENT ON COLUMN "users"."failed_login_attempts" IS 'Failed logins since the last successful one.';
+
+COMMENT ON COLUMN "users"."locked_until" IS 'Logins are refused until this time. NULL when not locked.';
@@ -0,0 +1,23 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "users" ADD COLUMN "totp_enabled" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "users" ADD COLUMN "totp_last_used_step" bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE "totp_recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "totp_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "totp_recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'Base32 TOTP secret. Set at enrollment, only used once totp_enabled is true.';
+
+COMMENT ON COLUMN "users"."totp_last_used_step" IS 'Time step of the last accepted code, so a code cannot be replayed.';
+
+COMMENT ON COLUMN "totp_recovery_codes"."code_hash" IS 'SHA-256 of the normalized recovery code.';
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "frozen";
+
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_role_check";
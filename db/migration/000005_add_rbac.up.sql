@@ -0,0 +1,5 @@
+ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('depositor', 'banker', 'admin'));
+
+ALTER TABLE "accounts" ADD COLUMN "frozen" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "accounts"."frozen" IS 'Frozen accounts can not send or receive transfers.';
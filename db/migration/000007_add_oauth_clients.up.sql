@@ -0,0 +1,17 @@
+CREATE TABLE "oauth_clients" (
+  "client_id" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "owner" varchar NOT NULL,
+  "hashed_secret" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL DEFAULT '{}',
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "oauth_clients" ("owner");
+
+COMMENT ON COLUMN "oauth_clients"."owner" IS 'The user the client acts as.';
+
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'The most a token issued to the client can be granted.';
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS "audit_events_append_only" ON "audit_events";
+
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "after";
+
+ALTER TABLE "audit_events" DROP COLUMN IF EXISTS "before";
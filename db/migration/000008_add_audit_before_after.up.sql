@@ -0,0 +1,22 @@
+ALTER TABLE "audit_events" ADD COLUMN "before" jsonb NOT NULL DEFAULT 'null';
+
+ALTER TABLE "audit_events" ADD COLUMN "after" jsonb NOT NULL DEFAULT 'null';
+
+CREATE INDEX ON "audit_events" ("action");
+
+CREATE INDEX ON "audit_events" ("created_at");
+
+COMMENT ON COLUMN "audit_events"."before" IS 'The entity before the change. null when it was created.';
+
+COMMENT ON COLUMN "audit_events"."after" IS 'The entity after the change. null when it was deleted.';
+
+-- The audit log is append only, so updates and deletes are refused outright.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_append_only"
+BEFORE UPDATE OR DELETE ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
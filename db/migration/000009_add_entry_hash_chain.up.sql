@@ -0,0 +1,35 @@
+ALTER TABLE "entries" ADD COLUMN "prev_hash" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "entries" ADD COLUMN "hash" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "entries" ("account_id", "id");
+
+COMMENT ON COLUMN "entries"."prev_hash" IS 'Hash of the account''s previous entry, or 64 zeros for its first one.';
+
+COMMENT ON COLUMN "entries"."hash" IS 'SHA-256 of prev_hash, id, account_id, amount and created_at. See ledger.EntryHash.';
+
+-- Chain the entries that already exist, oldest first for each account.
+-- This has to hash exactly like ledger.EntryHash: the fields joined with |,
+-- with created_at as Unix microseconds.
+DO $$
+DECLARE
+  e RECORD;
+  prev varchar;
+  last_account bigint;
+BEGIN
+  FOR e IN SELECT * FROM entries ORDER BY account_id, id LOOP
+    IF last_account IS DISTINCT FROM e.account_id THEN
+      prev := repeat('0', 64);
+      last_account := e.account_id;
+    END IF;
+
+    UPDATE entries
+    SET prev_hash = prev,
+        hash = encode(sha256(convert_to(concat_ws('|',
+          prev, e.id, e.account_id, e.amount,
+          (extract(epoch FROM e.created_at) * 1000000)::bigint
+        ), 'UTF8')), 'hex')
+    WHERE id = e.id
+    RETURNING hash INTO prev;
+  END LOOP;
+END $$;
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS "adjustments";
+
+-- The internal accounts' entries have to go before the accounts do. The customer side
+-- of every adjustment stays, so customer balances are kept but no longer add up to zero
+-- with the bank's accounts.
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "kind" <> 'customer');
+
+DELETE FROM "accounts" WHERE "kind" <> 'customer';
+
+DROP INDEX IF EXISTS "accounts_kind_currency_idx";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "owner_display_name";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "nickname";
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_kind_check";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "kind";
@@ -0,0 +1,49 @@
+ALTER TABLE "accounts" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'customer';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_kind_check" CHECK ("kind" IN ('customer', 'suspense'));
+
+ALTER TABLE "accounts" ADD COLUMN "nickname" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "accounts" ADD COLUMN "owner_display_name" varchar NOT NULL DEFAULT '';
+
+-- There is one internal account of each kind per currency.
+CREATE UNIQUE INDEX ON "accounts" ("kind", "currency") WHERE "kind" <> 'customer';
+
+COMMENT ON COLUMN "accounts"."kind" IS 'customer for accounts people own. Internal accounts belong to the bank and are owned by system.';
+
+COMMENT ON COLUMN "accounts"."nickname" IS 'A name the owner gives the account.';
+
+COMMENT ON COLUMN "accounts"."owner_display_name" IS 'How the owner is shown on statements and to other users.';
+
+-- The other side of every balance adjustment.
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind") VALUES
+  ('system', 0, 'USD', 'suspense'),
+  ('system', 0, 'EUR', 'suspense'),
+  ('system', 0, 'CAD', 'suspense');
+
+CREATE TABLE "adjustments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "suspense_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" <> 0),
+  "reason_code" varchar NOT NULL CHECK ("reason_code" IN ('correction', 'fee', 'fee_refund', 'goodwill', 'chargeback', 'write_off')),
+  "memo" varchar NOT NULL DEFAULT '',
+  "operator" varchar NOT NULL,
+  "entry_id" bigint NOT NULL,
+  "suspense_entry_id" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("suspense_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("suspense_entry_id") REFERENCES "entries" ("id");
+
+CREATE INDEX ON "adjustments" ("account_id");
+
+COMMENT ON COLUMN "adjustments"."amount" IS 'Added to the account and taken from the suspense account. Negative to take money off the account.';
+
+COMMENT ON COLUMN "adjustments"."operator" IS 'The banker who made the adjustment.';
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS "cash_transactions";
+
+-- The cash accounts' entries have to go before the accounts do. The customer side of
+-- every deposit and withdrawal stays, so customer balances are kept.
+DELETE FROM "entries" WHERE "account_id" IN (SELECT "id" FROM "accounts" WHERE "kind" = 'cash');
+
+DELETE FROM "accounts" WHERE "kind" = 'cash';
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_kind_check";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_kind_check" CHECK ("kind" IN ('customer', 'suspense'));
@@ -0,0 +1,36 @@
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_kind_check";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_kind_check" CHECK ("kind" IN ('customer', 'suspense', 'cash'));
+
+-- Cash deposited is taken from the cash account and cash withdrawn is put back, so its
+-- balance is minus the cash held by customers and every account still adds up to zero.
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind") VALUES
+  ('system', 0, 'USD', 'cash'),
+  ('system', 0, 'EUR', 'cash'),
+  ('system', 0, 'CAD', 'cash');
+
+CREATE TABLE "cash_transactions" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "cash_account_id" bigint NOT NULL,
+  "kind" varchar NOT NULL CHECK ("kind" IN ('deposit', 'withdrawal')),
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "actor" varchar NOT NULL,
+  "entry_id" bigint NOT NULL,
+  "cash_entry_id" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "cash_transactions" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "cash_transactions" ADD FOREIGN KEY ("cash_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "cash_transactions" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "cash_transactions" ADD FOREIGN KEY ("cash_entry_id") REFERENCES "entries" ("id");
+
+CREATE INDEX ON "cash_transactions" ("account_id", "kind", "created_at");
+
+COMMENT ON COLUMN "cash_transactions"."amount" IS 'Always positive. kind says which way the money went.';
+
+COMMENT ON COLUMN "cash_transactions"."actor" IS 'The user who made the deposit or withdrawal.';
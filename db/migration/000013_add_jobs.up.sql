@@ -0,0 +1,28 @@
+CREATE TABLE "jobs" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "payload" jsonb NOT NULL DEFAULT '{}',
+  "status" varchar NOT NULL DEFAULT 'queued' CHECK ("status" IN ('queued', 'running', 'succeeded', 'dead')),
+  "attempts" int NOT NULL DEFAULT 0,
+  "max_attempts" int NOT NULL DEFAULT 5 CHECK ("max_attempts" > 0),
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "finished_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- What the workers poll: queued jobs, and running ones whose worker has gone quiet.
+CREATE INDEX ON "jobs" ("run_at") WHERE "status" = 'queued';
+
+CREATE INDEX ON "jobs" ("locked_until") WHERE "status" = 'running';
+
+CREATE INDEX ON "jobs" ("status");
+
+COMMENT ON COLUMN "jobs"."kind" IS 'Picks the handler. See the jobs package.';
+
+COMMENT ON COLUMN "jobs"."attempts" IS 'Times the job has been handed to a worker, including the current one.';
+
+COMMENT ON COLUMN "jobs"."run_at" IS 'The job is not picked up before this. Set to the next try after a failure.';
+
+COMMENT ON COLUMN "jobs"."locked_until" IS 'Visibility timeout of a running job. After this another worker may take it.';
@@ -0,0 +1,22 @@
+CREATE TABLE "outbox" (
+  "id" bigserial PRIMARY KEY,
+  "event_type" varchar NOT NULL,
+  "aggregate_type" varchar NOT NULL,
+  "aggregate_id" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "attempts" int NOT NULL DEFAULT 0,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "published_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- What the relay polls.
+CREATE INDEX ON "outbox" ("id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox" ("aggregate_type", "aggregate_id");
+
+COMMENT ON COLUMN "outbox"."event_type" IS 'Domain event name, e.g. TransferPosted. See db.Event* constants.';
+
+COMMENT ON COLUMN "outbox"."attempts" IS 'Failed tries to publish the event.';
+
+COMMENT ON COLUMN "outbox"."published_at" IS 'When the relay handed the event to the publisher. NULL until then.';
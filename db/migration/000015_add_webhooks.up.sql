@@ -0,0 +1,57 @@
+CREATE TABLE "webhook_subscriptions" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "url" varchar NOT NULL,
+  "event_types" varchar[] NOT NULL,
+  "secret" varchar NOT NULL,
+  "active" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_subscriptions" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "webhook_subscriptions" ("owner");
+
+COMMENT ON COLUMN "webhook_subscriptions"."event_types" IS 'Webhook event names like transfer.posted. See the webhooks package.';
+
+COMMENT ON COLUMN "webhook_subscriptions"."secret" IS 'Signs every delivery. Kept in plaintext because the signature needs it.';
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "subscription_id" bigint NOT NULL,
+  "event_id" bigint NOT NULL,
+  "event_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'succeeded', 'failed')),
+  "attempts" int NOT NULL DEFAULT 0,
+  "last_status_code" int,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("event_id") REFERENCES "outbox" ("id");
+
+-- The relay publishes at least once, so the same event must not be delivered twice.
+CREATE UNIQUE INDEX ON "webhook_deliveries" ("subscription_id", "event_id");
+
+COMMENT ON COLUMN "webhook_deliveries"."payload" IS 'The exact body sent, so a replay sends the same thing.';
+
+COMMENT ON COLUMN "webhook_deliveries"."attempts" IS 'Attempts since the delivery was created or last replayed.';
+
+CREATE TABLE "webhook_delivery_attempts" (
+  "id" bigserial PRIMARY KEY,
+  "delivery_id" bigint NOT NULL,
+  "status_code" int,
+  "error" varchar NOT NULL DEFAULT '',
+  "duration_ms" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_delivery_attempts" ADD FOREIGN KEY ("delivery_id") REFERENCES "webhook_deliveries" ("id");
+
+CREATE INDEX ON "webhook_delivery_attempts" ("delivery_id");
+
+COMMENT ON COLUMN "webhook_delivery_attempts"."status_code" IS 'HTTP status the endpoint answered with. NULL when there was no answer.';
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS "adjustments_suspense_entry_id_idx";
+
+DROP INDEX IF EXISTS "adjustments_entry_id_idx";
+
+DROP INDEX IF EXISTS "cash_transactions_cash_entry_id_idx";
+
+DROP INDEX IF EXISTS "cash_transactions_entry_id_idx";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_entry_id";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "from_entry_id";
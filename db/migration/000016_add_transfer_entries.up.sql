@@ -0,0 +1,37 @@
+ALTER TABLE "transfers" ADD COLUMN "from_entry_id" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "to_entry_id" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("from_entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("to_entry_id") REFERENCES "entries" ("id");
+
+CREATE INDEX ON "transfers" ("from_entry_id");
+
+CREATE INDEX ON "transfers" ("to_entry_id");
+
+CREATE INDEX ON "cash_transactions" ("entry_id");
+
+CREATE INDEX ON "cash_transactions" ("cash_entry_id");
+
+CREATE INDEX ON "adjustments" ("entry_id");
+
+CREATE INDEX ON "adjustments" ("suspense_entry_id");
+
+COMMENT ON COLUMN "transfers"."from_entry_id" IS 'The entry taking the money off from_account_id. NULL for old transfers that could not be matched.';
+
+COMMENT ON COLUMN "transfers"."to_entry_id" IS 'The entry adding the money to to_account_id. NULL for old transfers that could not be matched.';
+
+-- Older transfers were not linked to their entries. TransferTx creates both entries
+-- in the same transaction as the transfer, so they share its created_at and amount.
+UPDATE transfers t
+SET from_entry_id = (
+      SELECT e.id FROM entries e
+      WHERE e.account_id = t.from_account_id AND e.amount = -t.amount AND e.created_at = t.created_at
+      ORDER BY e.id LIMIT 1
+    ),
+    to_entry_id = (
+      SELECT e.id FROM entries e
+      WHERE e.account_id = t.to_account_id AND e.amount = t.amount AND e.created_at = t.created_at
+      ORDER BY e.id LIMIT 1
+    );
@@ -0,0 +1,23 @@
+CREATE TABLE "statements" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period_start" timestamptz NOT NULL,
+  "period_end" timestamptz NOT NULL,
+  "format" varchar NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'ready')),
+  "storage_path" varchar NOT NULL DEFAULT '',
+  "checksum" varchar NOT NULL DEFAULT '',
+  "size_bytes" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz
+);
+
+ALTER TABLE "statements" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE UNIQUE INDEX ON "statements" ("account_id", "period_start", "period_end");
+
+COMMENT ON COLUMN "statements"."period_end" IS 'Not included in the period, so a month ends at midnight on the first of the next.';
+
+COMMENT ON COLUMN "statements"."storage_path" IS 'Key of the file in blob storage. Empty until the statement is ready.';
+
+COMMENT ON COLUMN "statements"."checksum" IS 'Hex SHA-256 of the stored file.';
@@ -0,0 +1,50 @@
+CREATE TABLE "batches" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "mode" varchar NOT NULL CHECK ("mode" IN ('all_or_nothing', 'best_effort')),
+  "format" varchar NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'completed', 'partially_completed', 'failed')),
+  "item_count" int NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz
+);
+
+ALTER TABLE "batches" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "batches" ("owner");
+
+COMMENT ON COLUMN "batches"."mode" IS 'all_or_nothing makes every transfer or none of them. best_effort makes each one that can be made.';
+
+COMMENT ON COLUMN "batches"."format" IS 'The format of the uploaded file: csv or pain.001.';
+
+CREATE TABLE "batch_items" (
+  "id" bigserial PRIMARY KEY,
+  "batch_id" bigint NOT NULL,
+  "line" int NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "reference" varchar NOT NULL DEFAULT '',
+  "status" varchar NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'succeeded', 'failed', 'skipped')),
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT ''
+);
+
+ALTER TABLE "batch_items" ADD FOREIGN KEY ("batch_id") REFERENCES "batches" ("id");
+
+ALTER TABLE "batch_items" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "batch_items" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "batch_items" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE UNIQUE INDEX ON "batch_items" ("batch_id", "line");
+
+COMMENT ON COLUMN "batch_items"."line" IS 'Where the item is in the file: the CSV line number, or the position of the transaction in a pain.001 file.';
+
+COMMENT ON COLUMN "batch_items"."reference" IS 'The payer''s own reference, e.g. the pain.001 EndToEndId.';
+
+COMMENT ON COLUMN "batch_items"."status" IS 'skipped means another item of an all_or_nothing batch failed, so none were made.';
+
+COMMENT ON COLUMN "batch_items"."transfer_id" IS 'The transfer made for a succeeded item.';
@@ -0,0 +1,8 @@
+-- The bank's internal accounts are owned by system. Taking the username means nobody can
+-- register as system and become their owner. The password is not a bcrypt hash, so
+-- nothing ever matches it and the user can't log in.
+--
+-- This fails if someone has already registered as system. Look at what they did with
+-- the internal accounts before renaming or removing that user.
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('system', '!', 'Bank internal accounts', 'system@simplebank.invalid');
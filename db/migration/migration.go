@@ -0,0 +1,40 @@
+// Package migration embeds the SQL migration files so the app knows which
+// schema version it was built against.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// LatestVersion returns the highest version number among the migration files,
+// e.g. 1 for 000001_init_schema.up.sql. This is the version golang-migrate
+// records in schema_migrations once every migration has been applied.
+func LatestVersion() (int64, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			continue
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", entry.Name(), err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
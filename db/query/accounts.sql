@@ -18,18 +18,39 @@ WHERE id = $1 LIMIT 1
 -- This means we dont update the Key or ID. This will avoid deadlock.
 FOR NO KEY UPDATE;
 
+-- The bank's own account of the given kind, e.g. the suspense account, for a currency.
+-- name: GetSystemAccount :one
+SELECT * FROM accounts
+WHERE kind = $1 AND currency = $2
+LIMIT 1;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountsByOwner :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- Bankers freeze an account to stop money moving in or out of it.
+-- name: SetAccountFrozen :one
+UPDATE accounts
+SET frozen = sqlc.arg(frozen)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 
--- We only want to update the balance. The owner and currency stay the same.
+-- Only the descriptive fields can be updated. The balance only changes through
+-- entries (transfers and adjustments), and the owner and currency stay the same.
 -- We return the updated data to the client.
 -- name: UpdateAccount :one
 UPDATE accounts
-SET balance = $2
+SET nickname = $2, owner_display_name = $3
 WHERE id = $1
 RETURNING *;
 
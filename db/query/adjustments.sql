@@ -0,0 +1,21 @@
+-- name: CreateAdjustment :one
+INSERT INTO adjustments (
+  account_id,
+  suspense_account_id,
+  amount,
+  reason_code,
+  memo,
+  operator,
+  entry_id,
+  suspense_entry_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: ListAdjustmentsByAccount :many
+SELECT * FROM adjustments
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
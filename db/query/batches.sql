@@ -0,0 +1,57 @@
+-- Only a pending batch is finished, and only once.
+-- name: CompleteBatch :one
+UPDATE batches
+SET status = $2, completed_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: CreateBatch :one
+INSERT INTO batches (
+  owner,
+  mode,
+  format,
+  item_count
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: CreateBatchItem :one
+INSERT INTO batch_items (
+  batch_id,
+  line,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  reference
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetBatch :one
+SELECT * FROM batches
+WHERE id = $1 LIMIT 1;
+
+-- Waits for a run of the batch that is already going to finish.
+-- name: GetBatchForUpdate :one
+SELECT * FROM batches
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetBatchItemForUpdate :one
+SELECT * FROM batch_items
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListBatchItems :many
+SELECT * FROM batch_items
+WHERE batch_id = $1
+ORDER BY line;
+
+-- name: UpdateBatchItem :one
+UPDATE batch_items
+SET status = $2, transfer_id = $3, error = $4
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,26 @@
+-- name: CreateCashTransaction :one
+INSERT INTO cash_transactions (
+  account_id,
+  cash_account_id,
+  kind,
+  amount,
+  actor,
+  entry_id,
+  cash_entry_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: ListCashTransactionsByAccount :many
+SELECT * FROM cash_transactions
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- Used for the daily withdrawal limit.
+-- name: SumWithdrawalsSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM cash_transactions
+WHERE account_id = $1 AND kind = 'withdrawal' AND created_at > $2;
@@ -11,6 +11,13 @@ RETURNING *;
 SELECT * FROM entries
 WHERE id = $1 LIMIT 1;
 
+-- The newest entry of an account, which the next entry chains to.
+-- name: GetEntryChainHead :one
+SELECT * FROM entries
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1;
+
 -- name: ListEntries :many
 SELECT * FROM entries
 WHERE account_id = $1
@@ -19,3 +26,18 @@ LIMIT $2
 OFFSET $3;
 -- We are filtering by account_id. We only want entries from that account.
 -- You should not be able to modify and delete an entry.
+
+-- Walks an account's entries in chain order, a page at a time.
+-- name: ListEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- Entries are never modified, except that the hash is filled in straight after
+-- CreateEntry, in the same transaction, once the id and created_at are known.
+-- name: SetEntryHash :one
+UPDATE entries
+SET prev_hash = sqlc.arg(prev_hash), hash = sqlc.arg(hash)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,83 @@
+-- Only succeeds for the worker still holding the job, identified by its attempt.
+-- name: CompleteJob :one
+UPDATE jobs
+SET
+  status = 'succeeded',
+  locked_until = NULL,
+  finished_at = now()
+WHERE id = $1 AND attempts = $2 AND status = 'running'
+RETURNING *;
+
+-- Gives up on the job. Dead jobs stay in the table until an admin requeues them.
+-- name: DeadLetterJob :one
+UPDATE jobs
+SET
+  status = 'dead',
+  locked_until = NULL,
+  last_error = $3,
+  finished_at = now()
+WHERE id = $1 AND attempts = $2 AND status = 'running'
+RETURNING *;
+
+-- Takes the oldest job that is due, or whose visibility timeout ran out, and that
+-- no other worker has locked. It stays invisible to other workers until locked_until.
+-- name: DequeueJob :one
+UPDATE jobs
+SET
+  status = 'running',
+  attempts = attempts + 1,
+  locked_until = sqlc.arg(locked_until)
+WHERE id = (
+  SELECT id FROM jobs
+  WHERE (status = 'queued' AND run_at <= sqlc.arg(now))
+     OR (status = 'running' AND locked_until <= sqlc.arg(now))
+  ORDER BY run_at, id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: EnqueueJob :one
+INSERT INTO jobs (
+  kind,
+  payload,
+  max_attempts,
+  run_at
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListJobsByStatus :many
+SELECT * FROM jobs
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- Puts a dead job back in the queue with a fresh set of attempts.
+-- name: RequeueDeadJob :one
+UPDATE jobs
+SET
+  status = 'queued',
+  attempts = 0,
+  run_at = now(),
+  last_error = '',
+  finished_at = NULL
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
+
+-- Puts a failed job back in the queue to be tried again at run_at.
+-- name: RetryJob :one
+UPDATE jobs
+SET
+  status = 'queued',
+  locked_until = NULL,
+  run_at = $3,
+  last_error = $4
+WHERE id = $1 AND attempts = $2 AND status = 'running'
+RETURNING *;
@@ -0,0 +1,4 @@
+-- Tells the sessions LISTENing on the channel about a change. Postgres only sends the
+-- notification when the transaction commits, and drops it on rollback.
+-- name: Notify :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
@@ -0,0 +1,28 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+  client_id,
+  name,
+  owner,
+  hashed_secret,
+  scopes
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE client_id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at
+LIMIT $1
+OFFSET $2;
+
+-- Tokens already issued to the client stop passing introspection.
+-- name: RevokeOAuthClient :one
+UPDATE oauth_clients
+SET revoked_at = now()
+WHERE client_id = $1 AND revoked_at IS NULL
+RETURNING *;
@@ -0,0 +1,43 @@
+-- Takes the oldest unpublished events no other relay has locked. The lock is held
+-- until the relay's transaction ends. It is NO KEY UPDATE so rows referencing the
+-- events, like webhook deliveries made while publishing, can still be inserted.
+-- name: ClaimOutboxEvents :many
+SELECT * FROM outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  aggregate_type,
+  aggregate_id,
+  payload
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetOutboxEvent :one
+SELECT * FROM outbox
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOutboxEventsByAggregate :many
+SELECT * FROM outbox
+WHERE aggregate_type = $1 AND aggregate_id = $2
+ORDER BY id
+LIMIT $3
+OFFSET $4;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now()
+WHERE id = $1;
+
+-- name: RecordOutboxEventFailure :exec
+UPDATE outbox
+SET
+  attempts = attempts + 1,
+  last_error = $2
+WHERE id = $1;
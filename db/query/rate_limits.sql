@@ -0,0 +1,23 @@
+-- Takes one token from the bucket using GCRA. A new key starts with a full bucket.
+-- When the bucket is empty the WHERE clause skips the update and no row is returned,
+-- so the caller gets sql.ErrNoRows and the request is denied.
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS b (
+  key,
+  tat
+) VALUES (
+  sqlc.arg(key), now() + make_interval(secs => sqlc.arg(emission_interval)::float8)
+)
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(b.tat, now()) + make_interval(secs => sqlc.arg(emission_interval)::float8)
+WHERE GREATEST(b.tat, now()) - make_interval(secs => sqlc.arg(burst_tolerance)::float8) <= now()
+RETURNING tat, now()::timestamptz AS db_now;
+
+-- name: GetRateLimitBucket :one
+SELECT tat, now()::timestamptz AS db_now FROM rate_limit_buckets
+WHERE key = $1 LIMIT 1;
+
+-- Buckets whose tat has passed are full again, so they can be dropped.
+-- name: DeleteExpiredRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE tat < now();
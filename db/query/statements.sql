@@ -0,0 +1,105 @@
+-- Only the job generating a pending statement finishes it.
+-- name: CompleteStatement :one
+UPDATE statements
+SET
+  status = 'ready',
+  storage_path = sqlc.arg(storage_path),
+  checksum = sqlc.arg(checksum),
+  size_bytes = sqlc.arg(size_bytes),
+  completed_at = now()
+WHERE id = sqlc.arg(id) AND status = 'pending'
+RETURNING *;
+
+-- Adds a pending statement for the period to every active account that doesn't have
+-- one yet. Active means a customer account that had an entry in the period or held
+-- money at its end. Two instances running this at once can't add the same statement twice.
+-- name: CreatePendingStatements :many
+INSERT INTO statements (
+  account_id,
+  period_start,
+  period_end,
+  format
+)
+SELECT a.id, sqlc.arg(period_start), sqlc.arg(period_end), sqlc.arg(format)
+FROM accounts a
+WHERE a.kind = 'customer'
+  AND a.created_at < sqlc.arg(period_end)
+  AND (
+    EXISTS (SELECT 1 FROM entries e WHERE e.account_id = a.id AND e.created_at >= sqlc.arg(period_start) AND e.created_at < sqlc.arg(period_end))
+    OR a.balance <> COALESCE((SELECT SUM(e.amount) FROM entries e WHERE e.account_id = a.id AND e.created_at >= sqlc.arg(period_end)), 0)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM statements s
+    WHERE s.account_id = a.id AND s.period_start = sqlc.arg(period_start) AND s.period_end = sqlc.arg(period_end)
+  )
+ORDER BY a.id
+LIMIT sqlc.arg('limit')
+ON CONFLICT (account_id, period_start, period_end) DO NOTHING
+RETURNING *;
+
+-- name: GetStatement :one
+SELECT * FROM statements
+WHERE id = $1 LIMIT 1;
+
+-- The balance an account had when a period started, and what came in and went out
+-- during it. The opening balance is worked back from the current balance, so it also
+-- holds for accounts whose starting balance was set without an entry.
+-- name: GetStatementTotals :one
+SELECT
+  (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS opening_balance,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.created_at < sqlc.arg(period_end) AND e.amount > 0), 0)::bigint AS total_credits,
+  COALESCE(SUM(-e.amount) FILTER (WHERE e.created_at < sqlc.arg(period_end) AND e.amount < 0), 0)::bigint AS total_debits,
+  COUNT(e.id) FILTER (WHERE e.created_at < sqlc.arg(period_end)) AS entry_count
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= sqlc.arg(period_start)
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id;
+
+-- A page of an account's entries in a period, in chain order, each with what made it
+-- (a transfer, a deposit or withdrawal, or an adjustment) and the account on the other side.
+-- name: ListStatementEntries :many
+SELECT
+  e.id,
+  e.amount,
+  e.created_at,
+  COALESCE(link.kind, '')::varchar AS kind,
+  COALESCE(link.reference_id, 0)::bigint AS reference_id,
+  COALESCE(link.reason, '')::varchar AS reason,
+  COALESCE(link.counterparty_account_id, 0)::bigint AS counterparty_account_id,
+  COALESCE(c.owner, '')::varchar AS counterparty_owner
+FROM entries e
+LEFT JOIN LATERAL (
+  SELECT 'transfer' AS kind, t.id AS reference_id, '' AS reason, t.to_account_id AS counterparty_account_id
+  FROM transfers t WHERE t.from_entry_id = e.id
+  UNION ALL
+  SELECT 'transfer', t.id, '', t.from_account_id
+  FROM transfers t WHERE t.to_entry_id = e.id
+  UNION ALL
+  SELECT ct.kind, ct.id, '', ct.cash_account_id
+  FROM cash_transactions ct WHERE ct.entry_id = e.id
+  UNION ALL
+  SELECT ct.kind, ct.id, '', ct.account_id
+  FROM cash_transactions ct WHERE ct.cash_entry_id = e.id
+  UNION ALL
+  SELECT 'adjustment', ad.id, ad.reason_code, ad.suspense_account_id
+  FROM adjustments ad WHERE ad.entry_id = e.id
+  UNION ALL
+  SELECT 'adjustment', ad.id, ad.reason_code, ad.account_id
+  FROM adjustments ad WHERE ad.suspense_entry_id = e.id
+  LIMIT 1
+) link ON true
+LEFT JOIN accounts c ON c.id = link.counterparty_account_id
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(period_start)
+  AND e.created_at < sqlc.arg(period_end)
+  AND e.id > sqlc.arg(after_id)
+ORDER BY e.id
+LIMIT sqlc.arg('limit');
+
+-- Newest period first.
+-- name: ListStatementsByAccount :many
+SELECT * FROM statements
+WHERE account_id = $1
+ORDER BY period_start DESC, id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,19 @@
+-- name: CreateTOTPRecoveryCode :one
+INSERT INTO totp_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+)
+RETURNING *;
+
+-- name: DeleteTOTPRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE username = $1;
+
+-- Marks an unused code as used. No row comes back for a wrong or already used code.
+-- name: UseTOTPRecoveryCode :one
+UPDATE totp_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
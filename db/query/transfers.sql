@@ -19,4 +19,12 @@ ORDER BY id
 LIMIT $3
 OFFSET $4;
 
--- Editing and deleting is the same as entries.
\ No newline at end of file
+-- Editing and deleting is the same as entries.
+
+-- Transfers are never modified, except that the entry ids are filled in straight
+-- after the entries are created, in the same transaction.
+-- name: SetTransferEntries :one
+UPDATE transfers
+SET from_entry_id = sqlc.arg(from_entry_id), to_entry_id = sqlc.arg(to_entry_id)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,70 @@
+-- name: CreateUser :one
+INSERT INTO users (
+  username,
+  hashed_password,
+  full_name,
+  email
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1;
+
+-- Locks the row so concurrent failed logins are counted one after the other.
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY username
+LIMIT $1
+OFFSET $2;
+
+-- Counts a failed login and blocks further attempts until locked_until.
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1,
+  locked_until = sqlc.arg(locked_until)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- Clears the failed login counter and any lock, after a good login or an admin unlock.
+-- name: ResetFailedLogins :one
+UPDATE users
+SET failed_login_attempts = 0,
+  locked_until = NULL
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = sqlc.arg(role)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- Starts a new enrollment. TOTP stays off until a code from the new secret is confirmed.
+-- name: SetUserTOTPSecret :one
+UPDATE users
+SET totp_secret = sqlc.arg(totp_secret),
+  totp_enabled = false,
+  totp_last_used_step = 0
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+SET totp_enabled = true
+WHERE username = $1
+RETURNING *;
+
+-- Only moves forward, so no row comes back when a code's step was already used.
+-- name: UpdateUserTOTPLastUsedStep :one
+UPDATE users
+SET totp_last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND totp_last_used_step < sqlc.arg(step)
+RETURNING *;
@@ -0,0 +1,90 @@
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  subscription_id,
+  event_id,
+  event_type,
+  payload
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (subscription_id, event_id) DO NOTHING
+RETURNING *;
+
+-- name: CreateWebhookDeliveryAttempt :one
+INSERT INTO webhook_delivery_attempts (
+  delivery_id,
+  status_code,
+  error,
+  duration_ms
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  owner,
+  url,
+  event_types,
+  secret
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: DeactivateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET active = false
+WHERE id = $1 AND owner = $2 AND active
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookDeliveryForUpdate :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- The active subscriptions of any of the owners that want this event.
+-- name: ListMatchingWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = ANY(sqlc.arg(owners)::varchar[])
+  AND sqlc.arg(event_type)::varchar = ANY(event_types)
+  AND active
+ORDER BY id;
+
+-- name: ListWebhookDeliveriesBySubscription :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY id;
+
+-- name: ListWebhookSubscriptionsByOwner :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET
+  status = $2,
+  attempts = $3,
+  last_status_code = $4,
+  last_error = $5,
+  delivered_at = $6
+WHERE id = $1
+RETURNING *;
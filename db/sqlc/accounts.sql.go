@@ -13,7 +13,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, frozen, kind, nickname, owner_display_name
 `
 
 type AddAccountBalanceParams struct {
@@ -32,6 +32,10 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
 	)
 	return i, err
 }
@@ -44,7 +48,7 @@ INSERT INTO accounts (
 ) VALUES (
   $1, $2, $3
 )
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, frozen, kind, nickname, owner_display_name
 `
 
 type CreateAccountParams struct {
@@ -62,6 +66,10 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
 	)
 	return i, err
 }
@@ -77,7 +85,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, frozen FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -90,12 +98,16 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, frozen FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -110,12 +122,45 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
+	)
+	return i, err
+}
+
+const getSystemAccount = `-- name: GetSystemAccount :one
+SELECT id, owner, balance, currency, created_at, frozen, kind, nickname, owner_display_name FROM accounts
+WHERE kind = $1 AND currency = $2
+LIMIT 1
+`
+
+type GetSystemAccountParams struct {
+	Kind     string `json:"kind"`
+	Currency string `json:"currency"`
+}
+
+// The bank's own account of the given kind, e.g. the suspense account, for a currency.
+func (q *Queries) GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getSystemAccount, arg.Kind, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, frozen FROM accounts
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -141,6 +186,57 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.Frozen,
+			&i.Kind,
+			&i.Nickname,
+			&i.OwnerDisplayName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsByOwner = `-- name: ListAccountsByOwner :many
+SELECT id, owner, balance, currency, created_at, frozen FROM accounts
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListAccountsByOwnerParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsByOwner, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.Frozen,
+			&i.Kind,
+			&i.Nickname,
+			&i.OwnerDisplayName,
 		); err != nil {
 			return nil, err
 		}
@@ -155,22 +251,54 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 	return items, nil
 }
 
+const setAccountFrozen = `-- name: SetAccountFrozen :one
+UPDATE accounts
+SET frozen = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, frozen, kind, nickname, owner_display_name
+`
+
+type SetAccountFrozenParams struct {
+	Frozen bool  `json:"frozen"`
+	ID     int64 `json:"id"`
+}
+
+// Bankers freeze an account to stop money moving in or out of it.
+func (q *Queries) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setAccountFrozen, arg.Frozen, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
+	)
+	return i, err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
-SET balance = $2
+SET nickname = $2, owner_display_name = $3
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, frozen, kind, nickname, owner_display_name
 `
 
 type UpdateAccountParams struct {
-	ID      int64 `json:"id"`
-	Balance int64 `json:"balance"`
+	ID               int64  `json:"id"`
+	Nickname         string `json:"nickname"`
+	OwnerDisplayName string `json:"owner_display_name"`
 }
 
-// We only want to update the balance. The owner and currency stay the same.
+// Only the descriptive fields can be updated. The balance only changes through
+// entries (transfers and adjustments), and the owner and currency stay the same.
 // We return the updated data to the client.
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Balance)
+	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Nickname, arg.OwnerDisplayName)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -178,6 +306,10 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Frozen,
+		&i.Kind,
+		&i.Nickname,
+		&i.OwnerDisplayName,
 	)
 	return i, err
 }
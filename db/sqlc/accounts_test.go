@@ -66,7 +66,8 @@ func TestUpdateAccount(t *testing.T) {
 
 	arg := UpdateAccountParams{
 		ID: 	 account1.ID,
-		Balance: util.RandomMoney(),
+		Nickname: util.RandomString(8),
+		OwnerDisplayName: util.RandomOwner(),
 	}
 
 	// Updating the account based on the inputted arguements.
@@ -77,7 +78,10 @@ func TestUpdateAccount(t *testing.T) {
 	require.Equal(t, account1.ID, account2.ID)
 	require.Equal(t, account1.Owner, account2.Owner)
 	// We compare what the args inputted by the user are to the found account (account2)
-	require.Equal(t, arg.Balance, account2.Balance)
+	require.Equal(t, arg.Nickname, account2.Nickname)
+	require.Equal(t, arg.OwnerDisplayName, account2.OwnerDisplayName)
+	// The balance can't be changed this way.
+	require.Equal(t, account1.Balance, account2.Balance)
 	require.Equal(t, account1.Currency, account2.Currency)
 	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, time.Second)
 }
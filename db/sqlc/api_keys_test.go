@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func createRandomAPIKey(t *testing.T, owner User) ApiKey {
+	store := NewStore(testDB)
+
+	arg := CreateAPIKeyParams{
+		Owner:        owner.Username,
+		Name:         util.RandomString(8),
+		Prefix:       util.RandomString(8),
+		HashedSecret: util.RandomString(64),
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	key, err := store.CreateAPIKeyTx(context.Background(), CreateAPIKeyTxParams{CreateAPIKeyParams: arg})
+	require.NoError(t, err)
+	require.NotZero(t, key.ID)
+	require.Equal(t, arg.Owner, key.Owner)
+	require.Equal(t, arg.Prefix, key.Prefix)
+	require.Equal(t, arg.HashedSecret, key.HashedSecret)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, key.ExpiresAt.Time, time.Second)
+	require.False(t, key.LastUsedAt.Valid)
+	require.False(t, key.RevokedAt.Valid)
+
+	return key
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	key1 := createRandomAPIKey(t, createRandomUser(t))
+
+	key2, err := testQueries.GetAPIKeyByPrefix(context.Background(), key1.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, key1.ID, key2.ID)
+	require.Equal(t, key1.Scopes, key2.Scopes)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	key := createRandomAPIKey(t, createRandomUser(t))
+
+	require.NoError(t, testQueries.TouchAPIKey(context.Background(), key.ID))
+
+	touched, err := testQueries.GetAPIKeyByPrefix(context.Background(), key.Prefix)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+}
+
+func TestRevokeAPIKeyTx(t *testing.T) {
+	store := NewStore(testDB)
+	owner := createRandomUser(t)
+	key := createRandomAPIKey(t, owner)
+
+	// Someone else can't revoke the key.
+	_, err := store.RevokeAPIKeyTx(context.Background(), RevokeAPIKeyTxParams{ID: key.ID, Owner: createRandomUser(t).Username})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	revoked, err := store.RevokeAPIKeyTx(context.Background(), RevokeAPIKeyTxParams{ID: key.ID, Owner: owner.Username})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	// Revoking twice finds nothing to revoke.
+	_, err = store.RevokeAPIKeyTx(context.Background(), RevokeAPIKeyTxParams{ID: key.ID, Owner: owner.Username})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	keys, err := store.ListAPIKeysByOwner(context.Background(), ListAPIKeysByOwnerParams{Owner: owner.Username, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+}
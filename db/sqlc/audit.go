@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// auditEntry is what a transaction records about one change. Before and After are
+// stored as JSON; leave Before nil for a create and After nil for a delete.
+type auditEntry struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	IP         string
+	Before     any
+	After      any
+	Metadata   map[string]any
+}
+
+// recordAudit writes an audit event with the queries of the transaction making the
+// change, so the event is committed or rolled back together with it.
+func recordAudit(ctx context.Context, q *Queries, entry auditEntry) error {
+	before, err := json.Marshal(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(entry.After)
+	if err != nil {
+		return err
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = map[string]any{}
+	}
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateAuditEvent(ctx, CreateAuditEventParams{
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		RequestID:  entry.RequestID,
+		Ip:         entry.IP,
+		Metadata:   metadata,
+		Before:     before,
+		After:      after,
+	})
+	return err
+}
@@ -16,7 +16,7 @@ INSERT INTO entries (
 ) VALUES (
     $1, $2
 )
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, prev_hash, hash
 `
 
 type CreateEntryParams struct {
@@ -32,12 +32,14 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, prev_hash, hash FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -49,12 +51,36 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const getEntryChainHead = `-- name: GetEntryChainHead :one
+SELECT id, account_id, amount, created_at, prev_hash, hash FROM entries
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT 1
+`
+
+// The newest entry of an account, which the next entry chains to.
+func (q *Queries) GetEntryChainHead(ctx context.Context, accountID int64) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, getEntryChainHead, accountID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, prev_hash, hash FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -81,6 +107,52 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+SELECT id, account_id, amount, created_at, prev_hash, hash FROM entries
+WHERE account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	AfterID   int64 `json:"after_id"`
+	Limit     int32 `json:"limit"`
+}
+
+// Walks an account's entries in chain order, a page at a time.
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter, arg.AccountID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
 		); err != nil {
 			return nil, err
 		}
@@ -94,3 +166,32 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const setEntryHash = `-- name: SetEntryHash :one
+UPDATE entries
+SET prev_hash = $1, hash = $2
+WHERE id = $3
+RETURNING id, account_id, amount, created_at, prev_hash, hash
+`
+
+type SetEntryHashParams struct {
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+	ID       int64  `json:"id"`
+}
+
+// Entries are never modified, except that the hash is filled in straight after
+// CreateEntry, in the same transaction, once the id and created_at are known.
+func (q *Queries) SetEntryHash(ctx context.Context, arg SetEntryHashParams) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, setEntryHash, arg.PrevHash, arg.Hash, arg.ID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
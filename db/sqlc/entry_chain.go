@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/techschool/simplebank/ledger"
+)
+
+// createChainedEntry creates an entry chained to the account's previous one. The caller
+// must already hold the account's row lock (AddAccountBalance takes it), otherwise two
+// transactions could chain to the same head and fork the chain.
+func createChainedEntry(ctx context.Context, q *Queries, accountID int64, amount int64) (Entry, error) {
+	prevHash := ledger.GenesisHash
+	head, err := q.GetEntryChainHead(ctx, accountID)
+	if err == nil {
+		prevHash = head.Hash
+	} else if err != sql.ErrNoRows {
+		return Entry{}, err
+	}
+
+	entry, err := q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: accountID,
+		Amount:    amount,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry, err = q.SetEntryHash(ctx, SetEntryHashParams{
+		PrevHash: prevHash,
+		Hash:     ledger.EntryHash(prevHash, entry.ID, entry.AccountID, entry.Amount, entry.CreatedAt),
+		ID:       entry.ID,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+
+	payload, err := json.Marshal(EntryPostedNotification{AccountID: entry.AccountID, EntryID: entry.ID})
+	if err != nil {
+		return Entry{}, err
+	}
+	err = q.Notify(ctx, NotifyParams{
+		Channel: EntryPostedChannel,
+		Payload: string(payload),
+	})
+	return entry, err
+}
+
+// EntryPostedChannel is the Postgres channel every new entry is announced on, once its
+// transaction commits.
+const EntryPostedChannel = "entry_posted"
+
+// EntryPostedNotification is the payload of a notification on EntryPostedChannel.
+type EntryPostedNotification struct {
+	AccountID int64 `json:"account_id"`
+	EntryID   int64 `json:"entry_id"`
+}
+
+// How many entries VerifyEntryChain reads per query.
+const verifyEntryChainPageSize = 1000
+
+// EntryChainVerification is the result of walking an account's chain. When Valid is
+// false, BrokenEntryID is the first entry whose link doesn't hold and Reason says why.
+type EntryChainVerification struct {
+	AccountID      int64  `json:"account_id"`
+	Valid          bool   `json:"valid"`
+	EntriesChecked int64  `json:"entries_checked"`
+	HeadEntryID    int64  `json:"head_entry_id"`
+	HeadHash       string `json:"head_hash"`
+	BrokenEntryID  int64  `json:"broken_entry_id,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// VerifyEntryChain walks an account's entries oldest first, recomputing every hash. It
+// stops at the first broken link. An error means the walk itself failed, not the chain.
+func (store *SQLStore) VerifyEntryChain(ctx context.Context, accountID int64) (EntryChainVerification, error) {
+	result := EntryChainVerification{
+		AccountID: accountID,
+		Valid:     true,
+		HeadHash:  ledger.GenesisHash,
+	}
+
+	for {
+		entries, err := store.ListEntriesAfter(ctx, ListEntriesAfterParams{
+			AccountID: accountID,
+			AfterID:   result.HeadEntryID,
+			Limit:     verifyEntryChainPageSize,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		for _, entry := range entries {
+			if entry.PrevHash != result.HeadHash {
+				result.Valid = false
+				result.BrokenEntryID = entry.ID
+				result.Reason = "prev_hash doesn't match the hash of the previous entry"
+				return result, nil
+			}
+			if entry.Hash != ledger.EntryHash(entry.PrevHash, entry.ID, entry.AccountID, entry.Amount, entry.CreatedAt) {
+				result.Valid = false
+				result.BrokenEntryID = entry.ID
+				result.Reason = "hash doesn't match the entry"
+				return result, nil
+			}
+
+			result.EntriesChecked++
+			result.HeadEntryID = entry.ID
+			result.HeadHash = entry.Hash
+		}
+
+		if len(entries) < verifyEntryChainPageSize {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/ledger"
+	"github.com/techschool/simplebank/util"
+)
+
+func TestTransferTxEntryChain(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	var results []TransferTxResult
+	for i := 0; i < 3; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	// The first entry of each account chains to the genesis hash, the rest to the one before.
+	require.Equal(t, ledger.GenesisHash, results[0].FromEntry.PrevHash)
+	require.Equal(t, ledger.GenesisHash, results[0].ToEntry.PrevHash)
+	for i := 1; i < len(results); i++ {
+		require.Equal(t, results[i-1].FromEntry.Hash, results[i].FromEntry.PrevHash)
+		require.Equal(t, results[i-1].ToEntry.Hash, results[i].ToEntry.PrevHash)
+	}
+
+	verification, err := store.VerifyEntryChain(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+	require.Equal(t, int64(3), verification.EntriesChecked)
+	require.Equal(t, results[2].FromEntry.ID, verification.HeadEntryID)
+	require.Equal(t, results[2].FromEntry.Hash, verification.HeadHash)
+}
+
+func TestVerifyEntryChainDetectsTampering(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	var entries []Entry
+	for i := 0; i < 3; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		entries = append(entries, result.FromEntry)
+	}
+
+	// Editing the amount of the middle entry breaks the chain at that entry.
+	_, err := testDB.ExecContext(context.Background(), "UPDATE entries SET amount = -1 WHERE id = $1", entries[1].ID)
+	require.NoError(t, err)
+
+	verification, err := store.VerifyEntryChain(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.False(t, verification.Valid)
+	require.Equal(t, entries[1].ID, verification.BrokenEntryID)
+	require.Equal(t, int64(1), verification.EntriesChecked)
+
+	// Deleting it instead breaks the link from the entry after it.
+	_, err = testDB.ExecContext(context.Background(), "DELETE FROM entries WHERE id = $1", entries[1].ID)
+	require.NoError(t, err)
+
+	verification, err = store.VerifyEntryChain(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.False(t, verification.Valid)
+	require.Equal(t, entries[2].ID, verification.BrokenEntryID)
+}
+
+func TestVerifyEntryChainEmpty(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	verification, err := store.VerifyEntryChain(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.True(t, verification.Valid)
+	require.Zero(t, verification.EntriesChecked)
+	require.Equal(t, ledger.GenesisHash, verification.HeadHash)
+}
+
+func TestTransferTxNotifiesEntries(t *testing.T) {
+	config, err := util.LoadConfig("../..")
+	require.NoError(t, err)
+
+	// Listen waits for the connection, so fail fast without a database.
+	require.NoError(t, testDB.Ping())
+
+	listener := pq.NewListener(config.DBSource, time.Second, time.Second, nil)
+	defer listener.Close()
+	require.NoError(t, listener.Listen(EntryPostedChannel))
+
+	store := NewStore(testDB)
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: createRandomAccount(t).ID,
+		ToAccountID:   createRandomAccount(t).ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// Other transactions may be posting entries too, so look for ours.
+	want := map[EntryPostedNotification]bool{
+		{AccountID: result.FromAccount.ID, EntryID: result.FromEntry.ID}: true,
+		{AccountID: result.ToAccount.ID, EntryID: result.ToEntry.ID}:     true,
+	}
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case notification := <-listener.NotificationChannel():
+			if notification == nil {
+				continue
+			}
+			var posted EntryPostedNotification
+			require.NoError(t, json.Unmarshal([]byte(notification.Extra), &posted))
+			delete(want, posted)
+		case <-timeout:
+			t.Fatalf("no notification for %v", want)
+		}
+	}
+}
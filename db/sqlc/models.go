@@ -5,6 +5,8 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -14,6 +16,105 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// Frozen accounts can not send or receive transfers.
+	Frozen bool `json:"frozen"`
+	// customer for accounts people own. Internal accounts belong to the bank and are owned by system.
+	Kind string `json:"kind"`
+	// A name the owner gives the account.
+	Nickname string `json:"nickname"`
+	// How the owner is shown on statements and to other users.
+	OwnerDisplayName string `json:"owner_display_name"`
+}
+
+type Adjustment struct {
+	ID                int64 `json:"id"`
+	AccountID         int64 `json:"account_id"`
+	SuspenseAccountID int64 `json:"suspense_account_id"`
+	// Added to the account and taken from the suspense account. Negative to take money off the account.
+	Amount     int64  `json:"amount"`
+	ReasonCode string `json:"reason_code"`
+	Memo       string `json:"memo"`
+	// The banker who made the adjustment.
+	Operator        string    `json:"operator"`
+	EntryID         int64     `json:"entry_id"`
+	SuspenseEntryID int64     `json:"suspense_entry_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// The public part of the key, used to look it up.
+	Prefix string `json:"prefix"`
+	// SHA-256 of the whole key. The key itself is never stored.
+	HashedSecret string       `json:"hashed_secret"`
+	Scopes       []string     `json:"scopes"`
+	ExpiresAt    sql.NullTime `json:"expires_at"`
+	LastUsedAt   sql.NullTime `json:"last_used_at"`
+	RevokedAt    sql.NullTime `json:"revoked_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	RequestID  string          `json:"request_id"`
+	Ip         string          `json:"ip"`
+	Metadata   json.RawMessage `json:"metadata"`
+	CreatedAt  time.Time       `json:"created_at"`
+	// The entity before the change. null when it was created.
+	Before json.RawMessage `json:"before"`
+	// The entity after the change. null when it was deleted.
+	After json.RawMessage `json:"after"`
+}
+
+type Batch struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// all_or_nothing makes every transfer or none of them. best_effort makes each one that can be made.
+	Mode string `json:"mode"`
+	// The format of the uploaded file: csv or pain.001.
+	Format      string       `json:"format"`
+	Status      string       `json:"status"`
+	ItemCount   int32        `json:"item_count"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+}
+
+type BatchItem struct {
+	ID      int64 `json:"id"`
+	BatchID int64 `json:"batch_id"`
+	// Where the item is in the file: the CSV line number, or the position of the transaction in a pain.001 file.
+	Line          int32  `json:"line"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// The payer's own reference, e.g. the pain.001 EndToEndId.
+	Reference string `json:"reference"`
+	// skipped means another item of an all_or_nothing batch failed, so none were made.
+	Status string `json:"status"`
+	// The transfer made for a succeeded item.
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Error      string        `json:"error"`
+}
+
+type CashTransaction struct {
+	ID            int64  `json:"id"`
+	AccountID     int64  `json:"account_id"`
+	CashAccountID int64  `json:"cash_account_id"`
+	Kind          string `json:"kind"`
+	// Always positive. kind says which way the money went.
+	Amount int64 `json:"amount"`
+	// The user who made the deposit or withdrawal.
+	Actor       string    `json:"actor"`
+	EntryID     int64     `json:"entry_id"`
+	CashEntryID int64     `json:"cash_entry_id"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +123,118 @@ type Entry struct {
 	// Can be a negative or positive value.
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// Hash of the account's previous entry, or 64 zeros for its first one.
+	PrevHash string `json:"prev_hash"`
+	// SHA-256 of prev_hash, id, account_id, amount and created_at. See ledger.EntryHash.
+	Hash string `json:"hash"`
+}
+
+type Job struct {
+	ID int64 `json:"id"`
+	// Picks the handler. See the jobs package.
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+	Status  string          `json:"status"`
+	// Times the job has been handed to a worker, including the current one.
+	Attempts    int32 `json:"attempts"`
+	MaxAttempts int32 `json:"max_attempts"`
+	// The job is not picked up before this. Set to the next try after a failure.
+	RunAt time.Time `json:"run_at"`
+	// Visibility timeout of a running job. After this another worker may take it.
+	LockedUntil sql.NullTime `json:"locked_until"`
+	LastError   string       `json:"last_error"`
+	FinishedAt  sql.NullTime `json:"finished_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type OauthClient struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+	// The user the client acts as.
+	Owner        string `json:"owner"`
+	HashedSecret string `json:"hashed_secret"`
+	// The most a token issued to the client can be granted.
+	Scopes    []string     `json:"scopes"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Outbox struct {
+	ID int64 `json:"id"`
+	// Domain event name, e.g. TransferPosted. See db.Event* constants.
+	EventType     string          `json:"event_type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	// Failed tries to publish the event.
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error"`
+	// When the relay handed the event to the publisher. NULL until then.
+	PublishedAt sql.NullTime `json:"published_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	Key string    `json:"key"`
+	Tat time.Time `json:"tat"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// A 5 field cron expression or an RRULE. See the schedule package.
+	Schedule string    `json:"schedule"`
+	StartAt  time.Time `json:"start_at"`
+	// No runs after this. NULL runs until cancelled.
+	EndAt     sql.NullTime `json:"end_at"`
+	NextRunAt time.Time    `json:"next_run_at"`
+	Status    string       `json:"status"`
+	// Failed runs since the last successful one. Reaching the limit pauses the schedule.
+	ConsecutiveFailures int32        `json:"consecutive_failures"`
+	LastRunAt           sql.NullTime `json:"last_run_at"`
+	CreatedAt           time.Time    `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// The next_run_at that was due when the run happened.
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Status       string    `json:"status"`
+	// The transfer made by a successful run.
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Error      string        `json:"error"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type Statement struct {
+	ID          int64     `json:"id"`
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	// Not included in the period, so a month ends at midnight on the first of the next.
+	PeriodEnd time.Time `json:"period_end"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"`
+	// Key of the file in blob storage. Empty until the statement is ready.
+	StoragePath string `json:"storage_path"`
+	// Hex SHA-256 of the stored file.
+	Checksum    string       `json:"checksum"`
+	SizeBytes   int64        `json:"size_bytes"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+}
+
+type TotpRecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of the normalized recovery code.
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 type Transfer struct {
@@ -31,4 +244,65 @@ type Transfer struct {
 	// Must be a positive number value.
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// The entry taking the money off from_account_id. NULL for old transfers that could not be matched.
+	FromEntryID sql.NullInt64 `json:"from_entry_id"`
+	// The entry adding the money to to_account_id. NULL for old transfers that could not be matched.
+	ToEntryID sql.NullInt64 `json:"to_entry_id"`
+}
+
+type User struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+	FullName       string `json:"full_name"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	// Failed logins since the last successful one.
+	FailedLoginAttempts int32 `json:"failed_login_attempts"`
+	// Logins are refused until this time. NULL when not locked.
+	LockedUntil       sql.NullTime `json:"locked_until"`
+	PasswordChangedAt time.Time    `json:"password_changed_at"`
+	CreatedAt         time.Time    `json:"created_at"`
+	// Base32 TOTP secret. Set at enrollment, only used once totp_enabled is true.
+	TotpSecret  string `json:"totp_secret"`
+	TotpEnabled bool   `json:"totp_enabled"`
+	// Time step of the last accepted code, so a code cannot be replayed.
+	TotpLastUsedStep int64 `json:"totp_last_used_step"`
+}
+
+type WebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int64  `json:"subscription_id"`
+	EventID        int64  `json:"event_id"`
+	EventType      string `json:"event_type"`
+	// The exact body sent, so a replay sends the same thing.
+	Payload json.RawMessage `json:"payload"`
+	Status  string          `json:"status"`
+	// Attempts since the delivery was created or last replayed.
+	Attempts       int32         `json:"attempts"`
+	LastStatusCode sql.NullInt32 `json:"last_status_code"`
+	LastError      string        `json:"last_error"`
+	DeliveredAt    sql.NullTime  `json:"delivered_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64 `json:"id"`
+	DeliveryID int64 `json:"delivery_id"`
+	// HTTP status the endpoint answered with. NULL when there was no answer.
+	StatusCode sql.NullInt32 `json:"status_code"`
+	Error      string        `json:"error"`
+	DurationMs int64         `json:"duration_ms"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// Webhook event names like transfer.posted. See the webhooks package.
+	EventTypes []string `json:"event_types"`
+	// Signs every delivery. Kept in plaintext because the signature needs it.
+	Secret    string    `json:"secret"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func createRandomOAuthClient(t *testing.T) OauthClient {
+	store := NewStore(testDB)
+	owner := createRandomUser(t)
+
+	arg := CreateOAuthClientParams{
+		ClientID:     "client_" + util.RandomString(16),
+		Name:         util.RandomString(8),
+		Owner:        owner.Username,
+		HashedSecret: util.RandomString(60),
+		Scopes:       []string{"accounts:read"},
+	}
+
+	client, err := store.CreateOAuthClientTx(context.Background(), CreateOAuthClientTxParams{
+		CreateOAuthClientParams: arg,
+		Actor:                   "admin",
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.ClientID, client.ClientID)
+	require.Equal(t, arg.Owner, client.Owner)
+	require.Equal(t, arg.HashedSecret, client.HashedSecret)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.False(t, client.RevokedAt.Valid)
+	require.NotZero(t, client.CreatedAt)
+
+	return client
+}
+
+func TestGetOAuthClient(t *testing.T) {
+	client1 := createRandomOAuthClient(t)
+
+	client2, err := testQueries.GetOAuthClient(context.Background(), client1.ClientID)
+	require.NoError(t, err)
+	require.Equal(t, client1.ClientID, client2.ClientID)
+	require.Equal(t, client1.Scopes, client2.Scopes)
+}
+
+func TestRevokeOAuthClientTx(t *testing.T) {
+	store := NewStore(testDB)
+	client := createRandomOAuthClient(t)
+
+	revoked, err := store.RevokeOAuthClientTx(context.Background(), RevokeOAuthClientTxParams{
+		ClientID: client.ClientID,
+		Actor:    "admin",
+	})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = store.RevokeOAuthClientTx(context.Background(), RevokeOAuthClientTxParams{
+		ClientID: client.ClientID,
+		Actor:    "admin",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "oauth_client",
+		EntityID:   client.ClientID,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditActionOAuthClientCreated, events[0].Action)
+	require.Equal(t, AuditActionOAuthClientRevoked, events[1].Action)
+}
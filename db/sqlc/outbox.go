@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Domain events written to the outbox. They are named for what happened, in the past
+// tense, and their payload is the JSON of the rows involved.
+const (
+	EventAccountCreated  = "AccountCreated"
+	EventAccountUpdated  = "AccountUpdated"
+	EventAccountFrozen   = "AccountFrozen"
+	EventAccountUnfrozen = "AccountUnfrozen"
+	EventAccountDeleted  = "AccountDeleted"
+	EventBalanceAdjusted = "BalanceAdjusted"
+	EventCashDeposited   = "CashDeposited"
+	EventCashWithdrawn   = "CashWithdrawn"
+	EventTransferPosted  = "TransferPosted"
+)
+
+// domainEvent is what a transaction tells the rest of the world about one change.
+type domainEvent struct {
+	Type          string
+	AggregateType string
+	AggregateID   int64
+	Payload       any
+}
+
+// OutboxEventChannel is the Postgres channel the id of every outbox event is announced
+// on, once its transaction commits. Unlike the relay, every server instance hears it.
+const OutboxEventChannel = "outbox_event"
+
+// writeOutbox adds an event to the outbox with the queries of the transaction making
+// the change, so the event is published if and only if the change is committed.
+func writeOutbox(ctx context.Context, q *Queries, event domainEvent) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	row, err := q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:     event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   fmt.Sprint(event.AggregateID),
+		Payload:       payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	return q.Notify(ctx, NotifyParams{
+		Channel: OutboxEventChannel,
+		Payload: strconv.FormatInt(row.ID, 10),
+	})
+}
+
+// RelayOutboxTxParams says how many events to take and how to publish each one.
+type RelayOutboxTxParams struct {
+	Limit   int32
+	Publish func(ctx context.Context, event Outbox) error
+}
+
+// RelayOutboxTx publishes the oldest unpublished events in order, holding their row
+// locks so two relays never publish the same event. It stops at the first event that
+// fails to publish, records the failure and returns its error, so later events wait
+// their turn. Delivery is at least once: an event can be published again if the
+// commit fails after Publish.
+func (store *SQLStore) RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (int, error) {
+	published := 0
+	var publishErr error
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		events, err := q.ClaimOutboxEvents(ctx, arg.Limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if publishErr = arg.Publish(ctx, event); publishErr != nil {
+				// Committed, not rolled back, so the failure count is kept.
+				return q.RecordOutboxEventFailure(ctx, RecordOutboxEventFailureParams{
+					ID:        event.ID,
+					LastError: publishErr.Error(),
+				})
+			}
+
+			if err := q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if publishErr != nil {
+		return published, fmt.Errorf("cannot publish outbox event: %w", publishErr)
+	}
+
+	return published, nil
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func TestOutboxEventsWritten(t *testing.T) {
+	store := NewStore(testDB)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    util.RandomOwner(),
+			Balance:  100,
+			Currency: "USD",
+		},
+		Actor: "tester",
+	})
+	require.NoError(t, err)
+
+	_, err = store.SetAccountFrozenTx(context.Background(), SetAccountFrozenTxParams{
+		AccountID: account.ID,
+		Frozen:    true,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	events, err := store.ListOutboxEventsByAggregate(context.Background(), ListOutboxEventsByAggregateParams{
+		AggregateType: "account",
+		AggregateID:   strconv.FormatInt(account.ID, 10),
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, EventAccountCreated, events[0].EventType)
+	require.Equal(t, EventAccountFrozen, events[1].EventType)
+	require.False(t, events[0].PublishedAt.Valid)
+
+	other := createRandomAccount(t)
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   createRandomAccount(t).ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	events, err = store.ListOutboxEventsByAggregate(context.Background(), ListOutboxEventsByAggregateParams{
+		AggregateType: "transfer",
+		AggregateID:   strconv.FormatInt(result.Transfer.ID, 10),
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, EventTransferPosted, events[0].EventType)
+}
+
+func TestRelayOutboxTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	// Publish everything waiting, so the next batch starts with the events below.
+	for {
+		published, err := store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+			Limit:   100,
+			Publish: func(ctx context.Context, event Outbox) error { return nil },
+		})
+		require.NoError(t, err)
+		if published == 0 {
+			break
+		}
+	}
+
+	account1, err := store.CreateAccountTx(context.Background(), CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{Owner: util.RandomOwner(), Currency: "USD"},
+	})
+	require.NoError(t, err)
+	account2, err := store.CreateAccountTx(context.Background(), CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{Owner: util.RandomOwner(), Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	// The second event fails, so only the first is published and the failure is kept.
+	errBroker := errors.New("broker down")
+	var seen []string
+	published, err := store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+		Limit: 10,
+		Publish: func(ctx context.Context, event Outbox) error {
+			seen = append(seen, event.AggregateID)
+			if event.AggregateID == strconv.FormatInt(account2.ID, 10) {
+				return errBroker
+			}
+			return nil
+		},
+	})
+	require.ErrorIs(t, err, errBroker)
+	require.Equal(t, 1, published)
+	require.Equal(t, []string{strconv.FormatInt(account1.ID, 10), strconv.FormatInt(account2.ID, 10)}, seen)
+
+	events, err := store.ListOutboxEventsByAggregate(context.Background(), ListOutboxEventsByAggregateParams{
+		AggregateType: "account",
+		AggregateID:   strconv.FormatInt(account2.ID, 10),
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.False(t, events[0].PublishedAt.Valid)
+	require.Equal(t, int32(1), events[0].Attempts)
+	require.Equal(t, "broker down", events[0].LastError)
+}
+
+// The webhook dispatcher creates deliveries in its own transaction while the relay
+// holds the event's lock. The deliveries' foreign key to the event must not wait on it.
+func TestRelayOutboxTxCreatesWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+
+	subscription, err := store.CreateWebhookSubscriptionTx(context.Background(), CreateWebhookSubscriptionTxParams{
+		CreateWebhookSubscriptionParams: CreateWebhookSubscriptionParams{
+			Owner:      createRandomUser(t).Username,
+			Url:        "https://example.com/hooks",
+			EventTypes: []string{"transfer.posted"},
+			Secret:     "whsec_test",
+		},
+	})
+	require.NoError(t, err)
+
+	event, err := store.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+		EventType:     EventTransferPosted,
+		AggregateType: "transfer",
+		AggregateID:   "1",
+		Payload:       json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	// A lock wait would otherwise last until DB_STATEMENT_TIMEOUT.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var deliveries []WebhookDelivery
+	for len(deliveries) == 0 {
+		published, err := store.RelayOutboxTx(ctx, RelayOutboxTxParams{
+			Limit: 100,
+			Publish: func(ctx context.Context, published Outbox) error {
+				if published.ID != event.ID {
+					return nil
+				}
+				var err error
+				deliveries, err = store.CreateWebhookDeliveriesTx(ctx, CreateWebhookDeliveriesTxParams{
+					EventID:         event.ID,
+					EventType:       "transfer.posted",
+					Payload:         json.RawMessage(`{"id":1}`),
+					SubscriptionIDs: []int64{subscription.ID},
+					Job: func(delivery WebhookDelivery) JobRequest {
+						return JobRequest{Kind: "webhook.deliver", Payload: map[string]int64{"delivery_id": delivery.ID}}
+					},
+				})
+				return err
+			},
+		})
+		require.NoError(t, err)
+		require.NotZero(t, published, "event %d was not relayed", event.ID)
+	}
+
+	require.Len(t, deliveries, 1)
+	require.Equal(t, event.ID, deliveries[0].EventID)
+
+	relayed, err := store.GetOutboxEvent(context.Background(), event.ID)
+	require.NoError(t, err)
+	require.True(t, relayed.PublishedAt.Valid)
+}
@@ -6,27 +6,168 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
 	// We are separating the balance from the amount added, so that sqlc knows
 	// that the are both separate variables when performing the calculation.
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	// Picks the most overdue active schedule that no other worker has locked.
+	ClaimDueScheduledTransfer(ctx context.Context, now time.Time) (ScheduledTransfer, error)
+	// Takes the oldest unpublished events no other relay has locked. The lock is held
+	// until the relay's transaction ends. It is NO KEY UPDATE so rows referencing the
+	// events, like webhook deliveries made while publishing, can still be inserted.
+	ClaimOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
+	// Only a pending batch is finished, and only once.
+	CompleteBatch(ctx context.Context, arg CompleteBatchParams) (Batch, error)
+	// Only succeeds for the worker still holding the job, identified by its attempt.
+	CompleteJob(ctx context.Context, arg CompleteJobParams) (Job, error)
+	// Only the job generating a pending statement finishes it.
+	CompleteStatement(ctx context.Context, arg CompleteStatementParams) (Statement, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAdjustment(ctx context.Context, arg CreateAdjustmentParams) (Adjustment, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateBatch(ctx context.Context, arg CreateBatchParams) (Batch, error)
+	CreateBatchItem(ctx context.Context, arg CreateBatchItemParams) (BatchItem, error)
+	CreateCashTransaction(ctx context.Context, arg CreateCashTransactionParams) (CashTransaction, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	// Adds a pending statement for the period to every active account that doesn't have
+	// one yet. Active means a customer account that had an entry in the period or held
+	// money at its end. Two instances running this at once can't add the same statement twice.
+	CreatePendingStatements(ctx context.Context, arg CreatePendingStatementsParams) ([]Statement, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateTOTPRecoveryCode(ctx context.Context, arg CreateTOTPRecoveryCodeParams) (TotpRecoveryCode, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	CreateWebhookDeliveryAttempt(ctx context.Context, arg CreateWebhookDeliveryAttemptParams) (WebhookDeliveryAttempt, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeactivateWebhookSubscription(ctx context.Context, arg DeactivateWebhookSubscriptionParams) (WebhookSubscription, error)
+	// Gives up on the job. Dead jobs stay in the table until an admin requeues them.
+	DeadLetterJob(ctx context.Context, arg DeadLetterJobParams) (Job, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	// Buckets whose tat has passed are full again, so they can be dropped.
+	DeleteExpiredRateLimitBuckets(ctx context.Context) error
+	DeleteTOTPRecoveryCodes(ctx context.Context, username string) error
+	// Takes the oldest job that is due, or whose visibility timeout ran out, and that
+	// no other worker has locked. It stays invisible to other workers until locked_until.
+	DequeueJob(ctx context.Context, arg DequeueJobParams) (Job, error)
+	EnableUserTOTP(ctx context.Context, username string) (User, error)
+	EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	// This means we dont update the Key or ID. This will avoid deadlock.
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetBatch(ctx context.Context, id int64) (Batch, error)
+	// Waits for a run of the batch that is already going to finish.
+	GetBatchForUpdate(ctx context.Context, id int64) (Batch, error)
+	GetBatchItemForUpdate(ctx context.Context, id int64) (BatchItem, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	// The newest entry of an account, which the next entry chains to.
+	GetEntryChainHead(ctx context.Context, accountID int64) (Entry, error)
+	GetJob(ctx context.Context, id int64) (Job, error)
+	GetOAuthClient(ctx context.Context, clientID string) (OauthClient, error)
+	GetOutboxEvent(ctx context.Context, id int64) (Outbox, error)
+	GetRateLimitBucket(ctx context.Context, key string) (GetRateLimitBucketRow, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	// Waits for a worker that is running the schedule to finish.
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetStatement(ctx context.Context, id int64) (Statement, error)
+	// The balance an account had when a period started, and what came in and went out
+	// during it. The opening balance is worked back from the current balance, so it also
+	// holds for accounts whose starting balance was set without an entry.
+	GetStatementTotals(ctx context.Context, arg GetStatementTotalsParams) (GetStatementTotalsRow, error)
+	// The bank's own account of the given kind, e.g. the suspense account, for a currency.
+	GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetUser(ctx context.Context, username string) (User, error)
+	// Locks the row so concurrent failed logins are counted one after the other.
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookDeliveryForUpdate(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	ListAPIKeysByOwner(ctx context.Context, arg ListAPIKeysByOwnerParams) ([]ApiKey, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error)
+	ListAdjustmentsByAccount(ctx context.Context, arg ListAdjustmentsByAccountParams) ([]Adjustment, error)
+	// Every filter is optional. Newest events come first.
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	ListAuditEventsForEntity(ctx context.Context, arg ListAuditEventsForEntityParams) ([]AuditEvent, error)
+	ListBatchItems(ctx context.Context, batchID int64) ([]BatchItem, error)
+	ListCashTransactionsByAccount(ctx context.Context, arg ListCashTransactionsByAccountParams) ([]CashTransaction, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	// Walks an account's entries in chain order, a page at a time.
+	ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error)
+	ListJobsByStatus(ctx context.Context, arg ListJobsByStatusParams) ([]Job, error)
+	// The active subscriptions of any of the owners that want this event.
+	ListMatchingWebhookSubscriptions(ctx context.Context, arg ListMatchingWebhookSubscriptionsParams) ([]WebhookSubscription, error)
+	ListOAuthClients(ctx context.Context, arg ListOAuthClientsParams) ([]OauthClient, error)
+	ListOutboxEventsByAggregate(ctx context.Context, arg ListOutboxEventsByAggregateParams) ([]Outbox, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfersByOwner(ctx context.Context, arg ListScheduledTransfersByOwnerParams) ([]ScheduledTransfer, error)
+	// A page of an account's entries in a period, in chain order, each with what made it
+	// (a transfer, a deposit or withdrawal, or an adjustment) and the account on the other side.
+	ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error)
+	// Newest period first.
+	ListStatementsByAccount(ctx context.Context, arg ListStatementsByAccountParams) ([]Statement, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
-	// We only want to update the balance. The owner and currency stay the same.
+	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	ListWebhookDeliveriesBySubscription(ctx context.Context, arg ListWebhookDeliveriesBySubscriptionParams) ([]WebhookDelivery, error)
+	ListWebhookDeliveryAttempts(ctx context.Context, deliveryID int64) ([]WebhookDeliveryAttempt, error)
+	ListWebhookSubscriptionsByOwner(ctx context.Context, arg ListWebhookSubscriptionsByOwnerParams) ([]WebhookSubscription, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	// Tells the sessions LISTENing on the channel about a change. Postgres only sends the
+	// notification when the transaction commits, and drops it on rollback.
+	Notify(ctx context.Context, arg NotifyParams) error
+	// Counts a failed login and blocks further attempts until locked_until.
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error)
+	RecordOutboxEventFailure(ctx context.Context, arg RecordOutboxEventFailureParams) error
+	// Puts a dead job back in the queue with a fresh set of attempts.
+	RequeueDeadJob(ctx context.Context, id int64) (Job, error)
+	// Clears the failed login counter and any lock, after a good login or an admin unlock.
+	ResetFailedLogins(ctx context.Context, username string) (User, error)
+	// Puts a failed job back in the queue to be tried again at run_at.
+	RetryJob(ctx context.Context, arg RetryJobParams) (Job, error)
+	// Revoking is permanent. No row comes back when the key is unknown, not the
+	// owner's or already revoked.
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	// Tokens already issued to the client stop passing introspection.
+	RevokeOAuthClient(ctx context.Context, clientID string) (OauthClient, error)
+	// Bankers freeze an account to stop money moving in or out of it.
+	SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error)
+	// Entries are never modified, except that the hash is filled in straight after
+	// CreateEntry, in the same transaction, once the id and created_at are known.
+	SetEntryHash(ctx context.Context, arg SetEntryHashParams) (Entry, error)
+	// Transfers are never modified, except that the entry ids are filled in straight
+	// after the entries are created, in the same transaction.
+	SetTransferEntries(ctx context.Context, arg SetTransferEntriesParams) (Transfer, error)
+	// Starts a new enrollment. TOTP stays off until a code from the new secret is confirmed.
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	// Used for the daily withdrawal limit.
+	SumWithdrawalsSince(ctx context.Context, arg SumWithdrawalsSinceParams) (int64, error)
+	// Takes one token from the bucket using GCRA. A new key starts with a full bucket.
+	// When the bucket is empty the WHERE clause skips the update and no row is returned,
+	// so the caller gets sql.ErrNoRows and the request is denied.
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (TakeRateLimitTokenRow, error)
+	// Only written once a minute so busy keys don't rewrite their row on every request.
+	TouchAPIKey(ctx context.Context, id int64) error
+	// Only the descriptive fields can be updated. The balance only changes through
+	// entries (transfers and adjustments), and the owner and currency stay the same.
 	// We return the updated data to the client.
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateBatchItem(ctx context.Context, arg UpdateBatchItemParams) (BatchItem, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	// Only moves forward, so no row comes back when a code's step was already used.
+	UpdateUserTOTPLastUsedStep(ctx context.Context, arg UpdateUserTOTPLastUsedStepParams) (User, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	// Marks an unused code as used. No row comes back for a wrong or already used code.
+	UseTOTPRecoveryCode(ctx context.Context, arg UseTOTPRecoveryCodeParams) (TotpRecoveryCode, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	// A bucket of 2 that refills one token an hour, so it cannot refill during the test.
+	arg := TakeRateLimitTokenParams{
+		Key:              util.RandomString(12),
+		EmissionInterval: 3600,
+		BurstTolerance:   3600,
+	}
+
+	// The first two tokens are taken from the full bucket.
+	first, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, first.Tat.After(first.DbNow))
+
+	second, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, second.Tat.After(first.Tat))
+
+	// The bucket is empty now, so no row comes back and the bucket is left untouched.
+	_, err = testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	bucket, err := testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.NoError(t, err)
+	require.Equal(t, second.Tat.UnixMicro(), bucket.Tat.UnixMicro())
+}
+
+func TestDeleteExpiredRateLimitBuckets(t *testing.T) {
+	// A tiny emission interval means the bucket is full again almost immediately.
+	arg := TakeRateLimitTokenParams{
+		Key:              util.RandomString(12),
+		EmissionInterval: 0.000001,
+		BurstTolerance:   0,
+	}
+	_, err := testQueries.TakeRateLimitToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = testDB.Exec("SELECT pg_sleep(0.01)")
+	require.NoError(t, err)
+
+	err = testQueries.DeleteExpiredRateLimitBuckets(context.Background())
+	require.NoError(t, err)
+
+	_, err = testQueries.GetRateLimitBucket(context.Background(), arg.Key)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
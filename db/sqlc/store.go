@@ -3,13 +3,50 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+
+	"github.com/techschool/simplebank/util"
 )
 
 // Store will provide all functions needed to execute queries to a db.
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error)
+	UpdateAccountTx(ctx context.Context, arg UpdateAccountTxParams) (Account, error)
+	DeleteAccountTx(ctx context.Context, arg DeleteAccountTxParams) error
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (CashTxResult, error)
+	WithdrawTx(ctx context.Context, arg WithdrawTxParams) (CashTxResult, error)
+	CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferTxParams) (ScheduledTransfer, error)
+	UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferTxParams) (ScheduledTransfer, error)
+	RunDueScheduledTransferTx(ctx context.Context, arg RunDueScheduledTransferTxParams) (RunDueScheduledTransferTxResult, error)
+	EnqueueJobsTx(ctx context.Context, requests []JobRequest) ([]Job, error)
+	RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (int, error)
+	CreateWebhookSubscriptionTx(ctx context.Context, arg CreateWebhookSubscriptionTxParams) (WebhookSubscription, error)
+	DeactivateWebhookSubscriptionTx(ctx context.Context, arg DeactivateWebhookSubscriptionTxParams) (WebhookSubscription, error)
+	CreateWebhookDeliveriesTx(ctx context.Context, arg CreateWebhookDeliveriesTxParams) ([]WebhookDelivery, error)
+	RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (WebhookDelivery, error)
+	ReplayWebhookDeliveryTx(ctx context.Context, arg ReplayWebhookDeliveryTxParams) (WebhookDelivery, error)
+	FailedLoginTx(ctx context.Context, arg FailedLoginTxParams) (FailedLoginTxResult, error)
+	LoginAttemptTx(ctx context.Context, arg LoginAttemptTxParams) (LoginAttemptTxResult, error)
+	UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (User, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error)
+	SetAccountFrozenTx(ctx context.Context, arg SetAccountFrozenTxParams) (Account, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error)
+	CreateAPIKeyTx(ctx context.Context, arg CreateAPIKeyTxParams) (ApiKey, error)
+	RevokeAPIKeyTx(ctx context.Context, arg RevokeAPIKeyTxParams) (ApiKey, error)
+	CreateOAuthClientTx(ctx context.Context, arg CreateOAuthClientTxParams) (OauthClient, error)
+	RevokeOAuthClientTx(ctx context.Context, arg RevokeOAuthClientTxParams) (OauthClient, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (MigrationVersion, error)
+	VerifyEntryChain(ctx context.Context, accountID int64) (EntryChainVerification, error)
+	StatementTx(ctx context.Context, arg StatementTxParams) error
+	CreatePendingStatementsTx(ctx context.Context, arg CreatePendingStatementsTxParams) ([]Statement, error)
+	CreateBatchTx(ctx context.Context, arg CreateBatchTxParams) (BatchTxResult, error)
+	ExecuteBatchTx(ctx context.Context, batchID int64) (BatchTxResult, error)
 }
 
 // To execute all functions and transactions.
@@ -18,14 +55,72 @@ type SQLStore struct {
 	*Queries
 	// To create a new DB transaction, we need the DB object.
 	db *sql.DB
+	// Called after execTx rolls a transaction back. Used for metrics.
+	onRollback func(ctx context.Context, err error)
+	// Wraps the connection and every transaction before queries run on them. Used for tracing.
+	wrapDBTX func(DBTX) DBTX
+}
+
+// StoreOption configures optional behaviour of the SQLStore.
+type StoreOption func(*SQLStore)
+
+// WithRollbackHook registers a function that is called every time execTx rolls back,
+// with the error that caused the rollback.
+func WithRollbackHook(hook func(ctx context.Context, err error)) StoreOption {
+	return func(store *SQLStore) {
+		store.onRollback = hook
+	}
+}
+
+// WithDBTXWrapper wraps the *sql.DB and each *sql.Tx used by the store, so every
+// query, including those run inside execTx, goes through the wrapper.
+func WithDBTXWrapper(wrap func(DBTX) DBTX) StoreOption {
+	return func(store *SQLStore) {
+		store.wrapDBTX = wrap
+	}
 }
 
 // Store is no longer a pointer (star * value) because we have an interface option now.
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+func NewStore(db *sql.DB, opts ...StoreOption) Store {
+	store := &SQLStore{
+		db: db,
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	store.Queries = New(store.wrap(db))
+	return store
+}
+
+// wrap applies the DBTX wrapper if one was configured.
+func (store *SQLStore) wrap(conn DBTX) DBTX {
+	if store.wrapDBTX == nil {
+		return conn
+	}
+	return store.wrapDBTX(conn)
+}
+
+// Ping checks the database can still be reached.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// MigrationVersion is the row golang-migrate keeps in schema_migrations.
+// Dirty means a migration failed half way and needs fixing by hand.
+type MigrationVersion struct {
+	Version int64 `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
+
+// The schema_migrations table is created by golang-migrate rather than by our own
+// migrations, so this query is written by hand instead of being generated by sqlc.
+const getMigrationVersion = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+// MigrationVersion returns the schema version the database has been migrated to.
+func (store *SQLStore) MigrationVersion(ctx context.Context) (MigrationVersion, error) {
+	var v MigrationVersion
+	err := store.db.QueryRowContext(ctx, getMigrationVersion).Scan(&v.Version, &v.Dirty)
+	return v, err
 }
 
 // Create pass in context and a call back function. Once a queries object is created, its passed into
@@ -41,32 +136,47 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	}
 
 	// Creating a new transaction query.
-	q := New(tx)
+	q := New(store.wrap(tx))
 
 	// Callback function using Queries (see line 23 where it says fn func(...))
 	err = fn(q)
 
 	// If there is an error, execure the rollback func.
 	if err != nil {
+		if store.onRollback != nil {
+			store.onRollback(ctx, err)
+		}
+		logger := util.LoggerFromContext(ctx)
 		// If the rollback has a error, two errors are reported.
 		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.ErrorContext(ctx, "tx rollback failed", slog.Any("error", err), slog.Any("rollback_error", rbErr))
 			// The errored message will take in the err variable and rberr variable and print them.
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
+		logger.WarnContext(ctx, "tx rolled back", slog.Any("error", err))
 		return err
 	}
 	// If successful, commit the transaction.
 	return tx.Commit()
 }
 
+// ErrAccountFrozen is returned by TransferTx when money would move in or out of a frozen account.
+var ErrAccountFrozen = errors.New("account is frozen")
+
 // Transfer transaction parameters
 // from, to, results
 // var, in64 json
 
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id`
-	Amount        int64 `json:"amount`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// Who asked for the transfer, for the audit log.
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id"`
+	IP        string `json:"ip"`
+	// Jobs to enqueue in the same transaction, so they only run if the transfer commits.
+	Jobs []JobRequest `json:"-"`
 }
 
 // Transfer transaction results
@@ -76,10 +186,10 @@ type TransferTxParams struct {
 
 type TransferTxResult struct {
 	Transfer    Transfer `json:"transfer"`
-	FromAccount Account  `json: "from_account"`
-	ToAccount   Account  `json: "to_account`
-	FromEntry   Entry    `json: "from_entry`
-	ToEntry     Entry    `json: "to_entry`
+	FromAccount Account  `json:"from_account"`
+	ToAccount   Account  `json:"to_account"`
+	FromEntry   Entry    `json:"from_entry"`
+	ToEntry     Entry    `json:"to_entry"`
 }
 
 // TransferTx is a money transfer database entry from one account to another.
@@ -92,53 +202,114 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	// Passing in the queries object as an arg to the callback.
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, arg)
+		return err
+	})
 
-		// Accessing the 'result' from the transfertxresults function on line 79 using the
-		// func(q ...) callback on line 86. This will create a closure function. Similarly with arg.
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+	// Log the failure with the transfer details so it can be matched to the request ID.
+	if err != nil {
+		util.LoggerFromContext(ctx).ErrorContext(ctx, "transfer tx failed",
+			slog.Int64("from_account_id", arg.FromAccountID),
+			slog.Int64("to_account_id", arg.ToAccountID),
+			slog.Int64("amount", arg.Amount),
+			slog.Any("error", err),
+		)
+	}
 
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			// Negative amount because money is being deducted.
-			Amount: -arg.Amount,
-		})
+// transfer is the body of TransferTx. It is split out so other transactions, like
+// running a scheduled transfer, can make a transfer as part of their own work.
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
 
+	// Lock both accounts, lower ID first like the balance updates below, so two
+	// transfers between the same accounts can't deadlock and nothing can change
+	// them between these checks and the commit.
+	firstID, secondID := arg.FromAccountID, arg.ToAccountID
+	if secondID < firstID {
+		firstID, secondID = secondID, firstID
+	}
+	accounts := map[int64]Account{}
+	for _, id := range []int64{firstID, secondID} {
+		accounts[id], err = q.GetAccountForUpdate(ctx, id)
 		if err != nil {
-			return err
+			return result, err
 		}
+	}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+	if accounts[arg.FromAccountID].Frozen || accounts[arg.ToAccountID].Frozen {
+		return result, ErrAccountFrozen
+	}
+	// No account can be overdrawn by a transfer.
+	if accounts[arg.FromAccountID].Balance < arg.Amount {
+		return result, ErrInsufficientFunds
+	}
 
-		if err != nil {
-			return err
-		}
+	// The transfer record itself comes first.
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	})
 
-		// This checks if the current from account is the lesser ID, then it should update before the from.
-		if arg.FromAccountID < arg.ToAccountID {
-			// Call the add money function. Pass in the context, queries obj, from account id, money out (use - value), to account id , money in
-			// Store the from account result as the first func call the the to account as the second.
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-			// Reverse the order when the ID from ID is greater.
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-		}
+	if err != nil {
+		return result, err
+	}
 
-		return nil
+	// This checks if the current from account is the lesser ID, then it should update before the from.
+	if arg.FromAccountID < arg.ToAccountID {
+		// Call the add money function. Pass in the context, queries obj, from account id, money out (use - value), to account id , money in
+		// Store the from account result as the first func call the the to account as the second.
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+		// Reverse the order when the ID from ID is greater.
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
 
+	// The entries are created after the balance updates, while both account rows
+	// are locked, so each one chains to the true head of its account's chain.
+	result.FromEntry, err = createChainedEntry(ctx, q, arg.FromAccountID, -arg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = createChainedEntry(ctx, q, arg.ToAccountID, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	// Link the transfer to its entries, so a statement line can say who the money
+	// came from or went to.
+	result.Transfer, err = q.SetTransferEntries(ctx, SetTransferEntriesParams{
+		FromEntryID: sql.NullInt64{Int64: result.FromEntry.ID, Valid: true},
+		ToEntryID:   sql.NullInt64{Int64: result.ToEntry.ID, Valid: true},
+		ID:          result.Transfer.ID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if _, err := enqueueJobs(ctx, q, arg.Jobs); err != nil {
+		return result, err
+	}
+
+	err = writeOutbox(ctx, q, domainEvent{
+		Type:          EventTransferPosted,
+		AggregateType: "transfer",
+		AggregateID:   result.Transfer.ID,
+		Payload:       result,
 	})
+	if err != nil {
+		return result, err
+	}
 
-	return result, err
+	return result, auditTransfer(ctx, q, arg, result)
 }
 
 func addMoney(
@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
@@ -17,7 +18,7 @@ INSERT INTO transfers (
 ) VALUES (
     $1, $2, $3
 )
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, from_entry_id, to_entry_id
 `
 
 type CreateTransferParams struct {
@@ -35,12 +36,14 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.FromEntryID,
+		&i.ToEntryID,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, from_entry_id, to_entry_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -53,12 +56,14 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.FromEntryID,
+		&i.ToEntryID,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, from_entry_id, to_entry_id FROM transfers
 WHERE from_account_id = $1 OR to_account_id = $2
 ORDER BY id
 LIMIT $3
@@ -92,6 +97,8 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.FromEntryID,
+			&i.ToEntryID,
 		); err != nil {
 			return nil, err
 		}
@@ -105,3 +112,33 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const setTransferEntries = `-- name: SetTransferEntries :one
+UPDATE transfers
+SET from_entry_id = $1, to_entry_id = $2
+WHERE id = $3
+RETURNING id, from_account_id, to_account_id, amount, created_at, from_entry_id, to_entry_id
+`
+
+type SetTransferEntriesParams struct {
+	FromEntryID sql.NullInt64 `json:"from_entry_id"`
+	ToEntryID   sql.NullInt64 `json:"to_entry_id"`
+	ID          int64         `json:"id"`
+}
+
+// Transfers are never modified, except that the entry ids are filled in straight
+// after the entries are created, in the same transaction.
+func (q *Queries) SetTransferEntries(ctx context.Context, arg SetTransferEntriesParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, setTransferEntries, arg.FromEntryID, arg.ToEntryID, arg.ID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.FromEntryID,
+		&i.ToEntryID,
+	)
+	return i, err
+}
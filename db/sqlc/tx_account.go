@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"strconv"
+)
+
+// Account kinds. Internal accounts belong to the bank, are owned by SystemAccountOwner
+// and there is one of each kind per currency.
+const (
+	AccountKindCustomer = "customer"
+	AccountKindSuspense = "suspense"
+	AccountKindCash     = "cash"
+	SystemAccountOwner  = "system"
+)
+
+// Audit actions written for account changes and transfers.
+const (
+	AuditActionAccountCreated  = "account.created"
+	AuditActionAccountUpdated  = "account.updated"
+	AuditActionAccountDeleted  = "account.deleted"
+	AuditActionAccountAdjusted = "account.adjusted"
+	AuditActionTransferCreated = "transfer.created"
+)
+
+// CreateAccountTxParams is a new account plus who opened it.
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// CreateAccountTx creates an account and writes an audit event.
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, arg.CreateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventAccountCreated,
+			AggregateType: "account",
+			AggregateID:   account.ID,
+			Payload:       account,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionAccountCreated,
+			EntityType: "account",
+			EntityID:   strconv.FormatInt(account.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			After:      account,
+		})
+	})
+
+	return account, err
+}
+
+// UpdateAccountTxParams is the account change plus who made it.
+type UpdateAccountTxParams struct {
+	UpdateAccountParams
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// UpdateAccountTx updates an account and writes an audit event with the account
+// before and after. It returns sql.ErrNoRows for an unknown account.
+func (store *SQLStore) UpdateAccountTx(ctx context.Context, arg UpdateAccountTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// Lock the row so the before image is what the update actually changed.
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		account, err = q.UpdateAccount(ctx, arg.UpdateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventAccountUpdated,
+			AggregateType: "account",
+			AggregateID:   account.ID,
+			Payload:       account,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionAccountUpdated,
+			EntityType: "account",
+			EntityID:   strconv.FormatInt(account.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+			After:      account,
+		})
+	})
+
+	return account, err
+}
+
+// DeleteAccountTxParams says who deletes which account.
+type DeleteAccountTxParams struct {
+	ID        int64
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// DeleteAccountTx deletes an account and writes an audit event with the deleted
+// account. It returns sql.ErrNoRows for an unknown account.
+func (store *SQLStore) DeleteAccountTx(ctx context.Context, arg DeleteAccountTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteAccount(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventAccountDeleted,
+			AggregateType: "account",
+			AggregateID:   arg.ID,
+			Payload:       before,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionAccountDeleted,
+			EntityType: "account",
+			EntityID:   strconv.FormatInt(arg.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+		})
+	})
+}
+
+// auditTransfer records a transfer with the balances of both accounts before and after it.
+func auditTransfer(ctx context.Context, q *Queries, arg TransferTxParams, result TransferTxResult) error {
+	balances := func(fromBalance int64, toBalance int64) map[string]any {
+		return map[string]any{
+			"from_account": map[string]any{"id": arg.FromAccountID, "balance": fromBalance},
+			"to_account":   map[string]any{"id": arg.ToAccountID, "balance": toBalance},
+		}
+	}
+
+	return recordAudit(ctx, q, auditEntry{
+		Actor:      arg.Actor,
+		Action:     AuditActionTransferCreated,
+		EntityType: "transfer",
+		EntityID:   strconv.FormatInt(result.Transfer.ID, 10),
+		RequestID:  arg.RequestID,
+		IP:         arg.IP,
+		Before:     balances(result.FromAccount.Balance+arg.Amount, result.ToAccount.Balance-arg.Amount),
+		After:      balances(result.FromAccount.Balance, result.ToAccount.Balance),
+		Metadata:   map[string]any{"transfer": result.Transfer},
+	})
+}
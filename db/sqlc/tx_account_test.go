@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func TestAccountTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	requestID := util.RandomString(12)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomMoney(),
+			Currency: util.RandomCurrency(),
+		},
+		Actor:     user.Username,
+		RequestID: requestID,
+		IP:        "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	updated, err := store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		UpdateAccountParams: UpdateAccountParams{ID: account.ID, Nickname: "savings"},
+		Actor:               "banker",
+		RequestID:           requestID,
+		IP:                  "127.0.0.1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "savings", updated.Nickname)
+
+	err = store.DeleteAccountTx(context.Background(), DeleteAccountTxParams{
+		ID:        account.ID,
+		Actor:     user.Username,
+		RequestID: requestID,
+		IP:        "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	// Deleting it again finds nothing, and writes nothing.
+	err = store.DeleteAccountTx(context.Background(), DeleteAccountTxParams{ID: account.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "account",
+		EntityID:   strconv.FormatInt(account.ID, 10),
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	require.Equal(t, AuditActionAccountCreated, events[0].Action)
+	require.JSONEq(t, "null", string(events[0].Before))
+
+	var before, after Account
+	require.Equal(t, AuditActionAccountUpdated, events[1].Action)
+	require.Equal(t, "banker", events[1].Actor)
+	require.NoError(t, json.Unmarshal(events[1].Before, &before))
+	require.NoError(t, json.Unmarshal(events[1].After, &after))
+	require.Empty(t, before.Nickname)
+	require.Equal(t, "savings", after.Nickname)
+
+	require.Equal(t, AuditActionAccountDeleted, events[2].Action)
+	require.JSONEq(t, "null", string(events[2].After))
+}
+
+func TestDeleteAccountTxWithHistory(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// The transfer and entries refer to the account, so it can't go.
+	err = store.DeleteAccountTx(context.Background(), DeleteAccountTxParams{ID: account1.ID, Actor: account1.Owner})
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "foreign_key_violation", string(pqErr.Code.Name()))
+
+	// Nothing was deleted.
+	_, err = store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+}
+
+func TestTransferTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Actor:         account1.Owner,
+		RequestID:     util.RandomString(12),
+		IP:            "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "transfer",
+		EntityID:   strconv.FormatInt(result.Transfer.ID, 10),
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditActionTransferCreated, events[0].Action)
+	require.Equal(t, account1.Owner, events[0].Actor)
+
+	var before, after map[string]struct {
+		Balance int64 `json:"balance"`
+	}
+	require.NoError(t, json.Unmarshal(events[0].Before, &before))
+	require.NoError(t, json.Unmarshal(events[0].After, &after))
+	require.Equal(t, account1.Balance, before["from_account"].Balance)
+	require.Equal(t, account1.Balance-10, after["from_account"].Balance)
+	require.Equal(t, account2.Balance+10, after["to_account"].Balance)
+}
+
+func TestListAuditEvents(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+	requestID := util.RandomString(12)
+
+	for _, frozen := range []bool{true, false} {
+		_, err := store.SetAccountFrozenTx(context.Background(), SetAccountFrozenTxParams{
+			AccountID: account.ID,
+			Frozen:    frozen,
+			Actor:     "banker",
+			RequestID: requestID,
+			IP:        "127.0.0.1",
+		})
+		require.NoError(t, err)
+	}
+
+	events, err := store.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		RequestID: sql.NullString{String: requestID, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	// Newest first.
+	require.Equal(t, AuditActionAccountUnfrozen, events[0].Action)
+	require.Equal(t, AuditActionAccountFrozen, events[1].Action)
+
+	events, err = store.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		RequestID: sql.NullString{String: requestID, Valid: true},
+		Action:    sql.NullString{String: AuditActionAccountFrozen, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	events, err = store.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		RequestID:   sql.NullString{String: requestID, Valid: true},
+		CreatedFrom: sql.NullTime{Time: events[0].CreatedAt.AddDate(0, 0, 1), Valid: true},
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestAuditEventsAppendOnly(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	_, err := store.SetAccountFrozenTx(context.Background(), SetAccountFrozenTxParams{
+		AccountID: account.ID,
+		Frozen:    true,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	_, err = testDB.ExecContext(context.Background(),
+		"UPDATE audit_events SET actor = 'someone else' WHERE entity_id = $1", strconv.FormatInt(account.ID, 10))
+	require.ErrorContains(t, err, "append only")
+
+	_, err = testDB.ExecContext(context.Background(),
+		"DELETE FROM audit_events WHERE entity_id = $1", strconv.FormatInt(account.ID, 10))
+	require.ErrorContains(t, err, "append only")
+}
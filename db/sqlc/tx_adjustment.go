@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// AdjustBalanceTxParams is a balance correction and the banker making it. Amount is
+// added to the account; make it negative to take money off.
+type AdjustBalanceTxParams struct {
+	AccountID  int64
+	Amount     int64
+	ReasonCode string
+	Memo       string
+	Operator   string
+	RequestID  string
+	IP         string
+	// Jobs to enqueue in the same transaction, so they only run if the adjustment commits.
+	Jobs []JobRequest
+}
+
+// AdjustBalanceTxResult is everything the adjustment created or changed.
+type AdjustBalanceTxResult struct {
+	Adjustment      Adjustment `json:"adjustment"`
+	Account         Account    `json:"account"`
+	SuspenseAccount Account    `json:"suspense_account"`
+	Entry           Entry      `json:"entry"`
+	SuspenseEntry   Entry      `json:"suspense_entry"`
+}
+
+// AdjustBalanceTx changes an account's balance the same way a transfer would, with the
+// suspense account of its currency on the other side. So the money still adds up and
+// the change shows up in the entries. It returns sql.ErrNoRows for an unknown account.
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if account.Kind != AccountKindCustomer {
+			return fmt.Errorf("account %d is an internal account and can't be adjusted", account.ID)
+		}
+
+		suspense, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+			Kind:     AccountKindSuspense,
+			Currency: account.Currency,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot get %s suspense account: %w", account.Currency, err)
+		}
+
+		// Same lock order as TransferTx, lower ID first, to avoid deadlocks.
+		if account.ID < suspense.ID {
+			result.Account, result.SuspenseAccount, err = addMoney(ctx, q, account.ID, arg.Amount, suspense.ID, -arg.Amount)
+		} else {
+			result.SuspenseAccount, result.Account, err = addMoney(ctx, q, suspense.ID, -arg.Amount, account.ID, arg.Amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = createChainedEntry(ctx, q, account.ID, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.SuspenseEntry, err = createChainedEntry(ctx, q, suspense.ID, -arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Adjustment, err = q.CreateAdjustment(ctx, CreateAdjustmentParams{
+			AccountID:         account.ID,
+			SuspenseAccountID: suspense.ID,
+			Amount:            arg.Amount,
+			ReasonCode:        arg.ReasonCode,
+			Memo:              arg.Memo,
+			Operator:          arg.Operator,
+			EntryID:           result.Entry.ID,
+			SuspenseEntryID:   result.SuspenseEntry.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := enqueueJobs(ctx, q, arg.Jobs); err != nil {
+			return err
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventBalanceAdjusted,
+			AggregateType: "account",
+			AggregateID:   account.ID,
+			Payload:       result,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Worked out from the locked row rather than the read above, which another
+		// transaction could have changed since.
+		before := result.Account
+		before.Balance -= arg.Amount
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Operator,
+			Action:     AuditActionAccountAdjusted,
+			EntityType: "account",
+			EntityID:   strconv.FormatInt(account.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+			After:      result.Account,
+			Metadata:   map[string]any{"adjustment": result.Adjustment},
+		})
+	})
+
+	return result, err
+}
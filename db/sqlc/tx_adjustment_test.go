@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	suspense, err := store.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindSuspense,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, SystemAccountOwner, suspense.Owner)
+
+	result, err := store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID:  account.ID,
+		Amount:     -25,
+		ReasonCode: "fee",
+		Memo:       "card replacement",
+		Operator:   "banker",
+		RequestID:  "test-request",
+		IP:         "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	// The money moved to the suspense account, so none appeared or disappeared.
+	require.Equal(t, account.Balance-25, result.Account.Balance)
+	require.Equal(t, int64(-25), result.Entry.Amount)
+	require.Equal(t, suspense.ID, result.SuspenseAccount.ID)
+	require.Equal(t, int64(25), result.SuspenseEntry.Amount)
+	require.NotEmpty(t, result.Entry.Hash)
+
+	require.Equal(t, "fee", result.Adjustment.ReasonCode)
+	require.Equal(t, "banker", result.Adjustment.Operator)
+	require.Equal(t, result.Entry.ID, result.Adjustment.EntryID)
+	require.Equal(t, result.SuspenseEntry.ID, result.Adjustment.SuspenseEntryID)
+
+	adjustments, err := store.ListAdjustmentsByAccount(context.Background(), ListAdjustmentsByAccountParams{
+		AccountID: account.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	require.Equal(t, result.Adjustment.ID, adjustments[0].ID)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "account",
+		EntityID:   strconv.FormatInt(account.ID, 10),
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditActionAccountAdjusted, events[0].Action)
+}
+
+func TestAdjustBalanceTxInvalid(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	// Unknown reason codes are refused by the check constraint, and nothing is left behind.
+	_, err := store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID:  account.ID,
+		Amount:     10,
+		ReasonCode: "because",
+		Operator:   "banker",
+	})
+	require.Error(t, err)
+
+	unchanged, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+
+	// Internal accounts can't be adjusted themselves.
+	suspense, err := store.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindSuspense,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+
+	_, err = store.AdjustBalanceTx(context.Background(), AdjustBalanceTxParams{
+		AccountID:  suspense.ID,
+		Amount:     10,
+		ReasonCode: "correction",
+		Operator:   "banker",
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// How a batch is run.
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+)
+
+// Statuses of a batch. A pending batch hasn't been run yet. partially_completed means
+// some items of a best_effort batch failed.
+const (
+	BatchPending            = "pending"
+	BatchCompleted          = "completed"
+	BatchPartiallyCompleted = "partially_completed"
+	BatchFailed             = "failed"
+)
+
+// Statuses of a batch item.
+const (
+	BatchItemPending   = "pending"
+	BatchItemSucceeded = "succeeded"
+	BatchItemFailed    = "failed"
+	BatchItemSkipped   = "skipped"
+)
+
+const AuditActionBatchCreated = "batch.created"
+
+// CreateBatchTxParams is a new batch and its items. The items' BatchID is filled in.
+// Job returns the job that runs the batch; it is enqueued in the same transaction.
+type CreateBatchTxParams struct {
+	CreateBatchParams
+	Items     []CreateBatchItemParams
+	Actor     string
+	RequestID string
+	IP        string
+	Job       func(batch Batch) JobRequest
+}
+
+// BatchTxResult is a batch with all its items, in the order of the file.
+type BatchTxResult struct {
+	Batch Batch       `json:"batch"`
+	Items []BatchItem `json:"items"`
+}
+
+// CreateBatchTx stores a pending batch with its items and writes an audit event. The
+// items are expected to be checked already; nothing is transferred until it runs.
+func (store *SQLStore) CreateBatchTx(ctx context.Context, arg CreateBatchTxParams) (BatchTxResult, error) {
+	var result BatchTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		arg.ItemCount = int32(len(arg.Items))
+		result.Batch, err = q.CreateBatch(ctx, arg.CreateBatchParams)
+		if err != nil {
+			return err
+		}
+
+		result.Items = make([]BatchItem, len(arg.Items))
+		for i, item := range arg.Items {
+			item.BatchID = result.Batch.ID
+			result.Items[i], err = q.CreateBatchItem(ctx, item)
+			if err != nil {
+				return err
+			}
+		}
+
+		if arg.Job != nil {
+			if _, err := enqueueJobs(ctx, q, []JobRequest{arg.Job(result.Batch)}); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionBatchCreated,
+			EntityType: "batch",
+			EntityID:   strconv.FormatInt(result.Batch.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			After:      result.Batch,
+		})
+	})
+
+	return result, err
+}
+
+// ExecuteBatchTx makes the transfers of a pending batch and records how each item went.
+// An all_or_nothing batch is checked and run in one transaction: if any item can't be
+// made, none are. A best_effort batch runs each item in its own transaction, so a
+// failure only fails that item. Running a batch again, e.g. when a job is retried,
+// carries on where it stopped and never makes a transfer twice.
+func (store *SQLStore) ExecuteBatchTx(ctx context.Context, batchID int64) (BatchTxResult, error) {
+	batch, err := store.GetBatch(ctx, batchID)
+	if err != nil {
+		return BatchTxResult{}, err
+	}
+
+	if batch.Status == BatchPending {
+		if batch.Mode == BatchModeAllOrNothing {
+			err = store.executeAllOrNothing(ctx, batchID)
+		} else {
+			err = store.executeBestEffort(ctx, batchID)
+		}
+		if err != nil {
+			return BatchTxResult{}, err
+		}
+	}
+
+	result := BatchTxResult{}
+	result.Batch, err = store.GetBatch(ctx, batchID)
+	if err != nil {
+		return result, err
+	}
+	result.Items, err = store.ListBatchItems(ctx, batchID)
+	return result, err
+}
+
+func (store *SQLStore) executeAllOrNothing(ctx context.Context, batchID int64) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		batch, err := q.GetBatchForUpdate(ctx, batchID)
+		if err != nil {
+			return err
+		}
+		if batch.Status != BatchPending {
+			return nil
+		}
+
+		items, err := q.ListBatchItems(ctx, batchID)
+		if err != nil {
+			return err
+		}
+		accounts, err := lockBatchAccounts(ctx, q, items)
+		if err != nil {
+			return err
+		}
+
+		// Every item is checked against the balances the items before it leave, before
+		// any transfer is made.
+		failures := make([]error, len(items))
+		failed := false
+		for i, item := range items {
+			failures[i] = checkBatchItem(accounts, item)
+			if failures[i] != nil {
+				failed = true
+				continue
+			}
+			accounts[item.FromAccountID].Balance -= item.Amount
+			accounts[item.ToAccountID].Balance += item.Amount
+		}
+
+		if failed {
+			for i, item := range items {
+				update := UpdateBatchItemParams{ID: item.ID, Status: BatchItemSkipped}
+				if failures[i] != nil {
+					update.Status = BatchItemFailed
+					update.Error = failures[i].Error()
+				}
+				if _, err := q.UpdateBatchItem(ctx, update); err != nil {
+					return err
+				}
+			}
+			_, err = q.CompleteBatch(ctx, CompleteBatchParams{ID: batchID, Status: BatchFailed})
+			return err
+		}
+
+		for _, item := range items {
+			if err := executeBatchItem(ctx, q, batch, item); err != nil {
+				return err
+			}
+		}
+		_, err = q.CompleteBatch(ctx, CompleteBatchParams{ID: batchID, Status: BatchCompleted})
+		return err
+	})
+}
+
+func (store *SQLStore) executeBestEffort(ctx context.Context, batchID int64) error {
+	batch, err := store.GetBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	items, err := store.ListBatchItems(ctx, batchID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Status != BatchItemPending {
+			continue
+		}
+
+		err := store.execTx(ctx, func(q *Queries) error {
+			// Another run may have made it since the items were listed.
+			item, err := q.GetBatchItemForUpdate(ctx, item.ID)
+			if err != nil {
+				return err
+			}
+			if item.Status != BatchItemPending {
+				return nil
+			}
+
+			accounts, err := lockBatchAccounts(ctx, q, []BatchItem{item})
+			if err != nil {
+				return err
+			}
+			if err := checkBatchItem(accounts, item); err != nil {
+				_, err = q.UpdateBatchItem(ctx, UpdateBatchItemParams{
+					ID:     item.ID,
+					Status: BatchItemFailed,
+					Error:  err.Error(),
+				})
+				return err
+			}
+
+			return executeBatchItem(ctx, q, batch, item)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return store.execTx(ctx, func(q *Queries) error {
+		items, err := q.ListBatchItems(ctx, batchID)
+		if err != nil {
+			return err
+		}
+
+		succeeded := 0
+		for _, item := range items {
+			if item.Status == BatchItemSucceeded {
+				succeeded++
+			}
+		}
+		status := BatchPartiallyCompleted
+		switch succeeded {
+		case len(items):
+			status = BatchCompleted
+		case 0:
+			status = BatchFailed
+		}
+
+		// No row means another run finished it first.
+		_, err = q.CompleteBatch(ctx, CompleteBatchParams{ID: batchID, Status: status})
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	})
+}
+
+// executeBatchItem makes the item's transfer, on behalf of the batch's owner, and marks
+// the item succeeded.
+func executeBatchItem(ctx context.Context, q *Queries, batch Batch, item BatchItem) error {
+	result, err := transfer(ctx, q, TransferTxParams{
+		FromAccountID: item.FromAccountID,
+		ToAccountID:   item.ToAccountID,
+		Amount:        item.Amount,
+		Actor:         batch.Owner,
+		RequestID:     fmt.Sprintf("batch:%d", batch.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.UpdateBatchItem(ctx, UpdateBatchItemParams{
+		ID:         item.ID,
+		Status:     BatchItemSucceeded,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	return err
+}
+
+// lockBatchAccounts locks every account the items touch, lowest ID first like
+// TransferTx, so batches and transfers running side by side can't deadlock. Accounts
+// that no longer exist are left out of the map.
+func lockBatchAccounts(ctx context.Context, q *Queries, items []BatchItem) (map[int64]*Account, error) {
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.FromAccountID, item.ToAccountID)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	accounts := make(map[int64]*Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = &account
+	}
+	return accounts, nil
+}
+
+// checkBatchItem checks the item's transfer can be made. The error it returns is
+// recorded as the reason the item failed.
+func checkBatchItem(accounts map[int64]*Account, item BatchItem) error {
+	for _, id := range []int64{item.FromAccountID, item.ToAccountID} {
+		if accounts[id] == nil {
+			return fmt.Errorf("account %d no longer exists", id)
+		}
+	}
+	return checkTransferAccounts(*accounts[item.FromAccountID], *accounts[item.ToAccountID], item.Currency, item.Amount)
+}
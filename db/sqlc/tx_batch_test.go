@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createTestBatch stores a batch paying amounts from one new USD account with the given
+// balance to a new empty account each.
+func createTestBatch(t *testing.T, store Store, mode string, balance int64, amounts ...int64) (BatchTxResult, Account) {
+	user := createRandomUser(t)
+
+	newAccount := func(owner string, balance int64) Account {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    owner,
+			Balance:  balance,
+			Currency: "USD",
+		})
+		require.NoError(t, err)
+		return account
+	}
+	from := newAccount(user.Username, balance)
+
+	items := make([]CreateBatchItemParams, len(amounts))
+	for i, amount := range amounts {
+		items[i] = CreateBatchItemParams{
+			Line:          int32(i + 2),
+			FromAccountID: from.ID,
+			ToAccountID:   newAccount(createRandomUser(t).Username, 0).ID,
+			Amount:        amount,
+			Currency:      "USD",
+			Reference:     "salary",
+		}
+	}
+
+	result, err := store.CreateBatchTx(context.Background(), CreateBatchTxParams{
+		CreateBatchParams: CreateBatchParams{
+			Owner:  user.Username,
+			Mode:   mode,
+			Format: "csv",
+		},
+		Items: items,
+		Actor: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, BatchPending, result.Batch.Status)
+	require.Equal(t, int32(len(amounts)), result.Batch.ItemCount)
+	require.Len(t, result.Items, len(amounts))
+	for _, item := range result.Items {
+		require.Equal(t, result.Batch.ID, item.BatchID)
+		require.Equal(t, BatchItemPending, item.Status)
+	}
+
+	return result, from
+}
+
+func requireItemStatuses(t *testing.T, items []BatchItem, statuses ...string) {
+	require.Len(t, items, len(statuses))
+	for i, item := range items {
+		require.Equal(t, statuses[i], item.Status, "item on line %d", item.Line)
+		require.Equal(t, item.Status == BatchItemSucceeded, item.TransferID.Valid)
+	}
+}
+
+func TestExecuteBatchTxAllOrNothing(t *testing.T) {
+	store := NewStore(testDB)
+	created, from := createTestBatch(t, store, BatchModeAllOrNothing, 100, 30, 70)
+
+	result, err := store.ExecuteBatchTx(context.Background(), created.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, BatchCompleted, result.Batch.Status)
+	require.True(t, result.Batch.CompletedAt.Valid)
+	requireItemStatuses(t, result.Items, BatchItemSucceeded, BatchItemSucceeded)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Balance)
+
+	// Running it again, like a retried job, doesn't transfer anything twice.
+	again, err := store.ExecuteBatchTx(context.Background(), created.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Items, again.Items)
+
+	account, err = store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.Balance)
+}
+
+func TestExecuteBatchTxAllOrNothingFails(t *testing.T) {
+	store := NewStore(testDB)
+	// The second payment only fits if the first isn't made.
+	created, from := createTestBatch(t, store, BatchModeAllOrNothing, 100, 30, 80, 10)
+
+	result, err := store.ExecuteBatchTx(context.Background(), created.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, BatchFailed, result.Batch.Status)
+	requireItemStatuses(t, result.Items, BatchItemSkipped, BatchItemFailed, BatchItemSkipped)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Items[1].Error)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+}
+
+func TestExecuteBatchTxBestEffort(t *testing.T) {
+	store := NewStore(testDB)
+	created, from := createTestBatch(t, store, BatchModeBestEffort, 100, 30, 80, 10)
+
+	result, err := store.ExecuteBatchTx(context.Background(), created.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, BatchPartiallyCompleted, result.Batch.Status)
+	requireItemStatuses(t, result.Items, BatchItemSucceeded, BatchItemFailed, BatchItemSucceeded)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Items[1].Error)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), account.Balance)
+
+	for _, item := range result.Items {
+		if !item.TransferID.Valid {
+			continue
+		}
+		transfer, err := store.GetTransfer(context.Background(), item.TransferID.Int64)
+		require.NoError(t, err)
+		require.Equal(t, item.ToAccountID, transfer.ToAccountID)
+		require.Equal(t, item.Amount, transfer.Amount)
+	}
+}
+
+func TestExecuteBatchTxBestEffortAllFail(t *testing.T) {
+	store := NewStore(testDB)
+	created, _ := createTestBatch(t, store, BatchModeBestEffort, 5, 30, 80)
+
+	result, err := store.ExecuteBatchTx(context.Background(), created.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, BatchFailed, result.Batch.Status)
+	requireItemStatuses(t, result.Items, BatchItemFailed, BatchItemFailed)
+}
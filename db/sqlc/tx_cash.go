@@ -0,0 +1,235 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Kinds of cash transaction, and the audit actions written for them.
+const (
+	CashTransactionDeposit    = "deposit"
+	CashTransactionWithdrawal = "withdrawal"
+
+	AuditActionCashDeposited = "cash.deposited"
+	AuditActionCashWithdrawn = "cash.withdrawn"
+)
+
+var (
+	// ErrInsufficientFunds is returned by WithdrawTx and TransferTx when the balance doesn't
+	// cover the amount.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrWithdrawalLimitExceeded is returned by WithdrawTx when a withdrawal limit would be broken.
+	ErrWithdrawalLimitExceeded = errors.New("withdrawal limit exceeded")
+)
+
+// DepositTxParams is cash paid into an account and who took it in.
+type DepositTxParams struct {
+	AccountID int64
+	Amount    int64
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// WithdrawTxParams is cash taken out of an account. MaxAmount limits a single
+// withdrawal and DailyLimit all withdrawals in the last 24 hours. Zero means no limit.
+type WithdrawTxParams struct {
+	AccountID  int64
+	Amount     int64
+	MaxAmount  int64
+	DailyLimit int64
+	Actor      string
+	RequestID  string
+	IP         string
+}
+
+// CashTxResult is everything a deposit or withdrawal created or changed.
+type CashTxResult struct {
+	CashTransaction CashTransaction `json:"cash_transaction"`
+	Account         Account         `json:"account"`
+	CashAccount     Account         `json:"cash_account"`
+	Entry           Entry           `json:"entry"`
+	CashEntry       Entry           `json:"cash_entry"`
+}
+
+// DepositTx pays cash into an account. The money comes from the cash account of the
+// account's currency, so every deposit is explained by the entries.
+func (store *SQLStore) DepositTx(ctx context.Context, arg DepositTxParams) (CashTxResult, error) {
+	var result CashTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = moveCash(ctx, q, cashMove{
+			kind:      CashTransactionDeposit,
+			accountID: arg.AccountID,
+			amount:    arg.Amount,
+			actor:     arg.Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventCashDeposited,
+			AggregateType: "account",
+			AggregateID:   result.Account.ID,
+			Payload:       result,
+		})
+		if err != nil {
+			return err
+		}
+
+		return auditCash(ctx, q, AuditActionCashDeposited, arg.RequestID, arg.IP, result)
+	})
+
+	return result, err
+}
+
+// WithdrawTx pays cash out of an account into the cash account of its currency. It
+// returns ErrInsufficientFunds or ErrWithdrawalLimitExceeded, and nothing is written,
+// when the withdrawal isn't allowed.
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg WithdrawTxParams) (CashTxResult, error) {
+	var result CashTxResult
+
+	if arg.MaxAmount > 0 && arg.Amount > arg.MaxAmount {
+		return result, fmt.Errorf("%w: at most %d per withdrawal", ErrWithdrawalLimitExceeded, arg.MaxAmount)
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = moveCash(ctx, q, cashMove{
+			kind:      CashTransactionWithdrawal,
+			accountID: arg.AccountID,
+			amount:    -arg.Amount,
+			actor:     arg.Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The account row is locked by now, so the checks below can't race with
+		// another withdrawal from the same account.
+		if result.Account.Balance < 0 {
+			return ErrInsufficientFunds
+		}
+
+		if arg.DailyLimit > 0 {
+			// The withdrawal being made is already included.
+			total, err := q.SumWithdrawalsSince(ctx, SumWithdrawalsSinceParams{
+				AccountID: arg.AccountID,
+				CreatedAt: time.Now().Add(-24 * time.Hour),
+			})
+			if err != nil {
+				return err
+			}
+			if total > arg.DailyLimit {
+				return fmt.Errorf("%w: at most %d in 24 hours", ErrWithdrawalLimitExceeded, arg.DailyLimit)
+			}
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          EventCashWithdrawn,
+			AggregateType: "account",
+			AggregateID:   result.Account.ID,
+			Payload:       result,
+		})
+		if err != nil {
+			return err
+		}
+
+		return auditCash(ctx, q, AuditActionCashWithdrawn, arg.RequestID, arg.IP, result)
+	})
+
+	return result, err
+}
+
+// cashMove is one deposit or withdrawal. amount is what the account's balance changes
+// by, so it is negative for a withdrawal.
+type cashMove struct {
+	kind      string
+	accountID int64
+	amount    int64
+	actor     string
+}
+
+// moveCash moves money between an account and the cash account of its currency,
+// creating both entries and the cash transaction.
+func moveCash(ctx context.Context, q *Queries, move cashMove) (CashTxResult, error) {
+	var result CashTxResult
+
+	account, err := q.GetAccount(ctx, move.accountID)
+	if err != nil {
+		return result, err
+	}
+	if account.Kind != AccountKindCustomer {
+		return result, fmt.Errorf("account %d is an internal account", account.ID)
+	}
+
+	cash, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+		Kind:     AccountKindCash,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot get %s cash account: %w", account.Currency, err)
+	}
+
+	// Same lock order as TransferTx, lower ID first, to avoid deadlocks.
+	if account.ID < cash.ID {
+		result.Account, result.CashAccount, err = addMoney(ctx, q, account.ID, move.amount, cash.ID, -move.amount)
+	} else {
+		result.CashAccount, result.Account, err = addMoney(ctx, q, cash.ID, -move.amount, account.ID, move.amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if result.Account.Frozen {
+		return result, ErrAccountFrozen
+	}
+
+	result.Entry, err = createChainedEntry(ctx, q, account.ID, move.amount)
+	if err != nil {
+		return result, err
+	}
+
+	result.CashEntry, err = createChainedEntry(ctx, q, cash.ID, -move.amount)
+	if err != nil {
+		return result, err
+	}
+
+	amount := move.amount
+	if amount < 0 {
+		amount = -amount
+	}
+	result.CashTransaction, err = q.CreateCashTransaction(ctx, CreateCashTransactionParams{
+		AccountID:     account.ID,
+		CashAccountID: cash.ID,
+		Kind:          move.kind,
+		Amount:        amount,
+		Actor:         move.actor,
+		EntryID:       result.Entry.ID,
+		CashEntryID:   result.CashEntry.ID,
+	})
+	return result, err
+}
+
+// auditCash records a deposit or withdrawal with the account's balance before and after it.
+func auditCash(ctx context.Context, q *Queries, action string, requestID string, ip string, result CashTxResult) error {
+	before := result.Account
+	before.Balance -= result.Entry.Amount
+
+	return recordAudit(ctx, q, auditEntry{
+		Actor:      result.CashTransaction.Actor,
+		Action:     action,
+		EntityType: "account",
+		EntityID:   strconv.FormatInt(result.Account.ID, 10),
+		RequestID:  requestID,
+		IP:         ip,
+		Before:     before,
+		After:      result.Account,
+		Metadata:   map[string]any{"cash_transaction": result.CashTransaction},
+	})
+}
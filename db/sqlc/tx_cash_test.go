@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositAndWithdrawTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	cash, err := store.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindCash,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+
+	deposit, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+100, deposit.Account.Balance)
+	require.Equal(t, int64(100), deposit.Entry.Amount)
+	require.Equal(t, cash.ID, deposit.CashAccount.ID)
+	require.Equal(t, int64(-100), deposit.CashEntry.Amount)
+	require.Equal(t, CashTransactionDeposit, deposit.CashTransaction.Kind)
+	require.Equal(t, int64(100), deposit.CashTransaction.Amount)
+
+	withdrawal, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    40,
+		Actor:     account.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+60, withdrawal.Account.Balance)
+	require.Equal(t, int64(-40), withdrawal.Entry.Amount)
+	require.Equal(t, int64(40), withdrawal.CashEntry.Amount)
+	require.Equal(t, CashTransactionWithdrawal, withdrawal.CashTransaction.Kind)
+	require.Equal(t, int64(40), withdrawal.CashTransaction.Amount)
+
+	// The cash account moved by exactly the opposite of the customer account.
+	require.Equal(t, deposit.CashAccount.Balance+40, withdrawal.CashAccount.Balance)
+
+	transactions, err := store.ListCashTransactionsByAccount(context.Background(), ListCashTransactionsByAccountParams{
+		AccountID: account.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+}
+
+func TestWithdrawTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	_, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance + 1,
+		Actor:     account.Owner,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    20,
+		MaxAmount: 10,
+		Actor:     account.Owner,
+	})
+	require.ErrorIs(t, err, ErrWithdrawalLimitExceeded)
+
+	_, err = store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	// The second withdrawal takes the 24 hour total over the daily limit.
+	arg := WithdrawTxParams{
+		AccountID:  account.ID,
+		Amount:     30,
+		DailyLimit: 50,
+		Actor:      account.Owner,
+	}
+	_, err = store.WithdrawTx(context.Background(), arg)
+	require.NoError(t, err)
+	_, err = store.WithdrawTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrWithdrawalLimitExceeded)
+
+	// None of the refused withdrawals changed the balance.
+	updated, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+70, updated.Balance)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a job in the queue. Dead jobs failed every attempt, or failed in a way
+// retrying can't fix, and wait for an admin.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// DefaultJobMaxAttempts is how often a job is tried when its JobRequest doesn't say.
+const DefaultJobMaxAttempts = 5
+
+// JobRequest is a job to add to the queue. Payload is stored as JSON, and a zero RunAt
+// means as soon as a worker is free. The jobs package builds these for each kind.
+type JobRequest struct {
+	Kind        string
+	Payload     any
+	RunAt       time.Time
+	MaxAttempts int32
+}
+
+// enqueueJobs adds jobs with the queries of the transaction doing the business writes,
+// so a job is only ever picked up if those writes were committed.
+func enqueueJobs(ctx context.Context, q *Queries, requests []JobRequest) ([]Job, error) {
+	jobs := make([]Job, 0, len(requests))
+	for _, request := range requests {
+		payload, err := json.Marshal(request.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if request.RunAt.IsZero() {
+			request.RunAt = time.Now()
+		}
+		if request.MaxAttempts == 0 {
+			request.MaxAttempts = DefaultJobMaxAttempts
+		}
+
+		job, err := q.EnqueueJob(ctx, EnqueueJobParams{
+			Kind:        request.Kind,
+			Payload:     payload,
+			MaxAttempts: request.MaxAttempts,
+			RunAt:       request.RunAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// EnqueueJobsTx adds jobs that don't go with any other write. Either all of them are
+// added or none are.
+func (store *SQLStore) EnqueueJobsTx(ctx context.Context, requests []JobRequest) ([]Job, error) {
+	var jobs []Job
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		jobs, err = enqueueJobs(ctx, q, requests)
+		return err
+	})
+
+	return jobs, err
+}
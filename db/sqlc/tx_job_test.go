@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+// dequeueTestJob takes jobs until it gets the given one. Jobs other tests left behind
+// are completed on the way.
+func dequeueTestJob(t *testing.T, store Store, id int64, now time.Time) Job {
+	for range 100 {
+		job, err := store.DequeueJob(context.Background(), DequeueJobParams{
+			LockedUntil: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+			Now:         now,
+		})
+		require.NoError(t, err)
+		if job.ID == id {
+			return job
+		}
+		_, err = store.CompleteJob(context.Background(), CompleteJobParams{ID: job.ID, Attempts: job.Attempts})
+		require.NoError(t, err)
+	}
+	t.Fatalf("job %d was not dequeued", id)
+	return Job{}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	store := NewStore(testDB)
+
+	jobs, err := store.EnqueueJobsTx(context.Background(), []JobRequest{{
+		Kind:        "test." + util.RandomString(6),
+		Payload:     map[string]int64{"account_id": 1},
+		RunAt:       time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaxAttempts: 2,
+	}})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, JobQueued, jobs[0].Status)
+	require.JSONEq(t, `{"account_id": 1}`, string(jobs[0].Payload))
+
+	now := time.Now()
+	job := dequeueTestJob(t, store, jobs[0].ID, now)
+	require.Equal(t, JobRunning, job.Status)
+	require.Equal(t, int32(1), job.Attempts)
+
+	// Hidden from other workers until the visibility timeout runs out.
+	hidden, err := store.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Add(time.Minute), hidden.LockedUntil.Time, time.Second)
+
+	retried, err := store.RetryJob(context.Background(), RetryJobParams{
+		ID:        job.ID,
+		Attempts:  job.Attempts,
+		RunAt:     now,
+		LastError: "boom",
+	})
+	require.NoError(t, err)
+	require.Equal(t, JobQueued, retried.Status)
+	require.Equal(t, "boom", retried.LastError)
+
+	job = dequeueTestJob(t, store, job.ID, now)
+	require.Equal(t, int32(2), job.Attempts)
+
+	// A worker that lost the job can't record an outcome for it.
+	_, err = store.CompleteJob(context.Background(), CompleteJobParams{ID: job.ID, Attempts: 1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	dead, err := store.DeadLetterJob(context.Background(), DeadLetterJobParams{
+		ID:        job.ID,
+		Attempts:  job.Attempts,
+		LastError: "boom again",
+	})
+	require.NoError(t, err)
+	require.Equal(t, JobDead, dead.Status)
+	require.True(t, dead.FinishedAt.Valid)
+
+	requeued, err := store.RequeueDeadJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	require.Equal(t, JobQueued, requeued.Status)
+	require.Zero(t, requeued.Attempts)
+
+	job = dequeueTestJob(t, store, job.ID, time.Now())
+	done, err := store.CompleteJob(context.Background(), CompleteJobParams{ID: job.ID, Attempts: job.Attempts})
+	require.NoError(t, err)
+	require.Equal(t, JobSucceeded, done.Status)
+}
+
+func TestTransferTxJobsRollBack(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := store.SetAccountFrozenTx(context.Background(), SetAccountFrozenTxParams{
+		AccountID: account2.ID,
+		Frozen:    true,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	kind := "test." + util.RandomString(6)
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Jobs:          []JobRequest{{Kind: kind, Payload: map[string]any{}}},
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// The job went with the transfer that was rolled back.
+	queued, err := store.ListJobsByStatus(context.Background(), ListJobsByStatusParams{
+		Status: JobQueued,
+		Limit:  1000,
+	})
+	require.NoError(t, err)
+	for _, job := range queued {
+		require.NotEqual(t, kind, job.Kind)
+	}
+}
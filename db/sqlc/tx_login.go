@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Audit actions written by the login transactions.
+const (
+	AuditActionUserLocked   = "user.locked"
+	AuditActionUserUnlocked = "user.unlocked"
+)
+
+// FailedLoginTxParams is the lockout policy applied to a failed login.
+type FailedLoginTxParams struct {
+	Username string
+	// The account is locked for LockoutDuration once this many logins in a row have failed.
+	MaxAttempts int32
+	// Below MaxAttempts the next attempt has to wait BaseDelay, doubled for every failure.
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+	// Who tried, for the audit event.
+	RequestID string
+	IP        string
+}
+
+// FailedLoginTxResult is the user after the failure was counted.
+type FailedLoginTxResult struct {
+	User User `json:"user"`
+	// Locked is true when this failure triggered the lockout.
+	Locked bool `json:"locked"`
+}
+
+// FailedLoginTx counts a failed login and works out how long the user has to wait
+// before trying again. Reaching MaxAttempts locks the user and writes an audit event.
+func (store *SQLStore) FailedLoginTx(ctx context.Context, arg FailedLoginTxParams) (FailedLoginTxResult, error) {
+	var result FailedLoginTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// Lock the row first so concurrent failures are counted one after the other.
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result, err = failLogin(ctx, q, user, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// LoginAttemptTxParams is a login attempt, checked by Check, and the lockout policy
+// applied when it fails.
+type LoginAttemptTxParams struct {
+	FailedLoginTxParams
+	// Check reports whether the password or code is right. It runs in the transaction
+	// while the user's row is locked, so it must write through q, never the store.
+	Check func(q Querier, user User) (bool, error)
+}
+
+// LoginAttemptTxResult is how an attempt went. The user is as it was when the attempt
+// was made, or after the failure was counted.
+type LoginAttemptTxResult struct {
+	User User `json:"user"`
+	// Waiting is true when the user still had to wait after earlier failures, so the
+	// attempt was refused without being checked.
+	Waiting bool `json:"waiting"`
+	// Failed is true when Check said no. The failure has been counted.
+	Failed bool `json:"failed"`
+	// Locked is true when this failure triggered the lockout.
+	Locked bool `json:"locked"`
+}
+
+// LoginAttemptTx checks a login attempt while holding the user's row lock. Attempts
+// made at the same time are checked one after the other, so once one of them fails
+// the rest have to wait, instead of all being checked against the state before it.
+func (store *SQLStore) LoginAttemptTx(ctx context.Context, arg LoginAttemptTxParams) (LoginAttemptTxResult, error) {
+	var result LoginAttemptTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+		result.User = user
+
+		if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
+			result.Waiting = true
+			return nil
+		}
+
+		ok, err := arg.Check(q, user)
+		if err != nil || ok {
+			return err
+		}
+
+		failed, err := failLogin(ctx, q, user, arg.FailedLoginTxParams)
+		if err != nil {
+			return err
+		}
+		result.User = failed.User
+		result.Failed = true
+		result.Locked = failed.Locked
+		return nil
+	})
+
+	return result, err
+}
+
+// failLogin counts a failed login of a user whose row is locked.
+func failLogin(ctx context.Context, q *Queries, user User, arg FailedLoginTxParams) (FailedLoginTxResult, error) {
+	var result FailedLoginTxResult
+
+	failures := user.FailedLoginAttempts + 1
+	wait := loginDelay(failures, arg.MaxAttempts, arg.BaseDelay, arg.LockoutDuration)
+	result.Locked = failures >= arg.MaxAttempts
+
+	var err error
+	result.User, err = q.RecordFailedLogin(ctx, RecordFailedLoginParams{
+		Username:    arg.Username,
+		LockedUntil: sql.NullTime{Time: time.Now().Add(wait), Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if !result.Locked {
+		return result, nil
+	}
+
+	// The user locked themselves out by failing, so they are the actor.
+	return result, recordAudit(ctx, q, auditEntry{
+		Actor:      arg.Username,
+		Action:     AuditActionUserLocked,
+		EntityType: "user",
+		EntityID:   arg.Username,
+		RequestID:  arg.RequestID,
+		IP:         arg.IP,
+		Metadata: map[string]any{
+			"failed_login_attempts": failures,
+			"locked_until":          result.User.LockedUntil.Time,
+		},
+	})
+}
+
+// loginDelay is how long a user has to wait after their nth failed login in a row:
+// BaseDelay doubled for every failure, up to the lockout once MaxAttempts is reached.
+func loginDelay(failures int32, maxAttempts int32, baseDelay time.Duration, lockout time.Duration) time.Duration {
+	if failures >= maxAttempts {
+		return lockout
+	}
+
+	delay := baseDelay
+	for i := int32(1); i < failures && delay < lockout; i++ {
+		delay *= 2
+	}
+	return min(delay, lockout)
+}
+
+// UnlockUserTxParams says who is unlocking which user.
+type UnlockUserTxParams struct {
+	Username  string
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// UnlockUserTx clears a user's lockout and failed login counter and writes an audit event.
+func (store *SQLStore) UnlockUserTx(ctx context.Context, arg UnlockUserTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.ResetFailedLogins(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionUserUnlocked,
+			EntityType: "user",
+			EntityID:   arg.Username,
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Metadata: map[string]any{
+				"failed_login_attempts": before.FailedLoginAttempts,
+				"locked_until":          before.LockedUntil,
+			},
+		})
+	})
+
+	return user, err
+}
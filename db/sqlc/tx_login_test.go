@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedLoginTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := FailedLoginTxParams{
+		Username:        user.Username,
+		MaxAttempts:     3,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Hour,
+		RequestID:       "test-request",
+		IP:              "127.0.0.1",
+	}
+
+	// The first failures only delay the next attempt.
+	for i := int32(1); i < arg.MaxAttempts; i++ {
+		result, err := store.FailedLoginTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.False(t, result.Locked)
+		require.Equal(t, i, result.User.FailedLoginAttempts)
+		require.True(t, result.User.LockedUntil.Valid)
+	}
+
+	// Reaching MaxAttempts locks the user for the lockout duration.
+	result, err := store.FailedLoginTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.Locked)
+	require.WithinDuration(t, time.Now().Add(time.Hour), result.User.LockedUntil.Time, 5*time.Second)
+
+	// And the lockout is in the audit log.
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "user",
+		EntityID:   user.Username,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditActionUserLocked, events[0].Action)
+	require.Equal(t, user.Username, events[0].Actor)
+	require.Equal(t, "test-request", events[0].RequestID)
+	require.Equal(t, "127.0.0.1", events[0].Ip)
+
+	// An admin unlock clears the lock and is audited too.
+	unlocked, err := store.UnlockUserTx(context.Background(), UnlockUserTxParams{
+		Username: user.Username,
+		Actor:    "admin",
+	})
+	require.NoError(t, err)
+	require.Zero(t, unlocked.FailedLoginAttempts)
+	require.False(t, unlocked.LockedUntil.Valid)
+
+	events, err = store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "user",
+		EntityID:   user.Username,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditActionUserUnlocked, events[1].Action)
+	require.Equal(t, "admin", events[1].Actor)
+}
+
+func TestLoginAttemptTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := LoginAttemptTxParams{
+		FailedLoginTxParams: FailedLoginTxParams{
+			Username:        user.Username,
+			MaxAttempts:     3,
+			BaseDelay:       time.Minute,
+			LockoutDuration: time.Hour,
+		},
+	}
+
+	// A right attempt is checked and leaves the counter alone.
+	arg.Check = func(q Querier, user User) (bool, error) { return true, nil }
+	result, err := store.LoginAttemptTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, result.Waiting)
+	require.False(t, result.Failed)
+	require.Equal(t, user.Username, result.User.Username)
+
+	// Wrong attempts made at once are checked one after the other, so only the first
+	// is checked and the rest are refused by the wait it sets.
+	const n = 5
+	var checks atomic.Int32
+	arg.Check = func(q Querier, user User) (bool, error) {
+		checks.Add(1)
+		return false, nil
+	}
+
+	results := make(chan LoginAttemptTxResult, n)
+	errs := make(chan error, n)
+	for range n {
+		go func() {
+			result, err := store.LoginAttemptTx(context.Background(), arg)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	failed, waiting := 0, 0
+	for range n {
+		require.NoError(t, <-errs)
+		result := <-results
+		if result.Failed {
+			failed++
+		}
+		if result.Waiting {
+			waiting++
+		}
+	}
+	require.Equal(t, int32(1), checks.Load())
+	require.Equal(t, 1, failed)
+	require.Equal(t, n-1, waiting)
+
+	user, err = store.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), user.FailedLoginAttempts)
+}
+
+func TestUnlockUnknownUser(t *testing.T) {
+	store := NewStore(testDB)
+
+	_, err := store.UnlockUserTx(context.Background(), UnlockUserTxParams{Username: "nobody", Actor: "admin"})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestLoginDelay(t *testing.T) {
+	base := time.Second
+	lockout := time.Minute
+
+	// The wait doubles with every failure below the limit.
+	require.Equal(t, time.Second, loginDelay(1, 5, base, lockout))
+	require.Equal(t, 2*time.Second, loginDelay(2, 5, base, lockout))
+	require.Equal(t, 8*time.Second, loginDelay(4, 5, base, lockout))
+	// Reaching or passing the limit means the full lockout.
+	require.Equal(t, lockout, loginDelay(5, 5, base, lockout))
+	require.Equal(t, lockout, loginDelay(9, 5, base, lockout))
+	// The delay never goes past the lockout.
+	require.Equal(t, lockout, loginDelay(8, 20, base, lockout))
+}
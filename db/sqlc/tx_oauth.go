@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+)
+
+// Audit actions written by the OAuth2 client transactions.
+const (
+	AuditActionOAuthClientCreated = "oauth_client.created"
+	AuditActionOAuthClientRevoked = "oauth_client.revoked"
+)
+
+// CreateOAuthClientTxParams is a new client plus the admin registering it.
+type CreateOAuthClientTxParams struct {
+	CreateOAuthClientParams
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// CreateOAuthClientTx registers an OAuth2 client and writes an audit event.
+func (store *SQLStore) CreateOAuthClientTx(ctx context.Context, arg CreateOAuthClientTxParams) (OauthClient, error) {
+	var client OauthClient
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		client, err = q.CreateOAuthClient(ctx, arg.CreateOAuthClientParams)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionOAuthClientCreated,
+			EntityType: "oauth_client",
+			EntityID:   client.ClientID,
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Metadata: map[string]any{
+				"name":   client.Name,
+				"owner":  client.Owner,
+				"scopes": client.Scopes,
+			},
+		})
+	})
+
+	return client, err
+}
+
+// RevokeOAuthClientTxParams says which admin revokes which client.
+type RevokeOAuthClientTxParams struct {
+	ClientID  string
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// RevokeOAuthClientTx revokes a client and writes an audit event. It returns
+// sql.ErrNoRows when the client is unknown or already revoked.
+func (store *SQLStore) RevokeOAuthClientTx(ctx context.Context, arg RevokeOAuthClientTxParams) (OauthClient, error) {
+	var client OauthClient
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		client, err = q.RevokeOAuthClient(ctx, arg.ClientID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionOAuthClientRevoked,
+			EntityType: "oauth_client",
+			EntityID:   client.ClientID,
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+		})
+	})
+
+	return client, err
+}
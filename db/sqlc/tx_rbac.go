@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"strconv"
+)
+
+// Audit actions written by the banker and admin transactions.
+const (
+	AuditActionAccountFrozen   = "account.frozen"
+	AuditActionAccountUnfrozen = "account.unfrozen"
+	AuditActionUserRoleChanged = "user.role_changed"
+)
+
+// SetAccountFrozenTxParams says who is freezing or unfreezing which account.
+type SetAccountFrozenTxParams struct {
+	AccountID int64
+	Frozen    bool
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// SetAccountFrozenTx freezes or unfreezes an account and writes an audit event.
+func (store *SQLStore) SetAccountFrozenTx(ctx context.Context, arg SetAccountFrozenTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		account, err = q.SetAccountFrozen(ctx, SetAccountFrozenParams{
+			ID:     arg.AccountID,
+			Frozen: arg.Frozen,
+		})
+		if err != nil {
+			return err
+		}
+
+		action, eventType := AuditActionAccountUnfrozen, EventAccountUnfrozen
+		if arg.Frozen {
+			action, eventType = AuditActionAccountFrozen, EventAccountFrozen
+		}
+
+		err = writeOutbox(ctx, q, domainEvent{
+			Type:          eventType,
+			AggregateType: "account",
+			AggregateID:   account.ID,
+			Payload:       account,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     action,
+			EntityType: "account",
+			EntityID:   strconv.FormatInt(arg.AccountID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+			After:      account,
+		})
+	})
+
+	return account, err
+}
+
+// UpdateUserRoleTxParams says which admin gives which user a new role.
+type UpdateUserRoleTxParams struct {
+	Username  string
+	Role      string
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// UpdateUserRoleTx changes a user's role and writes an audit event with the old and new role.
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionUserRoleChanged,
+			EntityType: "user",
+			EntityID:   arg.Username,
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     map[string]any{"role": before.Role},
+			After:      map[string]any{"role": user.Role},
+		})
+	})
+
+	return user, err
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+func TestSetAccountFrozenTx(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	require.False(t, account1.Frozen)
+
+	arg := SetAccountFrozenTxParams{
+		AccountID: account1.ID,
+		Frozen:    true,
+		Actor:     "banker",
+		RequestID: "test-request",
+		IP:        "127.0.0.1",
+	}
+	frozen, err := store.SetAccountFrozenTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, frozen.Frozen)
+
+	// No money moves in or out of a frozen account, in either direction.
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// The rolled back transfers left the balances alone.
+	updated, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updated.Balance)
+
+	arg.Frozen = false
+	unfrozen, err := store.SetAccountFrozenTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, unfrozen.Frozen)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "account",
+		EntityID:   strconv.FormatInt(account1.ID, 10),
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditActionAccountFrozen, events[0].Action)
+	require.Equal(t, AuditActionAccountUnfrozen, events[1].Action)
+}
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	updated, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username:  user.Username,
+		Role:      util.BankerRole,
+		Actor:     "admin",
+		RequestID: "test-request",
+		IP:        "127.0.0.1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.BankerRole, updated.Role)
+
+	// The check constraint only allows the known roles.
+	_, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username: user.Username,
+		Role:     "superuser",
+		Actor:    "admin",
+	})
+	require.Error(t, err)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "user",
+		EntityID:   user.Username,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditActionUserRoleChanged, events[0].Action)
+}
+
+func TestListAccountsByOwner(t *testing.T) {
+	account := createRandomAccount(t)
+
+	accounts, err := testQueries.ListAccountsByOwner(context.Background(), ListAccountsByOwnerParams{
+		Owner: account.Owner,
+		Limit: 5,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, accounts)
+	for _, a := range accounts {
+		require.Equal(t, account.Owner, a.Owner)
+	}
+}
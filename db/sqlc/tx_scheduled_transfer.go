@@ -0,0 +1,342 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/techschool/simplebank/schedule"
+)
+
+// Statuses of a scheduled transfer. Only active ones run. Completed and cancelled
+// ones can't be changed any more.
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferPaused    = "paused"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferCancelled = "cancelled"
+)
+
+// Outcomes of a scheduled transfer run.
+const (
+	ScheduledRunSucceeded = "succeeded"
+	ScheduledRunFailed    = "failed"
+)
+
+// Audit actions written for scheduled transfers.
+const (
+	AuditActionScheduledTransferCreated = "scheduled_transfer.created"
+	AuditActionScheduledTransferUpdated = "scheduled_transfer.updated"
+)
+
+// SchedulerActor is the actor recorded for changes the worker makes.
+const SchedulerActor = "scheduler"
+
+// ErrScheduledTransferClosed is returned when changing a completed or cancelled scheduled transfer.
+var ErrScheduledTransferClosed = errors.New("scheduled transfer is completed or cancelled")
+
+// CreateScheduledTransferTxParams is a new scheduled transfer plus who created it.
+// NextRunAt is filled in from the schedule.
+type CreateScheduledTransferTxParams struct {
+	CreateScheduledTransferParams
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// CreateScheduledTransferTx works out the first run, creates the scheduled transfer and
+// writes an audit event. A schedule with no run before EndAt is refused.
+func (store *SQLStore) CreateScheduledTransferTx(ctx context.Context, arg CreateScheduledTransferTxParams) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	sched, err := schedule.Parse(arg.Schedule, arg.StartAt)
+	if err != nil {
+		return scheduled, err
+	}
+	arg.NextRunAt = sched.Next(arg.StartAt.Add(-time.Minute))
+	if arg.EndAt.Valid && arg.NextRunAt.After(arg.EndAt.Time) {
+		return scheduled, fmt.Errorf("%w before end_at", schedule.ErrNeverRuns)
+	}
+
+	err = store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		scheduled, err = q.CreateScheduledTransfer(ctx, arg.CreateScheduledTransferParams)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionScheduledTransferCreated,
+			EntityType: "scheduled_transfer",
+			EntityID:   strconv.FormatInt(scheduled.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			After:      scheduled,
+		})
+	})
+
+	return scheduled, err
+}
+
+// UpdateScheduledTransferTxParams changes a scheduled transfer. Fields that aren't
+// Valid are left alone. Status can be active, paused or cancelled.
+type UpdateScheduledTransferTxParams struct {
+	ID        int64
+	Amount    sql.NullInt64
+	Schedule  sql.NullString
+	EndAt     sql.NullTime
+	Status    sql.NullString
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// UpdateScheduledTransferTx changes a scheduled transfer and writes an audit event.
+// Changing the schedule or resuming a paused one works out the next run from now, and
+// resuming clears the failure count. It returns sql.ErrNoRows for an unknown ID.
+func (store *SQLStore) UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferTxParams) (ScheduledTransfer, error) {
+	var scheduled ScheduledTransfer
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetScheduledTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if before.Status == ScheduledTransferCompleted || before.Status == ScheduledTransferCancelled {
+			return ErrScheduledTransferClosed
+		}
+
+		update := UpdateScheduledTransferParams{
+			ID:                  before.ID,
+			Amount:              before.Amount,
+			Schedule:            before.Schedule,
+			EndAt:               before.EndAt,
+			NextRunAt:           before.NextRunAt,
+			Status:              before.Status,
+			ConsecutiveFailures: before.ConsecutiveFailures,
+			LastRunAt:           before.LastRunAt,
+		}
+		if arg.Amount.Valid {
+			update.Amount = arg.Amount.Int64
+		}
+		if arg.EndAt.Valid {
+			update.EndAt = arg.EndAt
+		}
+		if arg.Status.Valid {
+			update.Status = arg.Status.String
+		}
+
+		resumed := before.Status == ScheduledTransferPaused && update.Status == ScheduledTransferActive
+		if resumed {
+			update.ConsecutiveFailures = 0
+		}
+		if arg.Schedule.Valid || resumed {
+			if arg.Schedule.Valid {
+				update.Schedule = arg.Schedule.String
+			}
+			sched, err := schedule.Parse(update.Schedule, before.StartAt)
+			if err != nil {
+				return err
+			}
+			update.NextRunAt = sched.Next(time.Now())
+			if update.NextRunAt.IsZero() {
+				return schedule.ErrNeverRuns
+			}
+		}
+		if update.Status == ScheduledTransferActive && update.EndAt.Valid && update.NextRunAt.After(update.EndAt.Time) {
+			return fmt.Errorf("%w before end_at", schedule.ErrNeverRuns)
+		}
+
+		scheduled, err = q.UpdateScheduledTransfer(ctx, update)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionScheduledTransferUpdated,
+			EntityType: "scheduled_transfer",
+			EntityID:   strconv.FormatInt(scheduled.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+			After:      scheduled,
+		})
+	})
+
+	return scheduled, err
+}
+
+// RunDueScheduledTransferTxParams controls what happens when a run fails. A failed run
+// is tried again after RetryDelay, until MaxFailures runs in a row have failed and the
+// scheduled transfer is paused.
+type RunDueScheduledTransferTxParams struct {
+	Now         time.Time
+	MaxFailures int32
+	RetryDelay  time.Duration
+}
+
+// RunDueScheduledTransferTxResult is the run that was made. Transfer is only set when it succeeded.
+type RunDueScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+	Transfer          *TransferTxResult    `json:"transfer"`
+}
+
+// RunDueScheduledTransferTx claims one due scheduled transfer with FOR UPDATE SKIP LOCKED,
+// so several workers can run side by side, and runs it. The transfer, the run record
+// and the next run time are committed together, so a transfer can't be made twice.
+// It returns sql.ErrNoRows when nothing is due. Other errors leave the schedule as it
+// was, to be picked up again on the next poll.
+func (store *SQLStore) RunDueScheduledTransferTx(ctx context.Context, arg RunDueScheduledTransferTxParams) (RunDueScheduledTransferTxResult, error) {
+	var result RunDueScheduledTransferTxResult
+	idle := false
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		scheduled, err := q.ClaimDueScheduledTransfer(ctx, arg.Now)
+		if err == sql.ErrNoRows {
+			// Nothing to do isn't a failure, so don't roll back and log it on every poll.
+			idle = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		update := UpdateScheduledTransferParams{
+			ID:                  scheduled.ID,
+			Amount:              scheduled.Amount,
+			Schedule:            scheduled.Schedule,
+			EndAt:               scheduled.EndAt,
+			NextRunAt:           scheduled.NextRunAt,
+			Status:              scheduled.Status,
+			ConsecutiveFailures: scheduled.ConsecutiveFailures,
+			LastRunAt:           sql.NullTime{Time: arg.Now, Valid: true},
+		}
+		run := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledFor:        scheduled.NextRunAt,
+		}
+
+		runErr := checkScheduledTransfer(ctx, q, scheduled)
+		if runErr == nil {
+			transferResult, err := transfer(ctx, q, TransferTxParams{
+				FromAccountID: scheduled.FromAccountID,
+				ToAccountID:   scheduled.ToAccountID,
+				Amount:        scheduled.Amount,
+				Actor:         SchedulerActor,
+				RequestID:     fmt.Sprintf("scheduled_transfer:%d", scheduled.ID),
+			})
+			if err != nil {
+				return err
+			}
+			result.Transfer = &transferResult
+			run.TransferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+		}
+
+		if runErr == nil {
+			run.Status = ScheduledRunSucceeded
+			update.ConsecutiveFailures = 0
+
+			// Runs missed while no worker was running are skipped, not made up for.
+			sched, err := schedule.Parse(scheduled.Schedule, scheduled.StartAt)
+			if err != nil {
+				return err
+			}
+			update.NextRunAt = sched.Next(arg.Now)
+			if update.NextRunAt.IsZero() || (update.EndAt.Valid && update.NextRunAt.After(update.EndAt.Time)) {
+				update.Status = ScheduledTransferCompleted
+			}
+		} else {
+			run.Status = ScheduledRunFailed
+			run.Error = runErr.Error()
+			update.ConsecutiveFailures++
+
+			if update.ConsecutiveFailures >= arg.MaxFailures {
+				update.Status = ScheduledTransferPaused
+			} else {
+				update.NextRunAt = arg.Now.Add(arg.RetryDelay)
+			}
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.UpdateScheduledTransfer(ctx, update)
+		if err != nil {
+			return err
+		}
+
+		if result.ScheduledTransfer.Status == scheduled.Status {
+			return nil
+		}
+		// The worker paused or completed it.
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      SchedulerActor,
+			Action:     AuditActionScheduledTransferUpdated,
+			EntityType: "scheduled_transfer",
+			EntityID:   strconv.FormatInt(scheduled.ID, 10),
+			Before:     scheduled,
+			After:      result.ScheduledTransfer,
+			Metadata:   map[string]any{"run": result.Run},
+		})
+	})
+	if err == nil && idle {
+		return result, sql.ErrNoRows
+	}
+
+	return result, err
+}
+
+// checkScheduledTransfer locks both accounts, lower ID first like TransferTx, and
+// checks the transfer can be made. The error it returns is recorded as the reason the
+// run failed.
+func checkScheduledTransfer(ctx context.Context, q *Queries, scheduled ScheduledTransfer) error {
+	firstID, secondID := scheduled.FromAccountID, scheduled.ToAccountID
+	if secondID < firstID {
+		firstID, secondID = secondID, firstID
+	}
+
+	accounts := map[int64]Account{}
+	for _, id := range []int64{firstID, secondID} {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("account %d no longer exists", id)
+			}
+			return err
+		}
+		accounts[id] = account
+	}
+
+	return checkTransferAccounts(accounts[scheduled.FromAccountID], accounts[scheduled.ToAccountID], scheduled.Currency, scheduled.Amount)
+}
+
+// checkTransferAccounts checks the amount can move from one account to the other: both
+// are customer accounts in the currency and not frozen, and the money is there. The
+// accounts should be locked, so nothing changes before the transfer is made.
+func checkTransferAccounts(from, to Account, currency string, amount int64) error {
+	for _, account := range []Account{from, to} {
+		if account.Kind != AccountKindCustomer {
+			return fmt.Errorf("account %d is an internal account", account.ID)
+		}
+		if account.Currency != currency {
+			return fmt.Errorf("account %d currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		}
+		if account.Frozen {
+			return ErrAccountFrozen
+		}
+	}
+
+	if from.Balance < amount {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createTestScheduledTransfer schedules a daily transfer between two new USD accounts
+// that has been due since long before any other test's.
+func createTestScheduledTransfer(t *testing.T, store Store, fromBalance int64, amount int64) ScheduledTransfer {
+	user := createRandomUser(t)
+
+	accounts := make([]Account, 2)
+	for i, balance := range []int64{fromBalance, 0} {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  balance,
+			Currency: "USD",
+		})
+		require.NoError(t, err)
+		accounts[i] = account
+	}
+
+	startAt := time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC)
+	scheduled, err := store.CreateScheduledTransferTx(context.Background(), CreateScheduledTransferTxParams{
+		CreateScheduledTransferParams: CreateScheduledTransferParams{
+			Owner:         user.Username,
+			FromAccountID: accounts[0].ID,
+			ToAccountID:   accounts[1].ID,
+			Amount:        amount,
+			Currency:      "USD",
+			Schedule:      "0 9 * * *",
+			StartAt:       startAt,
+		},
+		Actor: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferActive, scheduled.Status)
+	require.WithinDuration(t, startAt, scheduled.NextRunAt, time.Second)
+
+	return scheduled
+}
+
+// runScheduledTransfer runs due scheduled transfers until it gets to the given one.
+// Rows other tests left behind are run on the way.
+func runScheduledTransfer(t *testing.T, store Store, id int64, arg RunDueScheduledTransferTxParams) RunDueScheduledTransferTxResult {
+	for range 100 {
+		result, err := store.RunDueScheduledTransferTx(context.Background(), arg)
+		require.NoError(t, err)
+		if result.ScheduledTransfer.ID == id {
+			return result
+		}
+	}
+	t.Fatalf("scheduled transfer %d was not run", id)
+	return RunDueScheduledTransferTxResult{}
+}
+
+func TestRunDueScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	scheduled := createTestScheduledTransfer(t, store, 100, 30)
+
+	now := time.Now()
+	arg := RunDueScheduledTransferTxParams{Now: now, MaxFailures: 3, RetryDelay: time.Hour}
+	result := runScheduledTransfer(t, store, scheduled.ID, arg)
+
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, int64(70), result.Transfer.FromAccount.Balance)
+	require.Equal(t, int64(30), result.Transfer.ToAccount.Balance)
+
+	require.Equal(t, ScheduledRunSucceeded, result.Run.Status)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Run.TransferID.Int64)
+	require.WithinDuration(t, scheduled.NextRunAt, result.Run.ScheduledFor, time.Second)
+
+	// Missed runs are skipped, so the next one is at 9:00 after now.
+	require.Equal(t, ScheduledTransferActive, result.ScheduledTransfer.Status)
+	require.True(t, result.ScheduledTransfer.NextRunAt.After(now))
+	require.True(t, result.ScheduledTransfer.NextRunAt.Before(now.Add(24*time.Hour+time.Second)))
+	require.True(t, result.ScheduledTransfer.LastRunAt.Valid)
+
+	runs, err := store.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+}
+
+func TestRunDueScheduledTransferTxPausesAfterFailures(t *testing.T) {
+	store := NewStore(testDB)
+	scheduled := createTestScheduledTransfer(t, store, 10, 30)
+
+	now := time.Now()
+	arg := RunDueScheduledTransferTxParams{Now: now, MaxFailures: 2, RetryDelay: time.Minute}
+
+	// The first failure is retried after RetryDelay.
+	result := runScheduledTransfer(t, store, scheduled.ID, arg)
+	require.Nil(t, result.Transfer)
+	require.Equal(t, ScheduledRunFailed, result.Run.Status)
+	require.Equal(t, ErrInsufficientFunds.Error(), result.Run.Error)
+	require.Equal(t, int32(1), result.ScheduledTransfer.ConsecutiveFailures)
+	require.Equal(t, ScheduledTransferActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, now.Add(time.Minute), result.ScheduledTransfer.NextRunAt, time.Second)
+
+	// The second one pauses it.
+	arg.Now = now.Add(time.Minute)
+	result = runScheduledTransfer(t, store, scheduled.ID, arg)
+	require.Equal(t, int32(2), result.ScheduledTransfer.ConsecutiveFailures)
+	require.Equal(t, ScheduledTransferPaused, result.ScheduledTransfer.Status)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "scheduled_transfer",
+		EntityID:   strconv.FormatInt(scheduled.ID, 10),
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, AuditActionScheduledTransferCreated, events[0].Action)
+	require.Equal(t, SchedulerActor, events[1].Actor)
+
+	// Resuming clears the failures and picks the next run from now.
+	resumed, err := store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Status: sql.NullString{String: ScheduledTransferActive, Valid: true},
+		Actor:  scheduled.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferActive, resumed.Status)
+	require.Zero(t, resumed.ConsecutiveFailures)
+	require.True(t, resumed.NextRunAt.After(time.Now()))
+}
+
+func TestUpdateScheduledTransferTxClosed(t *testing.T) {
+	store := NewStore(testDB)
+	scheduled := createTestScheduledTransfer(t, store, 100, 30)
+
+	cancelled, err := store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Status: sql.NullString{String: ScheduledTransferCancelled, Valid: true},
+		Actor:  scheduled.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferCancelled, cancelled.Status)
+
+	_, err = store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Amount: sql.NullInt64{Int64: 50, Valid: true},
+		Actor:  scheduled.Owner,
+	})
+	require.ErrorIs(t, err, ErrScheduledTransferClosed)
+}
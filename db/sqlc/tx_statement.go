@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Entries are read this many at a time, so a statement of any size is never held in memory.
+const defaultStatementPageSize = 500
+
+// Statuses of a row in the statements table.
+const (
+	StatementStatusPending = "pending"
+	StatementStatusReady   = "ready"
+)
+
+// StatementSummary is everything about a statement that is known before its lines.
+type StatementSummary struct {
+	Account     Account   `json:"account"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	// The balance at PeriodStart, before the first line.
+	OpeningBalance int64 `json:"opening_balance"`
+	// The balance after the last line. OpeningBalance + TotalCredits - TotalDebits.
+	ClosingBalance int64 `json:"closing_balance"`
+	TotalCredits   int64 `json:"total_credits"`
+	TotalDebits    int64 `json:"total_debits"`
+	EntryCount     int64 `json:"entry_count"`
+}
+
+// StatementLine is one entry of a statement and the balance straight after it.
+type StatementLine struct {
+	ListStatementEntriesRow
+	Balance int64 `json:"balance"`
+}
+
+// StatementTxParams is the account and period of a statement, and what to do with it.
+// The period includes PeriodStart and excludes PeriodEnd.
+type StatementTxParams struct {
+	AccountID   int64
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// How many entries to read per query. Defaults to 500.
+	PageSize int32
+	// Called once, before any line.
+	Begin func(summary StatementSummary) error
+	// Called for every entry in the period, oldest first.
+	Line func(line StatementLine) error
+}
+
+// StatementTx reads an account's statement for a period and hands it to Begin and Line
+// as it goes. Everything is read from one snapshot, so the lines always add up to the
+// totals even while transfers are being made. Returns sql.ErrNoRows if the account
+// doesn't exist, or the first error from Begin or Line.
+func (store *SQLStore) StatementTx(ctx context.Context, arg StatementTxParams) error {
+	pageSize := arg.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultStatementPageSize
+	}
+
+	// Nothing is written, so this doesn't go through execTx: a Line that fails because
+	// the client went away isn't a failed transaction worth logging or counting.
+	tx, err := store.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := New(store.wrap(tx))
+
+	account, err := q.GetAccount(ctx, arg.AccountID)
+	if err != nil {
+		return err
+	}
+
+	totals, err := q.GetStatementTotals(ctx, GetStatementTotalsParams{
+		PeriodEnd:   arg.PeriodEnd,
+		PeriodStart: arg.PeriodStart,
+		AccountID:   arg.AccountID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = arg.Begin(StatementSummary{
+		Account:        account,
+		PeriodStart:    arg.PeriodStart,
+		PeriodEnd:      arg.PeriodEnd,
+		OpeningBalance: totals.OpeningBalance,
+		ClosingBalance: totals.OpeningBalance + totals.TotalCredits - totals.TotalDebits,
+		TotalCredits:   totals.TotalCredits,
+		TotalDebits:    totals.TotalDebits,
+		EntryCount:     totals.EntryCount,
+	})
+	if err != nil {
+		return err
+	}
+
+	balance := totals.OpeningBalance
+	var afterID int64
+	for {
+		rows, err := q.ListStatementEntries(ctx, ListStatementEntriesParams{
+			AccountID:   arg.AccountID,
+			PeriodStart: arg.PeriodStart,
+			PeriodEnd:   arg.PeriodEnd,
+			AfterID:     afterID,
+			Limit:       pageSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			balance += row.Amount
+			if err := arg.Line(StatementLine{ListStatementEntriesRow: row, Balance: balance}); err != nil {
+				return err
+			}
+			afterID = row.ID
+		}
+
+		if len(rows) < int(pageSize) {
+			return tx.Commit()
+		}
+	}
+}
+
+// CreatePendingStatementsTxParams is the period to add statements for. Job returns the
+// job that generates a statement; it is enqueued in the same transaction.
+type CreatePendingStatementsTxParams struct {
+	CreatePendingStatementsParams
+	Job func(statement Statement) JobRequest
+}
+
+// CreatePendingStatementsTx adds up to Limit pending statements for the period, and a
+// job to generate each one. Accounts that already have a statement for the period are
+// skipped, so call it until it returns fewer than Limit.
+func (store *SQLStore) CreatePendingStatementsTx(ctx context.Context, arg CreatePendingStatementsTxParams) ([]Statement, error) {
+	var statements []Statement
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		statements, err = q.CreatePendingStatements(ctx, arg.CreatePendingStatementsParams)
+		if err != nil {
+			return err
+		}
+
+		requests := make([]JobRequest, len(statements))
+		for i, statement := range statements {
+			requests[i] = arg.Job(statement)
+		}
+		_, err = enqueueJobs(ctx, q, requests)
+		return err
+	})
+
+	return statements, err
+}
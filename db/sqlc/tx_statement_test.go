@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	sent, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, sent.FromEntry.ID, sent.Transfer.FromEntryID.Int64)
+	require.Equal(t, sent.ToEntry.ID, sent.Transfer.ToEntryID.Int64)
+
+	deposit, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	received, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        5,
+	})
+	require.NoError(t, err)
+
+	var summary StatementSummary
+	var lines []StatementLine
+	err = store.StatementTx(context.Background(), StatementTxParams{
+		AccountID:   account.ID,
+		PeriodStart: time.Now().Add(-time.Hour),
+		PeriodEnd:   time.Now().Add(time.Hour),
+		// Small pages so the statement takes more than one.
+		PageSize: 2,
+		Begin: func(s StatementSummary) error {
+			summary = s
+			return nil
+		},
+		Line: func(line StatementLine) error {
+			lines = append(lines, line)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	// The account started with a balance but no entry, so the opening balance is that.
+	require.Equal(t, account.Balance, summary.OpeningBalance)
+	require.Equal(t, account.Balance+95, summary.ClosingBalance)
+	require.Equal(t, int64(105), summary.TotalCredits)
+	require.Equal(t, int64(10), summary.TotalDebits)
+	require.Equal(t, int64(3), summary.EntryCount)
+
+	require.Len(t, lines, 3)
+	require.Equal(t, "transfer", lines[0].Kind)
+	require.Equal(t, sent.Transfer.ID, lines[0].ReferenceID)
+	require.Equal(t, other.ID, lines[0].CounterpartyAccountID)
+	require.Equal(t, other.Owner, lines[0].CounterpartyOwner)
+	require.Equal(t, account.Balance-10, lines[0].Balance)
+
+	require.Equal(t, CashTransactionDeposit, lines[1].Kind)
+	require.Equal(t, deposit.CashTransaction.ID, lines[1].ReferenceID)
+	require.Equal(t, deposit.CashAccount.ID, lines[1].CounterpartyAccountID)
+	require.Equal(t, account.Balance+90, lines[1].Balance)
+
+	require.Equal(t, received.Transfer.ID, lines[2].ReferenceID)
+	require.Equal(t, other.ID, lines[2].CounterpartyAccountID)
+	require.Equal(t, summary.ClosingBalance, lines[2].Balance)
+
+	// A period after all of it opens and closes on the current balance.
+	err = store.StatementTx(context.Background(), StatementTxParams{
+		AccountID:   account.ID,
+		PeriodStart: time.Now().Add(time.Hour),
+		PeriodEnd:   time.Now().Add(2 * time.Hour),
+		Begin: func(s StatementSummary) error {
+			summary = s
+			return nil
+		},
+		Line: func(line StatementLine) error {
+			t.Fatal("no lines expected")
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+95, summary.OpeningBalance)
+	require.Equal(t, summary.OpeningBalance, summary.ClosingBalance)
+	require.Zero(t, summary.EntryCount)
+}
+
+func TestCreatePendingStatementsTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+	_, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+
+	// A period no other test uses, so every statement in it comes from this test.
+	now := time.Now().Truncate(time.Microsecond)
+	arg := CreatePendingStatementsTxParams{
+		CreatePendingStatementsParams: CreatePendingStatementsParams{
+			PeriodStart: now.Add(-time.Minute),
+			PeriodEnd:   now.Add(time.Minute),
+			Format:      "pdf",
+			Limit:       1000,
+		},
+		Job: func(statement Statement) JobRequest {
+			return JobRequest{Kind: "statement.generate", Payload: map[string]int64{"statement_id": statement.ID}}
+		},
+	}
+
+	var statement Statement
+	for {
+		statements, err := store.CreatePendingStatementsTx(context.Background(), arg)
+		require.NoError(t, err)
+		for _, s := range statements {
+			if s.AccountID == account.ID {
+				statement = s
+			}
+		}
+		if len(statements) < int(arg.Limit) {
+			break
+		}
+	}
+	require.NotZero(t, statement.ID)
+	require.Equal(t, StatementStatusPending, statement.Status)
+	require.Empty(t, statement.StoragePath)
+
+	// Running again adds nothing for the account.
+	statements, err := store.CreatePendingStatementsTx(context.Background(), arg)
+	require.NoError(t, err)
+	for _, s := range statements {
+		require.NotEqual(t, account.ID, s.AccountID)
+	}
+
+	ready, err := store.CompleteStatement(context.Background(), CompleteStatementParams{
+		StoragePath: "statements/1/statement.pdf",
+		Checksum:    "abc",
+		SizeBytes:   10,
+		ID:          statement.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, StatementStatusReady, ready.Status)
+	require.True(t, ready.CompletedAt.Valid)
+
+	// A statement is only completed once.
+	_, err = store.CompleteStatement(context.Background(), CompleteStatementParams{ID: statement.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	listed, err := store.ListStatementsByAccount(context.Background(), ListStatementsByAccountParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, ready, listed[0])
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+)
+
+// AuditActionTOTPEnabled is written when a user finishes TOTP enrollment.
+const AuditActionTOTPEnabled = "user.totp_enabled"
+
+// EnableTOTPTxParams confirms an enrollment with the step of the first valid code.
+type EnableTOTPTxParams struct {
+	Username string
+	// The time step the confirmation code matched, so it can't be used again to log in.
+	Step int64
+	// Hashes of the new recovery codes. Any old codes are replaced.
+	RecoveryCodeHashes []string
+	RequestID          string
+	IP                 string
+}
+
+// EnableTOTPTx turns TOTP on, stores the recovery codes and writes an audit event.
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		_, err = q.UpdateUserTOTPLastUsedStep(ctx, UpdateUserTOTPLastUsedStepParams{
+			Username: arg.Username,
+			Step:     arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.EnableUserTOTP(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteTOTPRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hash := range arg.RecoveryCodeHashes {
+			_, err = q.CreateTOTPRecoveryCode(ctx, CreateTOTPRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: hash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Username,
+			Action:     AuditActionTOTPEnabled,
+			EntityType: "user",
+			EntityID:   arg.Username,
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Metadata:   map[string]any{"recovery_codes": len(arg.RecoveryCodeHashes)},
+		})
+	})
+
+	return user, err
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	// Enrollment stores the secret but leaves TOTP off.
+	user, err := store.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: "JBSWY3DPEHPK3PXP",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", user.TotpSecret)
+	require.False(t, user.TotpEnabled)
+
+	user, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: []string{"hash-1", "hash-2"},
+		RequestID:          "test-request",
+		IP:                 "127.0.0.1",
+	})
+	require.NoError(t, err)
+	require.True(t, user.TotpEnabled)
+	require.Equal(t, int64(100), user.TotpLastUsedStep)
+
+	// The confirmation code's step can't be used again, but a later one can.
+	_, err = store.UpdateUserTOTPLastUsedStep(context.Background(), UpdateUserTOTPLastUsedStepParams{
+		Username: user.Username,
+		Step:     100,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	updated, err := store.UpdateUserTOTPLastUsedStep(context.Background(), UpdateUserTOTPLastUsedStepParams{
+		Username: user.Username,
+		Step:     101,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), updated.TotpLastUsedStep)
+
+	// Recovery codes work once.
+	code, err := store.UseTOTPRecoveryCode(context.Background(), UseTOTPRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: "hash-1",
+	})
+	require.NoError(t, err)
+	require.True(t, code.UsedAt.Valid)
+
+	_, err = store.UseTOTPRecoveryCode(context.Background(), UseTOTPRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: "hash-1",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	events, err := store.ListAuditEventsForEntity(context.Background(), ListAuditEventsForEntityParams{
+		EntityType: "user",
+		EntityID:   user.Username,
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditActionTOTPEnabled, events[0].Action)
+}
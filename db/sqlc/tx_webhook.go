@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Statuses of a webhook delivery. Failed ones used up their attempts and wait for a replay.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Audit actions written for webhooks.
+const (
+	AuditActionWebhookCreated     = "webhook.created"
+	AuditActionWebhookDeactivated = "webhook.deactivated"
+	AuditActionWebhookReplayed    = "webhook.delivery_replayed"
+)
+
+// ErrWebhookDeliveryPending is returned when replaying a delivery that is still being tried.
+var ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+
+// CreateWebhookSubscriptionTxParams is a new subscription plus the request that made it.
+type CreateWebhookSubscriptionTxParams struct {
+	CreateWebhookSubscriptionParams
+	RequestID string
+	IP        string
+}
+
+// CreateWebhookSubscriptionTx creates a subscription and writes an audit event. The
+// secret is left out of the audit log.
+func (store *SQLStore) CreateWebhookSubscriptionTx(ctx context.Context, arg CreateWebhookSubscriptionTxParams) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		subscription, err = q.CreateWebhookSubscription(ctx, arg.CreateWebhookSubscriptionParams)
+		if err != nil {
+			return err
+		}
+
+		after := subscription
+		after.Secret = ""
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      subscription.Owner,
+			Action:     AuditActionWebhookCreated,
+			EntityType: "webhook_subscription",
+			EntityID:   strconv.FormatInt(subscription.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			After:      after,
+		})
+	})
+
+	return subscription, err
+}
+
+// DeactivateWebhookSubscriptionTxParams says which of the owner's subscriptions to stop.
+type DeactivateWebhookSubscriptionTxParams struct {
+	ID        int64
+	Owner     string
+	RequestID string
+	IP        string
+}
+
+// DeactivateWebhookSubscriptionTx stops a subscription and writes an audit event. Its
+// deliveries are kept. It returns sql.ErrNoRows when the subscription is unknown, not
+// the owner's or already inactive.
+func (store *SQLStore) DeactivateWebhookSubscriptionTx(ctx context.Context, arg DeactivateWebhookSubscriptionTxParams) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		subscription, err = q.DeactivateWebhookSubscription(ctx, DeactivateWebhookSubscriptionParams{
+			ID:    arg.ID,
+			Owner: arg.Owner,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Owner,
+			Action:     AuditActionWebhookDeactivated,
+			EntityType: "webhook_subscription",
+			EntityID:   strconv.FormatInt(subscription.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+		})
+	})
+
+	return subscription, err
+}
+
+// CreateWebhookDeliveriesTxParams is an event to deliver to some subscriptions. Job
+// returns the job that sends a delivery; it is enqueued in the same transaction.
+type CreateWebhookDeliveriesTxParams struct {
+	EventID         int64
+	EventType       string
+	Payload         json.RawMessage
+	SubscriptionIDs []int64
+	Job             func(delivery WebhookDelivery) JobRequest
+}
+
+// CreateWebhookDeliveriesTx creates a delivery and its job for each subscription.
+// Subscriptions that already have a delivery for the event are skipped, so handling
+// the same event twice is harmless.
+func (store *SQLStore) CreateWebhookDeliveriesTx(ctx context.Context, arg CreateWebhookDeliveriesTxParams) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		for _, subscriptionID := range arg.SubscriptionIDs {
+			delivery, err := q.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams{
+				SubscriptionID: subscriptionID,
+				EventID:        arg.EventID,
+				EventType:      arg.EventType,
+				Payload:        arg.Payload,
+			})
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if _, err := enqueueJobs(ctx, q, []JobRequest{arg.Job(delivery)}); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+		return nil
+	})
+
+	return deliveries, err
+}
+
+// RecordWebhookAttemptTxParams is the outcome of one try at sending a delivery.
+// StatusCode is not Valid when the endpoint didn't answer.
+type RecordWebhookAttemptTxParams struct {
+	DeliveryID  int64
+	Succeeded   bool
+	StatusCode  sql.NullInt32
+	Error       string
+	Duration    time.Duration
+	MaxAttempts int32
+}
+
+// RecordWebhookAttemptTx logs an attempt and updates the delivery. A failed delivery
+// stays pending until it has had MaxAttempts tries.
+func (store *SQLStore) RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhookDeliveryForUpdate(ctx, arg.DeliveryID)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateWebhookDeliveryAttempt(ctx, CreateWebhookDeliveryAttemptParams{
+			DeliveryID: arg.DeliveryID,
+			StatusCode: arg.StatusCode,
+			Error:      arg.Error,
+			DurationMs: arg.Duration.Milliseconds(),
+		})
+		if err != nil {
+			return err
+		}
+
+		update := UpdateWebhookDeliveryParams{
+			ID:             before.ID,
+			Status:         WebhookDeliveryPending,
+			Attempts:       before.Attempts + 1,
+			LastStatusCode: arg.StatusCode,
+			LastError:      arg.Error,
+			DeliveredAt:    before.DeliveredAt,
+		}
+		switch {
+		case arg.Succeeded:
+			update.Status = WebhookDeliverySucceeded
+			update.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+		case update.Attempts >= arg.MaxAttempts:
+			update.Status = WebhookDeliveryFailed
+		}
+
+		delivery, err = q.UpdateWebhookDelivery(ctx, update)
+		return err
+	})
+
+	return delivery, err
+}
+
+// ReplayWebhookDeliveryTxParams says who replays which delivery. Job returns the job
+// that sends it again.
+type ReplayWebhookDeliveryTxParams struct {
+	ID        int64
+	Job       func(delivery WebhookDelivery) JobRequest
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+// ReplayWebhookDeliveryTx sends a delivery again with a fresh set of attempts, and
+// writes an audit event. The body is the same as the first time. A delivery that is
+// still pending can't be replayed.
+func (store *SQLStore) ReplayWebhookDeliveryTx(ctx context.Context, arg ReplayWebhookDeliveryTxParams) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhookDeliveryForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if before.Status == WebhookDeliveryPending {
+			return ErrWebhookDeliveryPending
+		}
+
+		delivery, err = q.UpdateWebhookDelivery(ctx, UpdateWebhookDeliveryParams{
+			ID:             before.ID,
+			Status:         WebhookDeliveryPending,
+			Attempts:       0,
+			LastStatusCode: before.LastStatusCode,
+			LastError:      before.LastError,
+			DeliveredAt:    before.DeliveredAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := enqueueJobs(ctx, q, []JobRequest{arg.Job(delivery)}); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEntry{
+			Actor:      arg.Actor,
+			Action:     AuditActionWebhookReplayed,
+			EntityType: "webhook_delivery",
+			EntityID:   strconv.FormatInt(delivery.ID, 10),
+			RequestID:  arg.RequestID,
+			IP:         arg.IP,
+			Before:     before,
+			After:      delivery,
+		})
+	})
+
+	return delivery, err
+}
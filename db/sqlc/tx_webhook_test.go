@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createTestWebhookDelivery(t *testing.T, store Store) (WebhookSubscription, WebhookDelivery) {
+	user := createRandomUser(t)
+
+	subscription, err := store.CreateWebhookSubscriptionTx(context.Background(), CreateWebhookSubscriptionTxParams{
+		CreateWebhookSubscriptionParams: CreateWebhookSubscriptionParams{
+			Owner:      user.Username,
+			Url:        "https://example.com/hooks",
+			EventTypes: []string{"transfer.posted"},
+			Secret:     "whsec_test",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"transfer.posted"}, subscription.EventTypes)
+	require.True(t, subscription.Active)
+
+	event, err := store.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+		EventType:     EventTransferPosted,
+		AggregateType: "transfer",
+		AggregateID:   "1",
+		Payload:       json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	job := func(delivery WebhookDelivery) JobRequest {
+		return JobRequest{Kind: "webhook.deliver", Payload: map[string]int64{"delivery_id": delivery.ID}}
+	}
+	arg := CreateWebhookDeliveriesTxParams{
+		EventID:         event.ID,
+		EventType:       "transfer.posted",
+		Payload:         json.RawMessage(`{"id":1}`),
+		SubscriptionIDs: []int64{subscription.ID},
+		Job:             job,
+	}
+	deliveries, err := store.CreateWebhookDeliveriesTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, WebhookDeliveryPending, deliveries[0].Status)
+
+	// The same event again doesn't make a second delivery.
+	again, err := store.CreateWebhookDeliveriesTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, again)
+
+	return subscription, deliveries[0]
+}
+
+func TestMatchingWebhookSubscriptions(t *testing.T) {
+	store := NewStore(testDB)
+	subscription, _ := createTestWebhookDelivery(t, store)
+
+	matching, err := store.ListMatchingWebhookSubscriptions(context.Background(), ListMatchingWebhookSubscriptionsParams{
+		Owners:    []string{"someone-else", subscription.Owner},
+		EventType: "transfer.posted",
+	})
+	require.NoError(t, err)
+	require.Len(t, matching, 1)
+	require.Equal(t, subscription.ID, matching[0].ID)
+
+	matching, err = store.ListMatchingWebhookSubscriptions(context.Background(), ListMatchingWebhookSubscriptionsParams{
+		Owners:    []string{subscription.Owner},
+		EventType: "account.created",
+	})
+	require.NoError(t, err)
+	require.Empty(t, matching)
+
+	_, err = store.DeactivateWebhookSubscriptionTx(context.Background(), DeactivateWebhookSubscriptionTxParams{
+		ID:    subscription.ID,
+		Owner: subscription.Owner,
+	})
+	require.NoError(t, err)
+
+	matching, err = store.ListMatchingWebhookSubscriptions(context.Background(), ListMatchingWebhookSubscriptionsParams{
+		Owners:    []string{subscription.Owner},
+		EventType: "transfer.posted",
+	})
+	require.NoError(t, err)
+	require.Empty(t, matching)
+
+	// Only once, and only by the owner.
+	_, err = store.DeactivateWebhookSubscriptionTx(context.Background(), DeactivateWebhookSubscriptionTxParams{
+		ID:    subscription.ID,
+		Owner: subscription.Owner,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRecordWebhookAttemptTx(t *testing.T) {
+	store := NewStore(testDB)
+	_, delivery := createTestWebhookDelivery(t, store)
+
+	// A pending delivery is still being tried.
+	_, err := store.ReplayWebhookDeliveryTx(context.Background(), ReplayWebhookDeliveryTxParams{
+		ID:  delivery.ID,
+		Job: func(delivery WebhookDelivery) JobRequest { return JobRequest{Kind: "webhook.deliver"} },
+	})
+	require.ErrorIs(t, err, ErrWebhookDeliveryPending)
+
+	delivery, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:  delivery.ID,
+		StatusCode:  sql.NullInt32{Int32: 503, Valid: true},
+		Error:       "endpoint answered 503 Service Unavailable",
+		Duration:    20 * time.Millisecond,
+		MaxAttempts: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryPending, delivery.Status)
+	require.Equal(t, int32(1), delivery.Attempts)
+
+	delivery, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:  delivery.ID,
+		Error:       "connection refused",
+		MaxAttempts: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryFailed, delivery.Status)
+	require.False(t, delivery.LastStatusCode.Valid)
+
+	attempts, err := store.ListWebhookDeliveryAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, int32(503), attempts[0].StatusCode.Int32)
+	require.Equal(t, int64(20), attempts[0].DurationMs)
+
+	// A replay starts the attempts over and queues a job.
+	delivery, err = store.ReplayWebhookDeliveryTx(context.Background(), ReplayWebhookDeliveryTxParams{
+		ID:    delivery.ID,
+		Job:   func(delivery WebhookDelivery) JobRequest { return JobRequest{Kind: "webhook.deliver"} },
+		Actor: "tester",
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryPending, delivery.Status)
+	require.Zero(t, delivery.Attempts)
+
+	delivery, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:  delivery.ID,
+		Succeeded:   true,
+		StatusCode:  sql.NullInt32{Int32: 200, Valid: true},
+		MaxAttempts: 2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliverySucceeded, delivery.Status)
+	require.True(t, delivery.DeliveredAt.Valid)
+}
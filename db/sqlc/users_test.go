@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/util"
+)
+
+// Create a random user.
+func createRandomUser(t *testing.T) User {
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	user, err := testQueries.CreateUser(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, user)
+
+	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, arg.HashedPassword, user.HashedPassword)
+	require.Equal(t, arg.FullName, user.FullName)
+	require.Equal(t, arg.Email, user.Email)
+	// New users are depositors and are not locked.
+	require.Equal(t, util.DepositorRole, user.Role)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.False(t, user.LockedUntil.Valid)
+
+	require.True(t, user.PasswordChangedAt.IsZero())
+	require.NotZero(t, user.CreatedAt)
+
+	return user
+}
+
+func TestCreateUser(t *testing.T) {
+	createRandomUser(t)
+}
+
+func TestGetUser(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2, err := testQueries.GetUser(context.Background(), user1.Username)
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, user1.HashedPassword, user2.HashedPassword)
+	require.Equal(t, user1.FullName, user2.FullName)
+	require.Equal(t, user1.Email, user2.Email)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+}
+
+func TestRecordAndResetFailedLogins(t *testing.T) {
+	user := createRandomUser(t)
+	lockedUntil := time.Now().Add(time.Minute)
+
+	// Each failure bumps the counter and sets the lock.
+	for i := int32(1); i <= 2; i++ {
+		updated, err := testQueries.RecordFailedLogin(context.Background(), RecordFailedLoginParams{
+			Username:    user.Username,
+			LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		})
+		require.NoError(t, err)
+		require.Equal(t, i, updated.FailedLoginAttempts)
+		require.WithinDuration(t, lockedUntil, updated.LockedUntil.Time, time.Second)
+	}
+
+	reset, err := testQueries.ResetFailedLogins(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, reset.FailedLoginAttempts)
+	require.False(t, reset.LockedUntil.Valid)
+}
+
+// The owner of the internal accounts is a user nobody can register as or log in as.
+func TestSystemUserReserved(t *testing.T) {
+	user, err := testQueries.GetUser(context.Background(), SystemAccountOwner)
+	require.NoError(t, err)
+	require.Error(t, util.CheckPassword("", user.HashedPassword))
+
+	_, err = testQueries.CreateUser(context.Background(), CreateUserParams{
+		Username:       SystemAccountOwner,
+		HashedPassword: user.HashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.Error(t, err)
+}
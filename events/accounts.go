@@ -0,0 +1,123 @@
+package events
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Name turns an event type into the dotted name users see it under, in webhooks and
+// on the WebSocket, e.g. TransferPosted becomes transfer.posted.
+func Name(eventType string) string {
+	for i, r := range eventType {
+		if i > 0 && unicode.IsUpper(r) {
+			return strings.ToLower(eventType[:i]) + "." + strings.ToLower(eventType[i:])
+		}
+	}
+	return strings.ToLower(eventType)
+}
+
+// Names lists the names of every event users can subscribe to.
+var Names = []string{
+	Name(db.EventAccountCreated),
+	Name(db.EventAccountUpdated),
+	Name(db.EventAccountFrozen),
+	Name(db.EventAccountUnfrozen),
+	Name(db.EventAccountDeleted),
+	Name(db.EventBalanceAdjusted),
+	Name(db.EventCashDeposited),
+	Name(db.EventCashWithdrawn),
+	Name(db.EventTransferPosted),
+}
+
+// AccountRef is a customer account an event is about.
+type AccountRef struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+// Accounts finds the customer accounts an event is about. Account events carry the
+// account itself, money movements carry the accounts involved, and transfers both sides.
+// The bank's own accounts, like the cash account of a deposit, are left out.
+func (event Event) Accounts() ([]AccountRef, error) {
+	var shape struct {
+		eventAccount
+		Account     *eventAccount `json:"account"`
+		FromAccount *eventAccount `json:"from_account"`
+		ToAccount   *eventAccount `json:"to_account"`
+	}
+	if err := json.Unmarshal(event.Data, &shape); err != nil {
+		return nil, err
+	}
+
+	var accounts []AccountRef
+	for _, account := range []*eventAccount{&shape.eventAccount, shape.Account, shape.FromAccount, shape.ToAccount} {
+		if account != nil && account.isCustomer() {
+			accounts = append(accounts, account.AccountRef)
+		}
+	}
+	return accounts, nil
+}
+
+// eventAccount is an account in an event's data, with its kind to tell the bank's own
+// accounts apart. Events written before accounts had a kind only have the owner for that.
+type eventAccount struct {
+	AccountRef
+	Kind string `json:"kind"`
+}
+
+func (account eventAccount) isCustomer() bool {
+	if account.Owner == "" || account.Owner == db.SystemAccountOwner {
+		return false
+	}
+	return account.Kind == "" || account.Kind == db.AccountKindCustomer
+}
+
+// Owners returns the users whose accounts an event is about, each once.
+func (event Event) Owners() ([]string, error) {
+	accounts, err := event.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []string
+	for _, account := range accounts {
+		if !slices.Contains(owners, account.Owner) {
+			owners = append(owners, account.Owner)
+		}
+	}
+	return owners, nil
+}
+
+// DataFor is the event's data as owner may see it. Other accounts in it, like the sender
+// of an incoming transfer or the bank's cash account, are cut down to their id and owner
+// so their balance isn't given away.
+func (event Event) DataFor(owner string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(event.Data, &fields); err != nil {
+		return nil, err
+	}
+
+	redacted := false
+	for key, raw := range fields {
+		var account AccountRef
+		// Only accounts have an owner.
+		if json.Unmarshal(raw, &account) != nil || account.Owner == "" || account.Owner == owner {
+			continue
+		}
+		short, err := json.Marshal(account)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = short
+		redacted = true
+	}
+
+	if !redacted {
+		return event.Data, nil
+	}
+	return json.Marshal(fields)
+}
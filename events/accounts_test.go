@@ -0,0 +1,80 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+func TestName(t *testing.T) {
+	require.Equal(t, "transfer.posted", Name(db.EventTransferPosted))
+	require.Equal(t, "account.unfrozen", Name(db.EventAccountUnfrozen))
+	require.Equal(t, "cash.withdrawn", Name(db.EventCashWithdrawn))
+	require.Len(t, Names, 9)
+}
+
+func TestAccounts(t *testing.T) {
+	event := Event{Data: json.RawMessage(`{"id":1,"owner":"alice"}`)}
+	accounts, err := event.Accounts()
+	require.NoError(t, err)
+	require.Equal(t, []AccountRef{{ID: 1, Owner: "alice"}}, accounts)
+
+	event.Data = json.RawMessage(`{
+		"transfer": {"id": 3},
+		"from_account": {"id": 1, "owner": "alice"},
+		"to_account": {"id": 2, "owner": "bob"}
+	}`)
+	accounts, err = event.Accounts()
+	require.NoError(t, err)
+	require.Equal(t, []AccountRef{{ID: 1, Owner: "alice"}, {ID: 2, Owner: "bob"}}, accounts)
+
+	// The cash account on the other side of a deposit belongs to the bank.
+	event.Data = json.RawMessage(`{"account":{"id":1,"owner":"alice"},"cash_account":{"id":9,"owner":"system"}}`)
+	owners, err := event.Owners()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, owners)
+
+	// An internal account is left out by its kind, whoever its owner is.
+	event.Data = json.RawMessage(`{"id":9,"owner":"mallory","kind":"suspense"}`)
+	accounts, err = event.Accounts()
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	// A transfer between two of alice's accounts is about her once.
+	event.Data = json.RawMessage(`{"from_account":{"id":1,"owner":"alice"},"to_account":{"id":4,"owner":"alice"}}`)
+	owners, err = event.Owners()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, owners)
+}
+
+func TestDataFor(t *testing.T) {
+	event := Event{Data: json.RawMessage(`{
+		"transfer": {"id": 3, "amount": 10},
+		"from_account": {"id": 1, "owner": "alice", "balance": 90},
+		"to_account": {"id": 2, "owner": "bob", "balance": 510}
+	}`)}
+
+	data, err := event.DataFor("bob")
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"transfer": {"id": 3, "amount": 10},
+		"from_account": {"id": 1, "owner": "alice"},
+		"to_account": {"id": 2, "owner": "bob", "balance": 510}
+	}`, string(data))
+
+	data, err = event.DataFor("alice")
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"transfer": {"id": 3, "amount": 10},
+		"from_account": {"id": 1, "owner": "alice", "balance": 90},
+		"to_account": {"id": 2, "owner": "bob"}
+	}`, string(data))
+
+	// Account events are only ever about the owner's own account.
+	event.Data = json.RawMessage(`{"id":1,"owner":"alice","balance":90}`)
+	data, err = event.DataFor("alice")
+	require.NoError(t, err)
+	require.Equal(t, event.Data, data)
+}
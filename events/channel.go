@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// ChannelPublisher hands events to a consumer in the same process. Publish waits while
+// the buffer is full, so a slow consumer slows the relay down rather than losing events.
+type ChannelPublisher struct {
+	events    chan Event
+	closeOnce sync.Once
+}
+
+// NewChannelPublisher creates a publisher with room for size events.
+func NewChannelPublisher(size int) *ChannelPublisher {
+	return &ChannelPublisher{events: make(chan Event, size)}
+}
+
+// Events is where the consumer reads from. It is closed by Close.
+func (publisher *ChannelPublisher) Events() <-chan Event {
+	return publisher.events
+}
+
+func (publisher *ChannelPublisher) Publish(ctx context.Context, event Event) error {
+	select {
+	case publisher.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the channel. Publish must not be called afterwards.
+func (publisher *ChannelPublisher) Close() error {
+	publisher.closeOnce.Do(func() {
+		close(publisher.events)
+	})
+	return nil
+}
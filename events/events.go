@@ -0,0 +1,61 @@
+// Package events publishes the domain events transactions write to the outbox table.
+// A Relay polls the outbox and hands each event to a Publisher: an in-process channel,
+// a writer such as stdout, or a NATS server.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// Event is what subscribers receive. ID increases with every event and is the same each
+// time an event is published, so consumers can drop duplicates with it.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// FromOutbox turns an outbox row into the event published for it.
+func FromOutbox(row db.Outbox) Event {
+	return Event{
+		ID:            row.ID,
+		Type:          row.EventType,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		OccurredAt:    row.CreatedAt,
+		Data:          row.Payload,
+	}
+}
+
+// Publisher sends events somewhere. Publish returns once the event has been handed
+// over, and an error means the relay should try it again later.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// NewPublisherFromConfig creates the publisher OUTBOX_PUBLISHER names: stdout or nats.
+func NewPublisherFromConfig(config util.Config) (Publisher, error) {
+	switch config.OutboxPublisher {
+	case "stdout":
+		return NewStdoutPublisher(), nil
+	case "nats":
+		return NewNATSPublisher(config.OutboxNATSURL, config.OutboxSubjectPrefix, NATSOptions{
+			Token:     config.OutboxNATSToken,
+			CredsFile: config.OutboxNATSCredsFile,
+			NKeyFile:  config.OutboxNATSNKeyFile,
+			CAFile:    config.OutboxNATSCAFile,
+		})
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", config.OutboxPublisher)
+	}
+}
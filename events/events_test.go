@@ -0,0 +1,192 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+func testEvent(id int64) Event {
+	return FromOutbox(db.Outbox{
+		ID:            id,
+		EventType:     db.EventAccountCreated,
+		AggregateType: "account",
+		AggregateID:   "7",
+		Payload:       json.RawMessage(`{"id":7}`),
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+}
+
+func TestChannelPublisher(t *testing.T) {
+	publisher := NewChannelPublisher(1)
+
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(1)))
+
+	// The buffer is full, so Publish waits until ctx gives up.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, publisher.Publish(ctx, testEvent(2)), context.DeadlineExceeded)
+
+	event := <-publisher.Events()
+	require.Equal(t, int64(1), event.ID)
+
+	require.NoError(t, publisher.Close())
+	require.NoError(t, publisher.Close())
+	_, ok := <-publisher.Events()
+	require.False(t, ok)
+}
+
+func TestWriterPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewWriterPublisher(&buf)
+
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(1)))
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(2)))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{
+		"id": 1,
+		"type": "AccountCreated",
+		"aggregate_type": "account",
+		"aggregate_id": "7",
+		"occurred_at": "2024-01-02T03:04:05Z",
+		"data": {"id": 7}
+	}`, lines[0])
+}
+
+// failingPublisher refuses every event.
+type failingPublisher struct{ closed bool }
+
+func (publisher *failingPublisher) Publish(ctx context.Context, event Event) error {
+	return fmt.Errorf("cannot publish event %d", event.ID)
+}
+
+func (publisher *failingPublisher) Close() error {
+	publisher.closed = true
+	return nil
+}
+
+func TestFanoutPublisher(t *testing.T) {
+	first := NewChannelPublisher(1)
+	second := NewChannelPublisher(1)
+	publisher := NewFanoutPublisher(first, second)
+
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(1)))
+	require.Equal(t, int64(1), (<-first.Events()).ID)
+	require.Equal(t, int64(1), (<-second.Events()).ID)
+
+	// An error stops the event there, so the relay tries it again later.
+	failing := &failingPublisher{}
+	publisher = NewFanoutPublisher(failing, first)
+	require.Error(t, publisher.Publish(context.Background(), testEvent(2)))
+	require.Empty(t, first.Events())
+
+	require.NoError(t, publisher.Close())
+	require.True(t, failing.closed)
+	_, ok := <-first.Events()
+	require.False(t, ok)
+}
+
+// startNATS runs a NATS server with JetStream in the test, and a stream capturing
+// simplebank.events.> when withStream is set. token, when set, is required to connect.
+func startNATS(t *testing.T, token string, withStream bool) (string, jetstream.JetStream) {
+	ns, err := server.NewServer(&server.Options{
+		Host:          "127.0.0.1",
+		Port:          -1,
+		JetStream:     true,
+		StoreDir:      t.TempDir(),
+		Authorization: token,
+		NoLog:         true,
+		NoSigs:        true,
+	})
+	require.NoError(t, err)
+	go ns.Start()
+	require.True(t, ns.ReadyForConnections(5*time.Second))
+	t.Cleanup(ns.Shutdown)
+
+	conn, err := nats.Connect(ns.ClientURL(), nats.Token(token))
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+
+	if withStream {
+		_, err = js.CreateStream(context.Background(), jetstream.StreamConfig{
+			Name:     "EVENTS",
+			Subjects: []string{"simplebank.events.>"},
+		})
+		require.NoError(t, err)
+	}
+	return ns.ClientURL(), js
+}
+
+func TestNATSPublisher(t *testing.T) {
+	serverURL, js := startNATS(t, "s3cret", true)
+
+	publisher, err := NewNATSPublisher(serverURL, "simplebank.events", NATSOptions{Token: "s3cret"})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(42)))
+	// Publishing an event again, as the relay does after a crash, doesn't store it twice.
+	require.NoError(t, publisher.Publish(context.Background(), testEvent(42)))
+
+	stream, err := js.Stream(context.Background(), "EVENTS")
+	require.NoError(t, err)
+	info, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.State.Msgs)
+
+	msg, err := stream.GetLastMsgForSubject(context.Background(), "simplebank.events.>")
+	require.NoError(t, err)
+	require.Equal(t, "simplebank.events.AccountCreated", msg.Subject)
+	require.Equal(t, "42", msg.Header.Get(jetstream.MsgIDHeader))
+
+	var event Event
+	require.NoError(t, json.Unmarshal(msg.Data, &event))
+	require.Equal(t, int64(42), event.ID)
+}
+
+func TestNATSPublisherNeedsAcknowledgement(t *testing.T) {
+	// Without a stream nothing stores the event, so nothing acknowledges it and the
+	// relay has to try again.
+	serverURL, _ := startNATS(t, "", false)
+
+	publisher, err := NewNATSPublisher(serverURL, "simplebank.events", NATSOptions{})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.Error(t, publisher.Publish(ctx, testEvent(1)))
+}
+
+func TestNATSPublisherServerDown(t *testing.T) {
+	// The publisher is created while the server is down, and Publish fails until it is up.
+	publisher, err := NewNATSPublisher("nats://127.0.0.1:1", "simplebank.events", NATSOptions{})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.Error(t, publisher.Publish(ctx, testEvent(1)))
+}
+
+func TestNewNATSPublisherInvalidURL(t *testing.T) {
+	_, err := NewNATSPublisher("http://localhost:4222", "simplebank.events", NATSOptions{})
+	require.Error(t, err)
+
+	_, err = NewNATSPublisher("nats://localhost:4222", "has space", NATSOptions{})
+	require.Error(t, err)
+}
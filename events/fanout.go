@@ -0,0 +1,36 @@
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// FanoutPublisher hands every event to several publishers in turn. When one of them
+// fails the event is tried again later by the relay, so the ones that already took it
+// see it twice and should drop duplicates by ID.
+type FanoutPublisher struct {
+	publishers []Publisher
+}
+
+// NewFanoutPublisher creates a publisher that publishes to all of publishers.
+func NewFanoutPublisher(publishers ...Publisher) *FanoutPublisher {
+	return &FanoutPublisher{publishers: publishers}
+}
+
+func (publisher *FanoutPublisher) Publish(ctx context.Context, event Event) error {
+	for _, p := range publisher.publishers {
+		if err := p.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every publisher and returns all their errors.
+func (publisher *FanoutPublisher) Close() error {
+	var errs []error
+	for _, p := range publisher.publishers {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// How long the NATS publisher waits to connect, or for JetStream to acknowledge a message.
+const natsTimeout = 5 * time.Second
+
+// NATSOptions are the optional NATS credentials and TLS settings. A user and password
+// can also go in the server URL, and a tls:// URL encrypts the connection.
+type NATSOptions struct {
+	// Token authentication.
+	Token string
+	// A .creds file with a user JWT and NKey seed, as handed out by an operator.
+	CredsFile string
+	// A file holding an NKey seed.
+	NKeyFile string
+	// Root CA for servers with certificates from a private CA.
+	CAFile string
+}
+
+// NATSPublisher publishes each event to the subject <prefix>.<event type> through
+// JetStream, so a JetStream stream has to capture <prefix>.>. An event only counts as
+// published once JetStream has acknowledged it. The event ID goes in the Nats-Msg-Id
+// header, which JetStream uses to drop duplicates when the relay publishes an event
+// again. The client reconnects by itself when the connection drops.
+type NATSPublisher struct {
+	conn          *nats.Conn
+	jetStream     jetstream.JetStream
+	subjectPrefix string
+}
+
+// NewNATSPublisher creates a publisher for one or more comma separated server URLs like
+// nats://localhost:4222. It doesn't wait for the server: until it is reachable, Publish
+// fails and the relay tries the events again later.
+func NewNATSPublisher(serverURL string, subjectPrefix string, options NATSOptions) (*NATSPublisher, error) {
+	for _, rawURL := range strings.Split(serverURL, ",") {
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil {
+			return nil, fmt.Errorf("invalid nats url: %w", err)
+		}
+		if (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+			return nil, fmt.Errorf("invalid nats url %q, expected nats://host:port or tls://host:port", u.Redacted())
+		}
+	}
+	if subjectPrefix == "" || strings.ContainsAny(subjectPrefix, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid nats subject prefix %q", subjectPrefix)
+	}
+
+	opts := []nats.Option{
+		nats.Name("simplebank-outbox"),
+		nats.Timeout(natsTimeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				slog.Warn("nats connection lost", slog.Any("error", err))
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			slog.Info("nats connected", slog.String("url", conn.ConnectedUrlRedacted()))
+		}),
+	}
+	if options.Token != "" {
+		opts = append(opts, nats.Token(options.Token))
+	}
+	if options.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(options.CredsFile))
+	}
+	if options.NKeyFile != "" {
+		opt, err := nats.NkeyOptionFromSeed(options.NKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read nats nkey: %w", err)
+		}
+		opts = append(opts, opt)
+	}
+	if options.CAFile != "" {
+		opts = append(opts, nats.RootCAs(options.CAFile))
+	}
+
+	conn, err := nats.Connect(serverURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to nats: %w", err)
+	}
+	jetStream, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &NATSPublisher{conn: conn, jetStream: jetStream, subjectPrefix: subjectPrefix}, nil
+}
+
+// Publish sends the event and waits for JetStream to store it. A duplicate of an event
+// JetStream already has is acknowledged too, so it counts as published.
+func (publisher *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, natsTimeout)
+		defer cancel()
+	}
+
+	subject := publisher.subjectPrefix + "." + event.Type
+	_, err = publisher.jetStream.Publish(ctx, subject, data, jetstream.WithMsgID(strconv.FormatInt(event.ID, 10)))
+	if err != nil {
+		return fmt.Errorf("cannot publish to nats: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection. Publish waits for every acknowledgement, so nothing
+// is left to send.
+func (publisher *NATSPublisher) Close() error {
+	publisher.conn.Close()
+	return nil
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// RelayConfig controls how often the relay polls the outbox and how many events it
+// publishes per transaction.
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int32
+}
+
+// Relay moves events from the outbox to a publisher, oldest first. Several relays can
+// run at once: each batch is locked with SKIP LOCKED.
+type Relay struct {
+	store     db.Store
+	publisher Publisher
+	config    RelayConfig
+}
+
+// NewRelay creates a relay publishing to publisher.
+func NewRelay(store db.Store, publisher Publisher, config RelayConfig) *Relay {
+	return &Relay{store: store, publisher: publisher, config: config}
+}
+
+// Run relays events until ctx is cancelled.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while whole batches come back, there are probably more waiting.
+		for ctx.Err() == nil {
+			published, err := relay.RelayBatch(ctx)
+			if err != nil {
+				util.LoggerFromContext(ctx).WarnContext(ctx, "cannot relay outbox events",
+					slog.Int("published", published),
+					slog.Any("error", err),
+				)
+				break
+			}
+			if published < int(relay.config.BatchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes up to BatchSize events and returns how many went out.
+func (relay *Relay) RelayBatch(ctx context.Context) (int, error) {
+	return relay.store.RelayOutboxTx(ctx, db.RelayOutboxTxParams{
+		Limit: relay.config.BatchSize,
+		Publish: func(ctx context.Context, row db.Outbox) error {
+			return relay.publisher.Publish(ctx, FromOutbox(row))
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/techschool/simplebank/events"
+)
+
+// client is one connection. Its reader handles the commands the user sends, and its
+// writer sends queued messages and pings.
+type client struct {
+	conn     *websocket.Conn
+	username string
+	send     chan []byte
+
+	// Closed to make the writer say goodbye with closeCode and hang up.
+	quit        chan struct{}
+	quitOnce    sync.Once
+	closeCode   int
+	closeReason string
+
+	mu     sync.Mutex
+	filter filter
+}
+
+func newClient(conn *websocket.Conn, username string) *client {
+	return &client{
+		conn:     conn,
+		username: username,
+		send:     make(chan []byte, sendBuffer),
+		quit:     make(chan struct{}),
+	}
+}
+
+// wants reports whether the client subscribed to an event about these accounts.
+func (c *client) wants(name string, accountIDs []int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.filter.matches(name, accountIDs)
+}
+
+// enqueue queues a message without waiting. A client whose queue is full is too slow
+// to keep up and is disconnected.
+func (c *client) enqueue(body []byte) {
+	select {
+	case c.send <- body:
+	default:
+		c.close(websocket.ClosePolicyViolation, "client is too slow")
+	}
+}
+
+// close makes the writer close the connection. Only the first call counts.
+func (c *client) close(code int, reason string) {
+	c.quitOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.quit)
+	})
+}
+
+// run reads until the connection fails or is closed, and waits for the writer.
+func (c *client) run() {
+	written := make(chan struct{})
+	go func() {
+		c.write()
+		close(written)
+	}()
+
+	c.read()
+	c.close(websocket.CloseNormalClosure, "")
+	<-written
+}
+
+func (c *client) write() {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	// Closing the connection also stops the reader.
+	defer c.conn.Close()
+
+	for {
+		select {
+		case body := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, body); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-c.quit:
+			message := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+			c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// command is what a client sends. "subscribe" replaces the filter; leaving events or
+// accounts out means all of them.
+type command struct {
+	Action   string   `json:"action"`
+	Events   []string `json:"events"`
+	Accounts []int64  `json:"accounts"`
+}
+
+func (c *client) read() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		reply := c.handle(data)
+		body, err := json.Marshal(reply)
+		if err != nil {
+			return
+		}
+		c.enqueue(body)
+	}
+}
+
+// handle runs a command and returns the reply.
+func (c *client) handle(data []byte) message {
+	var cmd command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return message{Type: "error", Error: "commands are JSON objects"}
+	}
+
+	switch cmd.Action {
+	case "subscribe":
+		for _, name := range cmd.Events {
+			if !slices.Contains(events.Names, name) {
+				return message{Type: "error", Error: fmt.Sprintf("unknown event type %q", name)}
+			}
+		}
+		// Accounts that aren't the user's never match, since events only go to owners.
+		f := filter{Events: cmd.Events, Accounts: cmd.Accounts}
+		c.mu.Lock()
+		c.filter = f
+		c.mu.Unlock()
+		return message{Type: "subscribed", Filter: &f}
+	default:
+		return message{Type: "error", Error: fmt.Sprintf("unknown action %q", cmd.Action)}
+	}
+}
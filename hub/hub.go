@@ -0,0 +1,216 @@
+// Package hub pushes domain events to users over WebSocket connections. Every server
+// instance hears about every committed outbox event through Postgres notifications, and
+// the hub sends each one to the connections of the users whose accounts it is about.
+//
+// Pushes are best effort: a client that falls behind is disconnected, and events that
+// happen while it is away aren't replayed, so clients refetch what they show when they
+// reconnect.
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/events"
+)
+
+const (
+	// How long a single write to a client may take.
+	writeWait = 10 * time.Second
+	// A client that sends nothing, not even a pong, for this long is gone.
+	pongWait = 60 * time.Second
+	// Pings go out often enough for the pong to arrive within pongWait.
+	pingPeriod = pongWait * 9 / 10
+	// Messages waiting for a client. A client that lets this fill up is disconnected
+	// rather than slowing down everyone else.
+	sendBuffer = 64
+	// The largest message a client may send.
+	maxMessageSize = 4 << 10
+	// Event ids waiting to be fetched and sent. More are dropped with a warning.
+	pendingEvents = 1024
+)
+
+// Hub keeps the open connections of each user and fans events out to them.
+type Hub struct {
+	store   db.Store
+	pending chan int64
+
+	mu      sync.Mutex
+	clients map[string]map[*client]struct{}
+}
+
+// New creates a hub. Run must be running for events to be sent.
+func New(store db.Store) *Hub {
+	return &Hub{
+		store:   store,
+		pending: make(chan int64, pendingEvents),
+		clients: map[string]map[*client]struct{}{},
+	}
+}
+
+// Notify tells the hub an outbox event was committed. It never blocks, so it can be
+// called from the listener that hears the notifications.
+func (hub *Hub) Notify(eventID int64) {
+	select {
+	case hub.pending <- eventID:
+	default:
+		slog.Warn("websocket hub is behind, dropping event", slog.Int64("event_id", eventID))
+	}
+}
+
+// Run sends the events the hub is notified of until ctx is cancelled, then
+// disconnects every client.
+func (hub *Hub) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			hub.mu.Lock()
+			for _, clients := range hub.clients {
+				for c := range clients {
+					c.close(websocket.CloseGoingAway, "server is shutting down")
+				}
+			}
+			hub.mu.Unlock()
+			return
+		case eventID := <-hub.pending:
+			if err := hub.broadcast(ctx, eventID); err != nil {
+				slog.Warn("cannot push event", slog.Int64("event_id", eventID), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// eventMessage is an event as a user sees it. Accounts are the user's accounts it is about.
+type eventMessage struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Accounts  []int64         `json:"accounts"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// message is everything the hub sends: "event", "subscribed" or "error".
+type message struct {
+	Type   string        `json:"type"`
+	Event  *eventMessage `json:"event,omitempty"`
+	Filter *filter       `json:"filter,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// broadcast sends an event to the connections of every user it is about.
+func (hub *Hub) broadcast(ctx context.Context, eventID int64) error {
+	// Most events are about users who aren't connected, so don't fetch them for nothing.
+	hub.mu.Lock()
+	connected := len(hub.clients) > 0
+	hub.mu.Unlock()
+	if !connected {
+		return nil
+	}
+
+	row, err := hub.store.GetOutboxEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	event := events.FromOutbox(row)
+	accounts, err := event.Accounts()
+	if err != nil {
+		return err
+	}
+
+	byOwner := map[string][]int64{}
+	for _, account := range accounts {
+		byOwner[account.Owner] = append(byOwner[account.Owner], account.ID)
+	}
+
+	name := events.Name(event.Type)
+	for owner, accountIDs := range byOwner {
+		hub.mu.Lock()
+		clients := make([]*client, 0, len(hub.clients[owner]))
+		for c := range hub.clients[owner] {
+			if c.wants(name, accountIDs) {
+				clients = append(clients, c)
+			}
+		}
+		hub.mu.Unlock()
+		if len(clients) == 0 {
+			continue
+		}
+
+		data, err := event.DataFor(owner)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(message{
+			Type: "event",
+			Event: &eventMessage{
+				ID:        event.ID,
+				Type:      name,
+				Accounts:  accountIDs,
+				CreatedAt: event.OccurredAt,
+				Data:      data,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, c := range clients {
+			c.enqueue(body)
+		}
+	}
+	return nil
+}
+
+// Serve runs a user's connection until it ends.
+func (hub *Hub) Serve(conn *websocket.Conn, username string) {
+	c := newClient(conn, username)
+
+	hub.mu.Lock()
+	if hub.clients[username] == nil {
+		hub.clients[username] = map[*client]struct{}{}
+	}
+	hub.clients[username][c] = struct{}{}
+	hub.mu.Unlock()
+
+	defer func() {
+		hub.mu.Lock()
+		delete(hub.clients[username], c)
+		if len(hub.clients[username]) == 0 {
+			delete(hub.clients, username)
+		}
+		hub.mu.Unlock()
+	}()
+
+	c.run()
+}
+
+// Connections returns how many connections a user has open.
+func (hub *Hub) Connections(username string) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.clients[username])
+}
+
+// filter is what a client subscribed to. Empty lists match everything.
+type filter struct {
+	Events   []string `json:"events"`
+	Accounts []int64  `json:"accounts"`
+}
+
+func (f filter) matches(name string, accountIDs []int64) bool {
+	if len(f.Events) > 0 && !slices.Contains(f.Events, name) {
+		return false
+	}
+	if len(f.Accounts) > 0 && !slices.ContainsFunc(accountIDs, func(id int64) bool {
+		return slices.Contains(f.Accounts, id)
+	}) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package hub
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// fakeStore serves outbox events from a map.
+type fakeStore struct {
+	db.Store
+	events map[int64]db.Outbox
+}
+
+func (store *fakeStore) GetOutboxEvent(ctx context.Context, id int64) (db.Outbox, error) {
+	event, ok := store.events[id]
+	if !ok {
+		return event, sql.ErrNoRows
+	}
+	return event, nil
+}
+
+func transferEvent(id int64, fromOwner string, toOwner string) db.Outbox {
+	return db.Outbox{
+		ID:        id,
+		EventType: db.EventTransferPosted,
+		Payload: json.RawMessage(`{
+			"transfer": {"id": 3, "amount": 10},
+			"from_account": {"id": 1, "owner": "` + fromOwner + `", "balance": 90},
+			"to_account": {"id": 2, "owner": "` + toOwner + `", "balance": 510}
+		}`),
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// testClient is the client side of a connection to the hub.
+type testClient struct {
+	conn *websocket.Conn
+}
+
+func connect(t *testing.T, hub *Hub, username string) *testClient {
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(conn, username)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	require.Eventually(t, func() bool { return hub.Connections(username) > 0 }, time.Second, time.Millisecond)
+	return &testClient{conn: conn}
+}
+
+func (c *testClient) sendJSON(t *testing.T, v any) {
+	require.NoError(t, c.conn.WriteJSON(v))
+}
+
+func (c *testClient) receiveMessage(t *testing.T) message {
+	messageType, payload, err := c.conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, messageType)
+	var msg message
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	return msg
+}
+
+// closeCode reads until the hub closes the connection and returns the close code.
+func (c *testClient) closeCode(t *testing.T) int {
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		require.ErrorAs(t, err, &closeErr)
+		return closeErr.Code
+	}
+}
+
+func TestHubPushesEventsToOwners(t *testing.T) {
+	store := &fakeStore{events: map[int64]db.Outbox{
+		1: transferEvent(1, "alice", "bob"),
+		2: transferEvent(2, "carol", "alice"),
+	}}
+	hub := New(store)
+
+	alice := connect(t, hub, "alice")
+	bob := connect(t, hub, "bob")
+
+	require.NoError(t, hub.broadcast(context.Background(), 1))
+
+	msg := bob.receiveMessage(t)
+	require.Equal(t, "event", msg.Type)
+	require.Equal(t, int64(1), msg.Event.ID)
+	require.Equal(t, "transfer.posted", msg.Event.Type)
+	require.Equal(t, []int64{2}, msg.Event.Accounts)
+	// Bob doesn't get to see alice's balance.
+	require.JSONEq(t, `{
+		"transfer": {"id": 3, "amount": 10},
+		"from_account": {"id": 1, "owner": "alice"},
+		"to_account": {"id": 2, "owner": "bob", "balance": 510}
+	}`, string(msg.Event.Data))
+
+	msg = alice.receiveMessage(t)
+	require.Equal(t, []int64{1}, msg.Event.Accounts)
+
+	// Alice only wants to hear about account 1 from now on.
+	alice.sendJSON(t, command{Action: "subscribe", Events: []string{"transfer.posted"}, Accounts: []int64{1}})
+	msg = alice.receiveMessage(t)
+	require.Equal(t, "subscribed", msg.Type)
+	require.Equal(t, []int64{1}, msg.Filter.Accounts)
+
+	alice.sendJSON(t, command{Action: "subscribe", Events: []string{"transfer.lost"}})
+	msg = alice.receiveMessage(t)
+	require.Equal(t, "error", msg.Type)
+
+	// Event 2 is about alice's account 2, which she filtered out, so the next thing
+	// she gets is event 1 again.
+	require.NoError(t, hub.broadcast(context.Background(), 2))
+	require.NoError(t, hub.broadcast(context.Background(), 1))
+	msg = alice.receiveMessage(t)
+	require.Equal(t, int64(1), msg.Event.ID)
+
+	// An event that was never committed, or is about nobody connected, is skipped.
+	require.Error(t, hub.broadcast(context.Background(), 99))
+}
+
+func TestHubDisconnectsSlowClients(t *testing.T) {
+	store := &fakeStore{events: map[int64]db.Outbox{1: transferEvent(1, "alice", "bob")}}
+	hub := New(store)
+	alice := connect(t, hub, "alice")
+
+	// Fill alice's queue faster than the writer can empty it.
+	hub.mu.Lock()
+	var c *client
+	for c = range hub.clients["alice"] {
+	}
+	hub.mu.Unlock()
+	for range sendBuffer * 16 {
+		c.enqueue([]byte(`{}`))
+	}
+
+	// Whatever made it out is followed by a close frame saying why.
+	require.Equal(t, websocket.ClosePolicyViolation, alice.closeCode(t))
+	require.Eventually(t, func() bool { return hub.Connections("alice") == 0 }, time.Second, time.Millisecond)
+}
+
+func TestHubShutdown(t *testing.T) {
+	hub := New(&fakeStore{})
+	alice := connect(t, hub, "alice")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	hub.Notify(1)
+	cancel()
+	<-done
+
+	require.Equal(t, websocket.CloseGoingAway, alice.closeCode(t))
+}
@@ -0,0 +1,86 @@
+// Package jobs runs background work from a queue kept in the jobs table. Jobs are
+// added with the store, either on their own or in the same transaction as the writes
+// they follow from, and run by workers polling the table with SKIP LOCKED.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Kind names a type of job and the Go type of its payload, so the code adding a job
+// and the handler running it agree on what the payload looks like.
+type Kind[T any] struct {
+	Name string
+}
+
+// NewKind declares a kind of job. Names are stored with each job, so don't rename one
+// while jobs of that kind are still queued.
+func NewKind[T any](name string) Kind[T] {
+	return Kind[T]{Name: name}
+}
+
+// New returns a request for a job of this kind, ready for Store.EnqueueJobsTx or the
+// Jobs field of a transaction's params.
+func (kind Kind[T]) New(payload T) db.JobRequest {
+	return db.JobRequest{Kind: kind.Name, Payload: payload}
+}
+
+// handler runs one job with its payload still encoded.
+type handler func(ctx context.Context, payload json.RawMessage) error
+
+// Registry maps kinds to their handlers. Handlers are registered at startup, before
+// any worker runs.
+type Registry struct {
+	handlers map[string]handler
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{handlers: map[string]handler{}}
+}
+
+// Register sets the handler for a kind. It panics if the kind already has one, like
+// registering a route twice.
+func Register[T any](registry *Registry, kind Kind[T], handle func(ctx context.Context, payload T) error) {
+	if _, ok := registry.handlers[kind.Name]; ok {
+		panic(fmt.Sprintf("jobs: handler for %s registered twice", kind.Name))
+	}
+
+	registry.handlers[kind.Name] = func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return Permanent(fmt.Errorf("cannot decode %s payload: %w", kind.Name, err))
+		}
+		return handle(ctx, payload)
+	}
+}
+
+// permanentError is a failure trying again won't fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks a handler error as one retrying won't fix, so the job goes straight
+// to the dead letters instead of using up its attempts.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
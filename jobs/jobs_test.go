@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+type greetPayload struct {
+	Name string `json:"name"`
+}
+
+var greet = NewKind[greetPayload]("test.greet")
+
+func TestRegisterDecodesPayload(t *testing.T) {
+	registry := NewRegistry()
+
+	var got greetPayload
+	Register(registry, greet, func(ctx context.Context, payload greetPayload) error {
+		got = payload
+		return nil
+	})
+
+	request := greet.New(greetPayload{Name: "alice"})
+	require.Equal(t, "test.greet", request.Kind)
+	raw, err := json.Marshal(request.Payload)
+	require.NoError(t, err)
+
+	worker := NewWorker(nil, registry, Config{VisibilityTimeout: time.Minute})
+	err = worker.handle(context.Background(), db.Job{Kind: greet.Name, Payload: raw})
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Name)
+
+	// A payload that doesn't decode will never work, however often it is tried.
+	err = worker.handle(context.Background(), db.Job{Kind: greet.Name, Payload: json.RawMessage(`"nope"`)})
+	require.Error(t, err)
+	require.True(t, IsPermanent(err))
+
+	require.Panics(t, func() {
+		Register(registry, greet, func(ctx context.Context, payload greetPayload) error { return nil })
+	})
+}
+
+func TestHandleFailures(t *testing.T) {
+	registry := NewRegistry()
+	errBoom := errors.New("boom")
+	Register(registry, greet, func(ctx context.Context, payload greetPayload) error {
+		if payload.Name == "panic" {
+			panic("oh no")
+		}
+		return errBoom
+	})
+	worker := NewWorker(nil, registry, Config{VisibilityTimeout: time.Minute})
+
+	err := worker.handle(context.Background(), db.Job{Kind: greet.Name, Payload: json.RawMessage(`{}`)})
+	require.ErrorIs(t, err, errBoom)
+	require.False(t, IsPermanent(err))
+
+	err = worker.handle(context.Background(), db.Job{Kind: greet.Name, Payload: json.RawMessage(`{"name":"panic"}`)})
+	require.ErrorContains(t, err, "oh no")
+
+	err = worker.handle(context.Background(), db.Job{Kind: "test.unknown", Payload: json.RawMessage(`{}`)})
+	require.True(t, IsPermanent(err))
+}
+
+func TestBackoff(t *testing.T) {
+	worker := NewWorker(nil, NewRegistry(), Config{
+		RetryBackoff:    10 * time.Second,
+		MaxRetryBackoff: time.Minute,
+	})
+
+	require.Equal(t, 10*time.Second, worker.backoff(1))
+	require.Equal(t, 20*time.Second, worker.backoff(2))
+	require.Equal(t, 40*time.Second, worker.backoff(3))
+	require.Equal(t, time.Minute, worker.backoff(4))
+	require.Equal(t, time.Minute, worker.backoff(30))
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// VerifyEntryChainPayload names the account whose entry hash chain to check.
+type VerifyEntryChainPayload struct {
+	AccountID int64 `json:"account_id"`
+}
+
+// VerifyEntryChain re-checks an account's hash chain, e.g. after a banker has adjusted it.
+var VerifyEntryChain = NewKind[VerifyEntryChainPayload]("ledger.verify_entry_chain")
+
+// VerifyEntryChainHandler fails for good when the chain is broken, so the job ends up
+// in the dead letters for an admin to look at.
+func VerifyEntryChainHandler(store db.Store) func(ctx context.Context, payload VerifyEntryChainPayload) error {
+	return func(ctx context.Context, payload VerifyEntryChainPayload) error {
+		result, err := store.VerifyEntryChain(ctx, payload.AccountID)
+		if err != nil {
+			return err
+		}
+		if !result.Valid {
+			return Permanent(fmt.Errorf("entry chain of account %d is broken at entry %d: %s",
+				payload.AccountID, result.BrokenEntryID, result.Reason))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// Config controls how workers poll and retry.
+type Config struct {
+	// How many jobs run at once in this process.
+	Concurrency  int
+	PollInterval time.Duration
+	// How long a job stays hidden from other workers once taken. A handler still
+	// running after this is cancelled, and the job may be taken by another worker.
+	VisibilityTimeout time.Duration
+	// The wait before the first retry. It doubles with each attempt, up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+// Worker takes jobs from the queue and runs their handlers.
+type Worker struct {
+	store    db.Store
+	registry *Registry
+	config   Config
+}
+
+// NewWorker creates a worker running the handlers in registry.
+func NewWorker(store db.Store, registry *Registry, config Config) *Worker {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	return &Worker{store: store, registry: registry, config: config}
+}
+
+// Run starts Concurrency pollers and blocks until ctx is cancelled and the jobs they
+// are running have finished.
+func (worker *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range worker.config.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.poll(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// poll runs jobs until the queue is empty, then waits for the next tick.
+func (worker *Worker) poll(ctx context.Context) {
+	ticker := time.NewTicker(worker.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && worker.RunNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext takes one job and runs it. It returns false when there was no job to take,
+// or the queue couldn't be read.
+func (worker *Worker) RunNext(ctx context.Context) bool {
+	logger := util.LoggerFromContext(ctx)
+	// Bookkeeping goes through even when ctx is cancelled, so a job is never left running.
+	storeCtx := context.WithoutCancel(ctx)
+
+	now := time.Now()
+	job, err := worker.store.DequeueJob(storeCtx, db.DequeueJobParams{
+		LockedUntil: sql.NullTime{Time: now.Add(worker.config.VisibilityTimeout), Valid: true},
+		Now:         now,
+	})
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "cannot dequeue job", slog.Any("error", err))
+		return false
+	}
+
+	logger = logger.With(
+		slog.Int64("job_id", job.ID),
+		slog.String("kind", job.Kind),
+		slog.Int("attempt", int(job.Attempts)),
+	)
+
+	var runErr error
+	if job.Attempts > job.MaxAttempts {
+		// Workers kept losing it, e.g. by crashing while running it.
+		runErr = Permanent(fmt.Errorf("visibility timeout ran out on the last of %d attempts", job.MaxAttempts))
+	} else {
+		runErr = worker.handle(ctx, job)
+	}
+
+	switch {
+	case runErr == nil:
+		_, err = worker.store.CompleteJob(storeCtx, db.CompleteJobParams{ID: job.ID, Attempts: job.Attempts})
+		logger.InfoContext(ctx, "job succeeded")
+	case IsPermanent(runErr) || job.Attempts >= job.MaxAttempts:
+		_, err = worker.store.DeadLetterJob(storeCtx, db.DeadLetterJobParams{
+			ID:        job.ID,
+			Attempts:  job.Attempts,
+			LastError: runErr.Error(),
+		})
+		logger.ErrorContext(ctx, "job failed for good, moved to dead letters", slog.Any("error", runErr))
+	default:
+		runAt := time.Now().Add(worker.backoff(job.Attempts))
+		_, err = worker.store.RetryJob(storeCtx, db.RetryJobParams{
+			ID:        job.ID,
+			Attempts:  job.Attempts,
+			RunAt:     runAt,
+			LastError: runErr.Error(),
+		})
+		logger.WarnContext(ctx, "job failed, will retry", slog.Any("error", runErr), slog.Time("run_at", runAt))
+	}
+
+	// No row means the visibility timeout ran out and another worker has the job now.
+	if err == sql.ErrNoRows {
+		logger.WarnContext(ctx, "job was taken over by another worker before it finished")
+	} else if err != nil {
+		logger.ErrorContext(ctx, "cannot record job outcome", slog.Any("error", err))
+	}
+	return true
+}
+
+// handle runs the job's handler within the visibility timeout, turning a panic into an error.
+func (worker *Worker) handle(ctx context.Context, job db.Job) (err error) {
+	handle, ok := worker.registry.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for %s", job.Kind))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, worker.config.VisibilityTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	return handle(ctx, job.Payload)
+}
+
+// backoff is the wait before retrying after the given attempt failed.
+func (worker *Worker) backoff(attempt int32) time.Duration {
+	wait := worker.config.RetryBackoff
+	for i := int32(1); i < attempt; i++ {
+		wait *= 2
+		if wait >= worker.config.MaxRetryBackoff {
+			return worker.config.MaxRetryBackoff
+		}
+	}
+	return min(wait, worker.config.MaxRetryBackoff)
+}
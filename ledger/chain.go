@@ -0,0 +1,23 @@
+// Package ledger makes the entries table tamper evident. Every entry stores the hash
+// of the account's previous entry, so editing, deleting or reordering an entry breaks
+// the chain from that point on.
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// GenesisHash is the previous hash of an account's first entry.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// EntryHash is the hex SHA-256 of an entry chained to the hash of the entry before it.
+// The migration that added the chain computes the same thing in SQL, so the two must
+// be changed together.
+func EntryHash(prevHash string, id int64, accountID int64, amount int64, createdAt time.Time) string {
+	data := fmt.Sprintf("%s|%d|%d|%d|%d", prevHash, id, accountID, amount, createdAt.UnixMicro())
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
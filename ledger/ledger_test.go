@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryHash(t *testing.T) {
+	createdAt := time.Date(2024, 1, 31, 12, 0, 0, 123456000, time.UTC)
+
+	hash := EntryHash(GenesisHash, 1, 2, -300, createdAt)
+	require.Len(t, hash, 64)
+	// Same input, same hash, whatever the time zone.
+	require.Equal(t, hash, EntryHash(GenesisHash, 1, 2, -300, createdAt.In(time.FixedZone("X", 3600))))
+
+	// Changing any part of the entry, or the hash it chains to, changes the hash.
+	require.NotEqual(t, hash, EntryHash(GenesisHash, 1, 2, 300, createdAt))
+	require.NotEqual(t, hash, EntryHash(GenesisHash, 3, 2, -300, createdAt))
+	require.NotEqual(t, hash, EntryHash(GenesisHash, 1, 2, -300, createdAt.Add(time.Microsecond)))
+	require.NotEqual(t, hash, EntryHash(strings.Repeat("1", 64), 1, 2, -300, createdAt))
+}
+
+func TestSignCheckpoint(t *testing.T) {
+	signer, err := NewSigner(strings.Repeat("ab", 32))
+	require.NoError(t, err)
+
+	signed := signer.Sign(Checkpoint{
+		AccountID:  1,
+		EntryID:    10,
+		Hash:       EntryHash(GenesisHash, 10, 1, 5, time.Now()),
+		EntryCount: 4,
+		SignedAt:   time.Now(),
+	})
+	require.Equal(t, CheckpointAlgorithm, signed.Algorithm)
+	require.Equal(t, signer.PublicKey(), signed.PublicKey)
+	require.NoError(t, VerifyCheckpoint(signed))
+
+	tampered := signed
+	tampered.EntryCount = 3
+	require.ErrorIs(t, VerifyCheckpoint(tampered), ErrInvalidSignature)
+
+	other, err := NewSigner(strings.Repeat("cd", 32))
+	require.NoError(t, err)
+	tampered = signed
+	tampered.PublicKey = other.PublicKey()
+	require.ErrorIs(t, VerifyCheckpoint(tampered), ErrInvalidSignature)
+}
+
+func TestNewSignerInvalidKey(t *testing.T) {
+	_, err := NewSigner("not hex")
+	require.Error(t, err)
+
+	_, err = NewSigner("abcd")
+	require.Error(t, err)
+}
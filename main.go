@@ -1,13 +1,28 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	_ "github.com/lib/pq"
 	"github.com/techschool/simplebank/api"
+	"github.com/techschool/simplebank/batch"
+	"github.com/techschool/simplebank/blob"
 	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/events"
+	"github.com/techschool/simplebank/hub"
+	"github.com/techschool/simplebank/jobs"
+	"github.com/techschool/simplebank/metrics"
+	"github.com/techschool/simplebank/scheduler"
+	"github.com/techschool/simplebank/statement"
+	"github.com/techschool/simplebank/stream"
+	"github.com/techschool/simplebank/tracing"
 	"github.com/techschool/simplebank/util"
+	"github.com/techschool/simplebank/webhooks"
 )
 
 func main() {
@@ -16,22 +31,181 @@ func main() {
 
 	// Handling a load config error.
 	if err != nil {
-		log.Fatal("cannot load config", err)
+		slog.Error("cannot load config", slog.Any("error", err))
+		os.Exit(1)
 	}
 
-	// Creating a sql connection using the config settings.
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	// Every package logs through the default logger, so set it up before anything else.
+	slog.SetDefault(util.NewLogger(config))
+
+	// Traces go to the OTLP collector, or stdout when no endpoint is configured.
+	shutdownTracing, err := tracing.Setup(context.Background(), config)
+	if err != nil {
+		slog.Error("cannot set up tracing", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// ctx is cancelled on SIGINT or SIGTERM, which stops the startup retries
+	// or starts the graceful shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Creating a sql connection pool using the config settings. This waits for
+	// the database to come up, so the server survives Postgres starting slowly.
+	conn, err := db.Connect(ctx, config)
+	if err != nil {
+		slog.Error("cannot connect to db", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Export the connection pool stats on /metrics.
+	if err := metrics.RegisterDBStats(conn, "simple_bank"); err != nil {
+		slog.Error("cannot register db metrics", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Create a new connection and store. The metrics decorator wraps the SQL store
+	// so the instrumentation stays out of SQLStore itself.
+	sqlStore := db.NewStore(conn,
+		db.WithRollbackHook(metrics.RollbackHook),
+		db.WithDBTXWrapper(tracing.WrapDBTX),
+	)
+	store := metrics.NewStore(tracing.NewStore(sqlStore))
+
+	// Listens for new entries on its own connection, for the account streams.
+	entryListener, err := stream.NewListener(config.DBSource)
 	if err != nil {
-		log.Fatal("cannot connect to db", err)
+		slog.Error("cannot listen for entries", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Pushes events to WebSocket clients. The listener tells it about every event
+	// committed by any instance.
+	notificationHub := hub.New(store)
+	if err := entryListener.ListenForEvents(notificationHub.Notify); err != nil {
+		slog.Error("cannot listen for events", slog.Any("error", err))
+		os.Exit(1)
 	}
 
-	// Create a new connection and store.
-	store := db.NewStore(conn)
-	server := api.NewServer(store)
+	// Generated statements are kept as files, outside the database.
+	blobs, err := blob.NewFileStore(config.BlobStorageDir)
+	if err != nil {
+		slog.Error("cannot open blob storage", slog.Any("error", err))
+		os.Exit(1)
+	}
 
-	// Error handling.
-	err = server.Start(config.ServerAddress)
+	server, err := api.NewServer(config, store,
+		api.WithEntryStream(entryListener),
+		api.WithNotificationHub(notificationHub),
+		api.WithStatementStorage(blobs),
+	)
 	if err != nil {
-		log.Fatal("cannot start server", err)
+		slog.Error("cannot create server", slog.Any("error", err))
+		os.Exit(1)
 	}
+
+	// Background workers run until ctx is cancelled.
+	var background sync.WaitGroup
+	background.Go(func() { entryListener.Run(ctx) })
+	background.Go(func() { notificationHub.Run(ctx) })
+
+	// Runs scheduled transfers.
+	worker := scheduler.NewWorker(store, scheduler.Config{
+		PollInterval: config.SchedulerPollInterval,
+		MaxFailures:  config.ScheduledTransferMaxFailures,
+		RetryDelay:   config.ScheduledTransferRetryDelay,
+	})
+	background.Go(func() { worker.Run(ctx) })
+
+	// Adds last month's statements once the month is over. Jobs generate them.
+	statementWorker := statement.NewMonthlyWorker(store, config.StatementPollInterval)
+	background.Go(func() { statementWorker.Run(ctx) })
+
+	// Every kind of background job needs its handler registered here.
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, jobs.VerifyEntryChain, jobs.VerifyEntryChainHandler(store))
+	jobs.Register(registry, webhooks.Deliver, webhooks.DeliverHandler(
+		store, webhooks.NewHTTPClient(config.WebhookTimeout), config.WebhookMaxAttempts,
+	))
+	jobs.Register(registry, statement.Generate, statement.GenerateHandler(store, blobs))
+	jobs.Register(registry, batch.Execute, batch.ExecuteHandler(store))
+
+	jobWorker := jobs.NewWorker(store, registry, jobs.Config{
+		Concurrency:       config.JobsConcurrency,
+		PollInterval:      config.JobsPollInterval,
+		VisibilityTimeout: config.JobsVisibilityTimeout,
+		RetryBackoff:      config.JobsRetryBackoff,
+		MaxRetryBackoff:   config.JobsMaxRetryBackoff,
+	})
+	background.Go(func() { jobWorker.Run(ctx) })
+
+	// Publishes the domain events transactions write to the outbox, and turns them
+	// into webhook deliveries for the users who subscribed.
+	broker, err := events.NewPublisherFromConfig(config)
+	if err != nil {
+		slog.Error("cannot create event publisher", slog.Any("error", err))
+		os.Exit(1)
+	}
+	publisher := events.NewFanoutPublisher(broker, webhooks.NewDispatcher(store, config.WebhookMaxAttempts))
+	relay := events.NewRelay(store, publisher, events.RelayConfig{
+		PollInterval: config.OutboxPollInterval,
+		BatchSize:    config.OutboxBatchSize,
+	})
+	background.Go(func() { relay.Run(ctx) })
+
+	// The server runs in its own goroutine so main can wait for either a signal or a startup error.
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("starting server", slog.String("address", config.ServerAddress))
+		serverErr <- server.Start(config.ServerAddress)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			slog.Error("cannot start server", slog.Any("error", err))
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down, draining in-flight requests", slog.Duration("timeout", config.ShutdownTimeout))
+	}
+
+	// Give in-flight transfers until the deadline to finish before the server is closed.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("server shutdown did not complete", slog.Any("error", err))
+	}
+
+	// Cancelling ctx stops the workers, but a run they are in the middle of still commits.
+	stop()
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
+	select {
+	case <-backgroundDone:
+	case <-shutdownCtx.Done():
+		slog.Error("background workers did not stop in time")
+	}
+
+	if err := publisher.Close(); err != nil {
+		slog.Error("cannot close event publisher", slog.Any("error", err))
+	}
+	if err := entryListener.Close(); err != nil {
+		slog.Error("cannot close entry listener", slog.Any("error", err))
+	}
+
+	// Only close the pool once no handler or worker can be using it.
+	if err := conn.Close(); err != nil {
+		slog.Error("cannot close db", slog.Any("error", err))
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("cannot flush traces", slog.Any("error", err))
+	}
+
+	slog.Info("server stopped")
 }
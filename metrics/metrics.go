@@ -0,0 +1,75 @@
+// Package metrics exposes Prometheus collectors for the HTTP API and the db store.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric name, e.g. simplebank_http_requests_total.
+const Namespace = "simplebank"
+
+// Registry holds every simplebank collector. A dedicated registry is used instead of
+// the global one so only what we register here shows up on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	transferTxTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "transfer_tx_total",
+		Help:      "Number of TransferTx calls by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	transferTxDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "transfer_tx_duration_seconds",
+		Help:      "TransferTx latency by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	txRollbacksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "db_tx_rollbacks_total",
+		Help:      "Number of database transactions rolled back by execTx, by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		transferTxTotal,
+		transferTxDuration,
+		txRollbacksTotal,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterDBStats exports the sql.DB connection pool stats (open, in use, idle,
+// wait count, ...) as gauges. It should be called once per connection pool.
+func RegisterDBStats(conn *sql.DB, dbName string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(conn, dbName))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Store decorates a db.Store with Prometheus instrumentation. Every method not
+// overridden here goes straight to the wrapped store.
+type Store struct {
+	db.Store
+}
+
+// NewStore wraps store so TransferTx calls are counted and timed.
+func NewStore(store db.Store) db.Store {
+	return &Store{Store: store}
+}
+
+// TransferTx records the call count, latency and failure reason of the wrapped TransferTx.
+func (store *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	start := time.Now()
+	result, err := store.Store.TransferTx(ctx, arg)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	transferTxTotal.WithLabelValues(outcome, FailureReason(err)).Inc()
+	transferTxDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+
+	return result, err
+}
+
+// RollbackHook counts execTx rollbacks. Pass it to db.NewStore with db.WithRollbackHook.
+func RollbackHook(ctx context.Context, err error) {
+	txRollbacksTotal.WithLabelValues(FailureReason(err)).Inc()
+}
+
+// FailureReason maps an error to a small, fixed set of label values.
+// Postgres errors use their condition name, e.g. foreign_key_violation.
+func FailureReason(err error) string {
+	if err == nil {
+		return "none"
+	}
+
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, sql.ErrNoRows):
+		return "not_found"
+	case errors.Is(err, db.ErrAccountFrozen):
+		return "account_frozen"
+	case errors.As(err, &pqErr):
+		return pqErr.Code.Name()
+	}
+	return "unknown"
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureReason(t *testing.T) {
+	require.Equal(t, "none", FailureReason(nil))
+	require.Equal(t, "canceled", FailureReason(context.Canceled))
+	require.Equal(t, "timeout", FailureReason(fmt.Errorf("tx err: %w", context.DeadlineExceeded)))
+	require.Equal(t, "not_found", FailureReason(sql.ErrNoRows))
+	// Postgres errors are labelled by their condition name.
+	require.Equal(t, "foreign_key_violation", FailureReason(&pq.Error{Code: "23503"}))
+	require.Equal(t, "unknown", FailureReason(errors.New("boom")))
+}
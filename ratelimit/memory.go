@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How often the in-memory backend drops buckets that have refilled completely.
+const memorySweepInterval = time.Minute
+
+// MemoryBackend keeps buckets in a map. Limits are per instance, so it suits a
+// single server or tests.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewMemoryBackend creates an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		tats: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Allow takes a token for key if one is available.
+func (backend *MemoryBackend) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+
+	now := backend.now()
+	backend.sweep(now)
+
+	tat := backend.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	if tat.Sub(now) > limit.burstTolerance() {
+		return deniedResult(limit, now, tat), nil
+	}
+
+	tat = tat.Add(limit.emissionInterval())
+	backend.tats[key] = tat
+	return allowedResult(limit, now, tat), nil
+}
+
+// sweep drops full buckets so the map doesn't grow with every client ever seen.
+// A missing key behaves exactly like a full bucket. Must be called with mu held.
+func (backend *MemoryBackend) sweep(now time.Time) {
+	if now.Sub(backend.lastSweep) < memorySweepInterval {
+		return
+	}
+	backend.lastSweep = now
+
+	for key, tat := range backend.tats {
+		if tat.Before(now) {
+			delete(backend.tats, key)
+		}
+	}
+}
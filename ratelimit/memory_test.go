@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	now := time.Now()
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+
+	// 3 requests a second with a bucket of 3.
+	limit := Limit{Rate: 3, Burst: 3}
+
+	// The full bucket lets the burst through.
+	for i := 2; i >= 0; i-- {
+		result, err := backend.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	// The next one is refused until a token has refilled.
+	result, err := backend.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, time.Second/3, result.RetryAfter, float64(time.Millisecond))
+	require.InDelta(t, time.Second, result.ResetAfter, float64(time.Millisecond))
+
+	// Other keys have their own bucket.
+	result, err = backend.Allow(context.Background(), "other", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// After one emission interval there is one token again.
+	now = now.Add(time.Second / 3)
+	result, err = backend.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10/m")
+	require.NoError(t, err)
+	require.Equal(t, 10, limit.Burst)
+	require.InDelta(t, 10.0/60, limit.Rate, 1e-9)
+
+	for _, bad := range []string{"", "10", "0/s", "x/s", "10/d"} {
+		_, err := ParseLimit(bad)
+		require.Error(t, err, bad)
+	}
+
+	routes, err := ParseRouteLimits("POST /transfers=20/m, GET  /accounts/:id=5/s")
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	require.Equal(t, 20, routes["POST /transfers"].Burst)
+	require.Equal(t, 5, routes["GET /accounts/:id"].Burst)
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// How often the Postgres backend deletes buckets that have refilled completely.
+const postgresCleanupInterval = time.Minute
+
+// PostgresBackend keeps buckets in the rate_limit_buckets table so every instance
+// behind a load balancer shares the same limits. Timestamps come from the database
+// clock, so instances with skewed clocks still agree.
+type PostgresBackend struct {
+	store db.Store
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewPostgresBackend creates a backend on top of the store.
+func NewPostgresBackend(store db.Store) *PostgresBackend {
+	return &PostgresBackend{store: store}
+}
+
+// Allow takes a token for key if one is available.
+func (backend *PostgresBackend) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	backend.cleanup(ctx)
+
+	bucket, err := backend.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:              key,
+		EmissionInterval: limit.emissionInterval().Seconds(),
+		BurstTolerance:   limit.burstTolerance().Seconds(),
+	})
+	if err == nil {
+		return allowedResult(limit, bucket.DbNow, bucket.Tat), nil
+	}
+	if err != sql.ErrNoRows {
+		return Result{}, err
+	}
+
+	// No row means the bucket was empty. Read it back to work out Retry-After.
+	current, err := backend.store.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	return deniedResult(limit, current.DbNow, current.Tat), nil
+}
+
+// cleanup deletes full buckets at most once per interval. It is best effort: a
+// failure is logged and the request carries on.
+func (backend *PostgresBackend) cleanup(ctx context.Context) {
+	backend.mu.Lock()
+	due := time.Since(backend.lastCleanup) >= postgresCleanupInterval
+	if due {
+		backend.lastCleanup = time.Now()
+	}
+	backend.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	if err := backend.store.DeleteExpiredRateLimitBuckets(ctx); err != nil {
+		util.LoggerFromContext(ctx).WarnContext(ctx, "cannot delete expired rate limit buckets", slog.Any("error", err))
+	}
+}
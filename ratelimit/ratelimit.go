@@ -0,0 +1,124 @@
+// Package ratelimit implements token bucket rate limiting using GCRA (the generic
+// cell rate algorithm), which needs a single timestamp per key and so maps cleanly
+// onto both an in-memory map and a Postgres row.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that refills at Rate tokens per second and holds at most Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token, with what the RateLimit-* headers need.
+type Result struct {
+	Allowed bool
+	// Size of the bucket.
+	Limit int
+	// Tokens left after this request.
+	Remaining int
+	// How long until the next request would be allowed. Zero when Allowed.
+	RetryAfter time.Duration
+	// How long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Backend stores the buckets. The in-memory backend is enough for a single
+// instance, the Postgres backend shares limits between instances.
+type Backend interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit reads limits written as "<count>/<unit>", e.g. "10/m" for ten requests a
+// minute. The unit is s, m or h and the bucket holds count tokens.
+func ParseLimit(s string) (Limit, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(s), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <count>/<unit>", s)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: count must be a positive number", s)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: n}, nil
+}
+
+// ParseRouteLimits reads a comma separated list of "<METHOD> <path>=<limit>" pairs,
+// e.g. "POST /transfers=20/m,POST /accounts=10/m". Paths are gin route templates.
+func ParseRouteLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	if strings.TrimSpace(s) == "" {
+		return limits, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		route, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected <METHOD> <path>=<limit>", pair)
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.Join(strings.Fields(route), " ")] = limit
+	}
+	return limits, nil
+}
+
+// emissionInterval is the time it takes to refill one token.
+func (l Limit) emissionInterval() time.Duration {
+	return time.Duration(float64(time.Second) / l.Rate)
+}
+
+// burstTolerance is how far ahead of now the theoretical arrival time may run
+// before requests are refused. It is what lets Burst requests through at once.
+func (l Limit) burstTolerance() time.Duration {
+	return l.emissionInterval() * time.Duration(l.Burst-1)
+}
+
+// allowedResult builds the result after a token was taken and the bucket's
+// theoretical arrival time moved to tat.
+func allowedResult(limit Limit, now time.Time, tat time.Time) Result {
+	interval := limit.emissionInterval()
+	remaining := int((now.Add(limit.burstTolerance()).Add(interval).Sub(tat)) / interval)
+
+	return Result{
+		Allowed:    true,
+		Limit:      limit.Burst,
+		Remaining:  max(0, min(remaining, limit.Burst)),
+		ResetAfter: max(0, tat.Sub(now)),
+	}
+}
+
+// deniedResult builds the result when the bucket was empty.
+func deniedResult(limit Limit, now time.Time, tat time.Time) Result {
+	return Result{
+		Allowed:    false,
+		Limit:      limit.Burst,
+		Remaining:  0,
+		RetryAfter: max(0, tat.Add(-limit.burstTolerance()).Sub(now)),
+		ResetAfter: max(0, tat.Sub(now)),
+	}
+}
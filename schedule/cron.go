@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a standard 5 field cron expression: minute, hour, day of month,
+// month and day of week (0 or 7 is Sunday). Fields take *, lists, ranges and steps.
+type cronSchedule struct {
+	start  time.Time
+	minute set
+	hour   set
+	dom    set
+	month  set
+	dow    set
+	anyDOM bool
+	anyDOW bool
+}
+
+// cronField is the range of values one field can hold.
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+func parseCron(spec string, start time.Time) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression needs %d fields, got %d", len(cronFields), len(fields))
+	}
+
+	var sets [5]set
+	for i, field := range fields {
+		s, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = s
+	}
+
+	// 7 is another way to write Sunday.
+	dow := sets[4]
+	if dow.has(7) {
+		dow.add(0)
+	}
+
+	return &cronSchedule{
+		start:  start,
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    dow,
+		anyDOM: fields[2] == "*",
+		anyDOW: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses one comma separated field, e.g. "1-5", "*/15" or "0,30".
+func parseCronField(field string, spec cronField) (set, error) {
+	var s set
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, spec.name)
+			}
+			step = n
+		}
+
+		low, high := spec.min, spec.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", lowPart, spec.name)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", highPart, spec.name)
+				}
+			} else if hasStep {
+				// "5/15" means every 15 starting at 5.
+				high = spec.max
+			}
+		}
+		if low < spec.min || high > spec.max || low > high {
+			return 0, fmt.Errorf("%s field must be between %d and %d", spec.name, spec.min, spec.max)
+		}
+
+		for v := low; v <= high; v += step {
+			s.add(v)
+		}
+	}
+	return s, nil
+}
+
+// dayMatches follows the usual cron rule: when both the day of month and the day of
+// week are restricted, a day matching either one runs.
+func (c *cronSchedule) dayMatches(day time.Time) bool {
+	if !c.month.has(int(day.Month())) {
+		return false
+	}
+	domMatch := c.dom.has(day.Day())
+	dowMatch := c.dow.has(int(day.Weekday()))
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dowMatch
+	case c.anyDOW:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	return nextOnMatchingDay(after, c.start, c.hour, c.minute, c.dayMatches)
+}
@@ -0,0 +1,176 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rruleSchedule is the part of RFC 5545 recurrence rules standing orders need: FREQ,
+// INTERVAL, BYMONTH, BYMONTHDAY (negative counts back from the end of the month),
+// BYDAY (weekdays only, without ordinals), BYHOUR and BYMINUTE. COUNT and UNTIL aren't
+// supported; the end of a schedule is stored next to it instead.
+type rruleSchedule struct {
+	start    time.Time
+	freq     string
+	interval int
+	months   set
+	monthDay []int
+	weekdays set
+	hours    set
+	minutes  set
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRRule(spec string, start time.Time) (*rruleSchedule, error) {
+	spec = strings.TrimPrefix(strings.ToUpper(spec), "RRULE:")
+
+	r := &rruleSchedule{start: start, interval: 1}
+	for _, part := range strings.Split(spec, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported rrule FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 {
+				return nil, fmt.Errorf("invalid rrule INTERVAL %q", value)
+			}
+		case "BYMONTH":
+			r.months, err = parseRRuleInts(key, value, 1, 12)
+		case "BYHOUR":
+			r.hours, err = parseRRuleInts(key, value, 0, 23)
+		case "BYMINUTE":
+			r.minutes, err = parseRRuleInts(key, value, 0, 59)
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, convErr := strconv.Atoi(v)
+				if convErr != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("invalid rrule BYMONTHDAY %q", v)
+				}
+				r.monthDay = append(r.monthDay, day)
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[v]
+				if !ok {
+					return nil, fmt.Errorf("unsupported rrule BYDAY %q", v)
+				}
+				r.weekdays.add(int(weekday))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.freq == "" {
+		return nil, fmt.Errorf("rrule needs a FREQ")
+	}
+
+	// Anything the rule leaves open is taken from the start, like RFC 5545 does with DTSTART.
+	if r.hours == 0 {
+		r.hours.add(start.Hour())
+	}
+	if r.minutes == 0 {
+		r.minutes.add(start.Minute())
+	}
+	switch r.freq {
+	case "WEEKLY":
+		if r.weekdays == 0 {
+			r.weekdays.add(int(start.Weekday()))
+		}
+	case "MONTHLY":
+		if r.monthDay == nil && r.weekdays == 0 {
+			r.monthDay = []int{start.Day()}
+		}
+	case "YEARLY":
+		if r.months == 0 {
+			r.months.add(int(start.Month()))
+		}
+		if r.monthDay == nil && r.weekdays == 0 {
+			r.monthDay = []int{start.Day()}
+		}
+	}
+	return r, nil
+}
+
+func parseRRuleInts(key string, value string, min int, max int) (set, error) {
+	var s set
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid rrule %s %q", key, v)
+		}
+		s.add(n)
+	}
+	return s, nil
+}
+
+// dayMatches checks the day is in a period the INTERVAL allows, then the BY parts.
+func (r *rruleSchedule) dayMatches(day time.Time) bool {
+	if r.period(day)%r.interval != 0 {
+		return false
+	}
+	if r.months != 0 && !r.months.has(int(day.Month())) {
+		return false
+	}
+	if r.weekdays != 0 && !r.weekdays.has(int(day.Weekday())) {
+		return false
+	}
+	if r.monthDay != nil {
+		last := daysIn(day)
+		for _, d := range r.monthDay {
+			if d == day.Day() || (d < 0 && last+d+1 == day.Day()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// period is how many days, weeks (starting Monday), months or years day is after the start.
+func (r *rruleSchedule) period(day time.Time) int {
+	startDay := time.Date(r.start.Year(), r.start.Month(), r.start.Day(), 0, 0, 0, 0, time.UTC)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch r.freq {
+	case "DAILY":
+		return int(day.Sub(startDay).Hours() / 24)
+	case "WEEKLY":
+		monday := func(t time.Time) time.Time {
+			return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		}
+		return int(monday(day).Sub(monday(startDay)).Hours() / (24 * 7))
+	case "MONTHLY":
+		return (day.Year()-startDay.Year())*12 + int(day.Month()) - int(startDay.Month())
+	default:
+		return day.Year() - startDay.Year()
+	}
+}
+
+func (r *rruleSchedule) Next(after time.Time) time.Time {
+	return nextOnMatchingDay(after, r.start, r.hours, r.minutes, r.dayMatches)
+}
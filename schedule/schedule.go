@@ -0,0 +1,99 @@
+// Package schedule works out when recurring jobs, like standing orders, run next. It
+// understands 5 field cron expressions and a subset of iCalendar RRULEs (RFC 5545).
+// All times are in UTC.
+package schedule
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// How far ahead Next looks before deciding a schedule has no more runs.
+const maxSearchDays = 10 * 366
+
+// ErrNeverRuns is returned by Parse for a valid schedule that has no run times, like
+// a cron expression for the 31st of February.
+var ErrNeverRuns = errors.New("schedule never runs")
+
+// Schedule gives the run times of a recurring job.
+type Schedule interface {
+	// Next returns the first run time after the given time, or the zero time if there
+	// isn't one in the next ten years.
+	Next(after time.Time) time.Time
+}
+
+// Parse reads a cron expression like "0 9 1 * *" or an RRULE like
+// "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=9" (the RRULE: prefix is optional). start is when
+// the schedule begins: no run is before it, and an RRULE takes its defaults and the
+// phase of its INTERVAL from it.
+func Parse(spec string, start time.Time) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	start = start.UTC().Truncate(time.Minute)
+
+	var sched Schedule
+	var err error
+	if strings.HasPrefix(strings.ToUpper(spec), "RRULE:") || strings.Contains(strings.ToUpper(spec), "FREQ=") {
+		sched, err = parseRRule(spec, start)
+	} else {
+		sched, err = parseCron(spec, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sched.Next(start.Add(-time.Minute)).IsZero() {
+		return nil, ErrNeverRuns
+	}
+	return sched, nil
+}
+
+// set is a bitset of the allowed values of one field, e.g. the hours a job runs at.
+type set uint64
+
+func (s set) has(v int) bool {
+	return v >= 0 && v < 64 && s&(1<<uint(v)) != 0
+}
+
+func (s *set) add(v int) {
+	*s |= 1 << uint(v)
+}
+
+// dayMatcher reports whether a job runs at all on the given day.
+type dayMatcher func(day time.Time) bool
+
+// nextOnMatchingDay walks forward a day at a time from after, returning the first
+// time on a matching day whose hour and minute are in the sets, that is after after
+// and not before start.
+func nextOnMatchingDay(after time.Time, start time.Time, hours set, minutes set, matches dayMatcher) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	if t.Before(start) {
+		t = start
+	}
+
+	for i := 0; i < maxSearchDays; i++ {
+		if matches(t) {
+			for h := t.Hour(); h < 24; h++ {
+				if !hours.has(h) {
+					continue
+				}
+				m := 0
+				if h == t.Hour() {
+					m = t.Minute()
+				}
+				for ; m < 60; m++ {
+					if minutes.has(m) {
+						return time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, time.UTC)
+					}
+				}
+			}
+		}
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
+}
+
+// daysIn returns the number of days in the month of t.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
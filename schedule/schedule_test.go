@@ -0,0 +1,137 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int, hour int, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+// runs returns the first n run times after start.
+func runs(t *testing.T, spec string, start time.Time, n int) []time.Time {
+	sched, err := Parse(spec, start)
+	require.NoError(t, err)
+
+	var times []time.Time
+	after := start.Add(-time.Minute)
+	for i := 0; i < n; i++ {
+		after = sched.Next(after)
+		require.False(t, after.IsZero())
+		times = append(times, after)
+	}
+	return times
+}
+
+func TestCron(t *testing.T) {
+	start := date(2024, time.January, 15, 10, 30)
+
+	// 9:00 on the first of every month.
+	require.Equal(t, []time.Time{
+		date(2024, time.February, 1, 9, 0),
+		date(2024, time.March, 1, 9, 0),
+		date(2024, time.April, 1, 9, 0),
+	}, runs(t, "0 9 1 * *", start, 3))
+
+	// Every 15 minutes.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 15, 10, 30),
+		date(2024, time.January, 15, 10, 45),
+		date(2024, time.January, 15, 11, 0),
+	}, runs(t, "*/15 * * * *", start, 3))
+
+	// Weekdays at 8:00. The 15th of January 2024 was a Monday.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 16, 8, 0),
+		date(2024, time.January, 17, 8, 0),
+		date(2024, time.January, 18, 8, 0),
+		date(2024, time.January, 19, 8, 0),
+		date(2024, time.January, 22, 8, 0),
+	}, runs(t, "0 8 * * 1-5", start, 5))
+
+	// With both day fields set, either one matches.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 20, 0, 0),
+		date(2024, time.January, 25, 0, 0),
+		date(2024, time.January, 27, 0, 0),
+	}, runs(t, "0 0 25 * 6", start, 3))
+
+	// Sunday can be written as 7.
+	require.Equal(t, []time.Time{date(2024, time.January, 21, 12, 0)}, runs(t, "0 12 * * 7", start, 1))
+}
+
+func TestRRule(t *testing.T) {
+	start := date(2024, time.January, 31, 9, 0)
+
+	// Monthly on the start day skips months that don't have it, like RFC 5545 says.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 31, 9, 0),
+		date(2024, time.March, 31, 9, 0),
+		date(2024, time.May, 31, 9, 0),
+	}, runs(t, "FREQ=MONTHLY", start, 3))
+
+	// The last day of every month instead.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 31, 9, 0),
+		date(2024, time.February, 29, 9, 0),
+		date(2024, time.March, 31, 9, 0),
+	}, runs(t, "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", start, 3))
+
+	// Every other week on Monday and Friday at 17:30. The 31st of January 2024 was a Wednesday.
+	require.Equal(t, []time.Time{
+		date(2024, time.February, 2, 17, 30),
+		date(2024, time.February, 12, 17, 30),
+		date(2024, time.February, 16, 17, 30),
+	}, runs(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;BYHOUR=17;BYMINUTE=30", start, 3))
+
+	// Yearly on the start date.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 31, 9, 0),
+		date(2025, time.January, 31, 9, 0),
+	}, runs(t, "FREQ=YEARLY", start, 2))
+
+	// Every three days.
+	require.Equal(t, []time.Time{
+		date(2024, time.January, 31, 9, 0),
+		date(2024, time.February, 3, 9, 0),
+	}, runs(t, "FREQ=DAILY;INTERVAL=3", start, 2))
+}
+
+func TestNextAfter(t *testing.T) {
+	start := date(2024, time.January, 1, 0, 0)
+	sched, err := Parse("0 9 1 * *", start)
+	require.NoError(t, err)
+
+	// Missed runs are skipped rather than caught up.
+	require.Equal(t, date(2024, time.June, 1, 9, 0), sched.Next(date(2024, time.May, 20, 0, 0)))
+	// Next is strictly after.
+	require.Equal(t, date(2024, time.July, 1, 9, 0), sched.Next(date(2024, time.June, 1, 9, 0)))
+}
+
+func TestParseInvalid(t *testing.T) {
+	start := date(2024, time.January, 1, 0, 0)
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"FREQ=HOURLY",
+		"FREQ=MONTHLY;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"INTERVAL=2",
+	} {
+		_, err := Parse(spec, start)
+		require.Error(t, err, spec)
+	}
+
+	_, err := Parse("0 0 31 2 *", start)
+	require.ErrorIs(t, err, ErrNeverRuns)
+}
@@ -0,0 +1,83 @@
+// Package scheduler runs scheduled transfers when they fall due.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/util"
+)
+
+// Config controls how often the worker polls and what it does when a run fails.
+type Config struct {
+	PollInterval time.Duration
+	// Failed runs in a row before a scheduled transfer is paused.
+	MaxFailures int32
+	// How long to wait before trying a failed run again.
+	RetryDelay time.Duration
+}
+
+// Worker polls for due scheduled transfers and runs them. Several workers, in one
+// process or many, can run at once: each due row is claimed with SKIP LOCKED.
+type Worker struct {
+	store  db.Store
+	config Config
+}
+
+// NewWorker creates a worker on top of the store.
+func NewWorker(store db.Store, config Config) *Worker {
+	return &Worker{store: store, config: config}
+}
+
+// Run polls until ctx is cancelled. A run that has started is allowed to commit.
+func (worker *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		worker.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs scheduled transfers until none are due, and returns how many it ran.
+// Errors are logged, and the rows involved are picked up again on the next poll.
+func (worker *Worker) RunDue(ctx context.Context) int {
+	logger := util.LoggerFromContext(ctx)
+	count := 0
+
+	for ctx.Err() == nil {
+		// The run finishes even if ctx is cancelled half way, so a transfer is never cut off.
+		result, err := worker.store.RunDueScheduledTransferTx(context.WithoutCancel(ctx), db.RunDueScheduledTransferTxParams{
+			Now:         time.Now(),
+			MaxFailures: worker.config.MaxFailures,
+			RetryDelay:  worker.config.RetryDelay,
+		})
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "cannot run scheduled transfer", slog.Any("error", err))
+			break
+		}
+		count++
+
+		logger.InfoContext(ctx, "ran scheduled transfer",
+			slog.Int64("scheduled_transfer_id", result.ScheduledTransfer.ID),
+			slog.String("outcome", result.Run.Status),
+			slog.String("error", result.Run.Error),
+			slog.String("status", result.ScheduledTransfer.Status),
+			slog.Time("next_run_at", result.ScheduledTransfer.NextRunAt),
+		)
+	}
+
+	return count
+}
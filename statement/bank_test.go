@@ -0,0 +1,101 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares out with the file in testdata. Run the tests with -update to
+// write the files after changing a format on purpose.
+func checkGolden(t *testing.T, name string, out []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, out, 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(out))
+}
+
+// bankStatement has a transfer each way, and names the bank formats have to escape
+// or replace.
+func bankStatement() *fakeStore {
+	store := testStatement(3)
+	store.summary.Account.Owner = "Zoë & <Co>"
+
+	received := db.ListStatementEntriesRow{
+		ID:                    4,
+		Amount:                40,
+		CreatedAt:             periodStart.Add(30 * time.Hour),
+		Kind:                  "transfer",
+		ReferenceID:           6,
+		CounterpartyAccountID: 13,
+		CounterpartyOwner:     "carol_o'neil",
+	}
+	store.summary.ClosingBalance += received.Amount
+	store.summary.TotalCredits += received.Amount
+	store.summary.EntryCount++
+	store.lines = append(store.lines, db.StatementLine{ListStatementEntriesRow: received, Balance: store.summary.ClosingBalance})
+	return store
+}
+
+func TestCAMT053(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewCAMT053Writer(&buf)
+	writer.now = func() time.Time { return time.Date(2024, 2, 1, 6, 0, 0, 0, time.UTC) }
+	err := Write(context.Background(), bankStatement(), Params{AccountID: 7, PeriodStart: periodStart, PeriodEnd: periodEnd}, writer)
+	require.NoError(t, err)
+
+	checkGolden(t, "statement.camt053.xml", buf.Bytes())
+
+	// The file is well formed XML.
+	decoder := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestMT940(t *testing.T) {
+	out := writeStatement(t, FormatMT940, bankStatement())
+	checkGolden(t, "statement.mt940.sta", out)
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), 4+mt940InformationLine, line)
+		require.Equal(t, line, swiftText(line))
+	}
+}
+
+func TestMT940Information(t *testing.T) {
+	text := strings.Repeat("a", mt940InformationLine) + "-b" + strings.Repeat("c", 10*mt940InformationLine)
+	lines := strings.Split(mt940Information(text), "\r\n")
+	require.Len(t, lines, mt940InformationLines)
+	require.Equal(t, " -b", lines[1][:3])
+	for _, line := range lines {
+		require.LessOrEqual(t, len(line), mt940InformationLine)
+	}
+
+	// No character is lost to the space, it is carried into the next line instead.
+	text = strings.Repeat("a", mt940InformationLine) + ":" + strings.Repeat("b", mt940InformationLine-1) + "c"
+	require.Equal(t, strings.Repeat("a", mt940InformationLine)+"\r\n :"+strings.Repeat("b", mt940InformationLine-2)+"\r\nbc",
+		mt940Information(text))
+
+	require.Equal(t, "Transfer from caf  (bob smith)", mt940Information("Transfer from café (bob_smith)"))
+}
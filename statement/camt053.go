@@ -0,0 +1,287 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// camt.053.001.02 is the version ERP systems most often import, so it is the one written.
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// CAMT053Writer writes a statement as an ISO 20022 camt.053 bank to customer statement.
+// camt.053 puts both balances and the totals before the entries, which works because
+// the summary is known before the first entry is read. Amounts are whole units of the
+// account's currency, like everywhere else in the bank.
+type CAMT053Writer struct {
+	w       io.Writer
+	encoder *xml.Encoder
+	summary db.StatementSummary
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+func NewCAMT053Writer(w io.Writer) *CAMT053Writer {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return &CAMT053Writer{w: w, encoder: encoder, now: time.Now}
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtAccount struct {
+	ID string `xml:"Id>Othr>Id"`
+}
+
+type camtGroupHeader struct {
+	MessageID string `xml:"MsgId"`
+	CreatedAt string `xml:"CreDtTm"`
+}
+
+type camtStatementAccount struct {
+	camtAccount
+	Currency string `xml:"Ccy"`
+	Owner    string `xml:"Ownr>Nm"`
+}
+
+type camtPeriod struct {
+	From string `xml:"FrDtTm"`
+	To   string `xml:"ToDtTm"`
+}
+
+type camtBalance struct {
+	Type          string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount        camtAmount `xml:"Amt"`
+	CreditOrDebit string     `xml:"CdtDbtInd"`
+	Date          string     `xml:"Dt>Dt"`
+}
+
+type camtTotals struct {
+	Count          int64  `xml:"TtlNtries>NbOfNtries"`
+	Sum            string `xml:"TtlNtries>Sum"`
+	Net            string `xml:"TtlNtries>TtlNetNtryAmt"`
+	NetCreditDebit string `xml:"TtlNtries>CdtDbtInd"`
+	Credits        string `xml:"TtlCdtNtries>Sum"`
+	Debits         string `xml:"TtlDbtNtries>Sum"`
+}
+
+type camtTransactionCode struct {
+	Domain    string `xml:"Domn>Cd"`
+	Family    string `xml:"Domn>Fmly>Cd"`
+	SubFamily string `xml:"Domn>Fmly>SubFmlyCd"`
+}
+
+type camtParty struct {
+	Name string `xml:"Nm,omitempty"`
+}
+
+type camtParties struct {
+	Debtor          *camtParty   `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camtAccount `xml:"DbtrAcct,omitempty"`
+	Creditor        *camtParty   `xml:"Cdtr,omitempty"`
+	CreditorAccount *camtAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type camtTransaction struct {
+	Reference  string       `xml:"Refs>AcctSvcrRef,omitempty"`
+	Parties    *camtParties `xml:"RltdPties,omitempty"`
+	Remittance string       `xml:"RmtInf>Ustrd"`
+}
+
+type camtEntry struct {
+	XMLName         xml.Name            `xml:"Ntry"`
+	Reference       string              `xml:"NtryRef"`
+	Amount          camtAmount          `xml:"Amt"`
+	CreditOrDebit   string              `xml:"CdtDbtInd"`
+	Status          string              `xml:"Sts"`
+	BookingDate     string              `xml:"BookgDt>DtTm"`
+	ValueDate       string              `xml:"ValDt>Dt"`
+	ServicerRef     string              `xml:"AcctSvcrRef"`
+	TransactionCode camtTransactionCode `xml:"BkTxCd"`
+	Transaction     camtTransaction     `xml:"NtryDtls>TxDtls"`
+	Information     string              `xml:"AddtlNtryInf"`
+}
+
+func (writer *CAMT053Writer) Begin(summary db.StatementSummary) error {
+	writer.summary = summary
+	if _, err := io.WriteString(writer.w, xml.Header); err != nil {
+		return err
+	}
+
+	id := statementID(summary)
+	createdAt := writer.now().UTC().Format(time.RFC3339)
+	currency := summary.Account.Currency
+
+	err := writer.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace})
+	if err == nil {
+		err = writer.start("BkToCstmrStmt")
+	}
+	if err == nil {
+		err = writer.encoder.EncodeElement(camtGroupHeader{MessageID: id, CreatedAt: createdAt}, element("GrpHdr"))
+	}
+	if err == nil {
+		err = writer.start("Stmt")
+	}
+	if err != nil {
+		return err
+	}
+
+	net, netCreditDebit := camtSigned(summary.TotalCredits - summary.TotalDebits)
+	opening, openingCreditDebit := camtSigned(summary.OpeningBalance)
+	closing, closingCreditDebit := camtSigned(summary.ClosingBalance)
+
+	for _, field := range []struct {
+		name  string
+		value any
+	}{
+		{"Id", id},
+		{"CreDtTm", createdAt},
+		{"FrToDt", camtPeriod{
+			From: summary.PeriodStart.UTC().Format(time.RFC3339),
+			To:   summary.PeriodEnd.UTC().Format(time.RFC3339),
+		}},
+		{"Acct", camtStatementAccount{
+			camtAccount: camtAccount{ID: strconv.FormatInt(summary.Account.ID, 10)},
+			Currency:    currency,
+			Owner:       summary.Account.Owner,
+		}},
+		{"Bal", camtBalance{
+			Type:          "OPBD",
+			Amount:        camtAmount{Currency: currency, Value: opening},
+			CreditOrDebit: openingCreditDebit,
+			Date:          summary.PeriodStart.UTC().Format(time.DateOnly),
+		}},
+		{"Bal", camtBalance{
+			Type:          "CLBD",
+			Amount:        camtAmount{Currency: currency, Value: closing},
+			CreditOrDebit: closingCreditDebit,
+			Date:          lastDay(summary).Format(time.DateOnly),
+		}},
+		{"TxsSummry", camtTotals{
+			Count:          summary.EntryCount,
+			Sum:            strconv.FormatInt(summary.TotalCredits+summary.TotalDebits, 10),
+			Net:            net,
+			NetCreditDebit: netCreditDebit,
+			Credits:        strconv.FormatInt(summary.TotalCredits, 10),
+			Debits:         strconv.FormatInt(summary.TotalDebits, 10),
+		}},
+	} {
+		if err := writer.encoder.EncodeElement(field.value, element(field.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *CAMT053Writer) Line(line db.StatementLine) error {
+	amount, creditDebit := camtSigned(line.Amount)
+	description := Describe(line)
+
+	transaction := camtTransaction{Remittance: description}
+	if line.ReferenceID != 0 {
+		transaction.Reference = strconv.FormatInt(line.ReferenceID, 10)
+	}
+	// Only a transfer has a counterparty worth naming. The others are the bank's own
+	// cash and adjustment accounts.
+	if line.Kind == "transfer" {
+		party := &camtParty{Name: line.CounterpartyOwner}
+		account := &camtAccount{ID: strconv.FormatInt(line.CounterpartyAccountID, 10)}
+		if line.Amount < 0 {
+			transaction.Parties = &camtParties{Creditor: party, CreditorAccount: account}
+		} else {
+			transaction.Parties = &camtParties{Debtor: party, DebtorAccount: account}
+		}
+	}
+
+	entryID := strconv.FormatInt(line.ID, 10)
+	return writer.encoder.Encode(camtEntry{
+		Reference:       entryID,
+		Amount:          camtAmount{Currency: writer.summary.Account.Currency, Value: amount},
+		CreditOrDebit:   creditDebit,
+		Status:          "BOOK",
+		BookingDate:     line.CreatedAt.UTC().Format(time.RFC3339),
+		ValueDate:       line.CreatedAt.UTC().Format(time.DateOnly),
+		ServicerRef:     entryID,
+		TransactionCode: camtCode(line),
+		Transaction:     transaction,
+		Information:     description,
+	})
+}
+
+func (writer *CAMT053Writer) End() error {
+	for _, name := range []string{"Stmt", "BkToCstmrStmt", "Document"} {
+		if err := writer.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	if err := writer.encoder.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer.w, "\n")
+	return err
+}
+
+func (writer *CAMT053Writer) start(name string, attrs ...xml.Attr) error {
+	return writer.encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+}
+
+func element(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// camtSigned splits an amount into the positive amount and credit or debit indicator
+// camt.053 wants instead of a sign.
+func camtSigned(amount int64) (string, string) {
+	if amount < 0 {
+		return strconv.FormatInt(-amount, 10), "DBIT"
+	}
+	return strconv.FormatInt(amount, 10), "CRDT"
+}
+
+// camtCode is the ISO bank transaction code for the entry, as domain, family and sub
+// family. Transfers between accounts here are book transfers.
+func camtCode(line db.StatementLine) camtTransactionCode {
+	credit := line.Amount >= 0
+	switch line.Kind {
+	case "transfer":
+		if credit {
+			return camtTransactionCode{"PMNT", "RCDT", "BOOK"}
+		}
+		return camtTransactionCode{"PMNT", "ICDT", "BOOK"}
+	case "deposit":
+		return camtTransactionCode{"PMNT", "CNTR", "CDPT"}
+	case "withdrawal":
+		return camtTransactionCode{"PMNT", "CNTR", "CWDL"}
+	case "adjustment":
+		if credit {
+			return camtTransactionCode{"PMNT", "MCOP", "ADJT"}
+		}
+		return camtTransactionCode{"PMNT", "MDOP", "ADJT"}
+	}
+	if credit {
+		return camtTransactionCode{"PMNT", "MCOP", "OTHR"}
+	}
+	return camtTransactionCode{"PMNT", "MDOP", "OTHR"}
+}
+
+// statementID identifies the statement in the bank formats, e.g. 7-20240101-20240201.
+func statementID(summary db.StatementSummary) string {
+	return fmt.Sprintf("%d-%s-%s",
+		summary.Account.ID,
+		summary.PeriodStart.UTC().Format("20060102"),
+		summary.PeriodEnd.UTC().Format("20060102"),
+	)
+}
+
+// lastDay is the last day the period includes. The closing balance is dated on it,
+// since the period ends just before PeriodEnd.
+func lastDay(summary db.StatementSummary) time.Time {
+	return summary.PeriodEnd.UTC().Add(-time.Nanosecond)
+}
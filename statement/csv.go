@@ -0,0 +1,82 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+var csvHeader = []string{
+	"entry_id", "date", "type", "description",
+	"counterparty_account_id", "counterparty_owner", "reference_id",
+	"amount", "balance",
+}
+
+// CSVWriter writes a statement as CSV. The first row after the header is the opening
+// balance and the last is the closing balance, so the file reads top to bottom.
+type CSVWriter struct {
+	csv     *csv.Writer
+	summary db.StatementSummary
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{csv: csv.NewWriter(w)}
+}
+
+func (writer *CSVWriter) Begin(summary db.StatementSummary) error {
+	writer.summary = summary
+	if err := writer.csv.Write(csvHeader); err != nil {
+		return err
+	}
+	return writer.balanceRow("opening_balance", "Opening balance", summary.PeriodStart, summary.OpeningBalance)
+}
+
+func (writer *CSVWriter) Line(line db.StatementLine) error {
+	counterparty := ""
+	if line.CounterpartyAccountID != 0 {
+		counterparty = strconv.FormatInt(line.CounterpartyAccountID, 10)
+	}
+	reference := ""
+	if line.ReferenceID != 0 {
+		reference = strconv.FormatInt(line.ReferenceID, 10)
+	}
+
+	// csv.Writer buffers, and flushes to w whenever its buffer fills up.
+	return writer.csv.Write([]string{
+		strconv.FormatInt(line.ID, 10),
+		line.CreatedAt.UTC().Format(time.RFC3339),
+		entryType(line),
+		Describe(line),
+		counterparty,
+		line.CounterpartyOwner,
+		reference,
+		strconv.FormatInt(line.Amount, 10),
+		strconv.FormatInt(line.Balance, 10),
+	})
+}
+
+func (writer *CSVWriter) End() error {
+	err := writer.balanceRow("closing_balance", "Closing balance", writer.summary.PeriodEnd, writer.summary.ClosingBalance)
+	if err != nil {
+		return err
+	}
+	writer.csv.Flush()
+	return writer.csv.Error()
+}
+
+func (writer *CSVWriter) balanceRow(kind, description string, at time.Time, balance int64) error {
+	return writer.csv.Write([]string{
+		"", at.UTC().Format(time.RFC3339), kind, description, "", "", "", "", strconv.FormatInt(balance, 10),
+	})
+}
+
+// entryType is the kind of the entry, or "entry" when nothing is known about it.
+func entryType(line db.StatementLine) string {
+	if line.Kind == "" {
+		return "entry"
+	}
+	return line.Kind
+}
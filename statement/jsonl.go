@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// JSONLWriter writes a statement as JSON lines: an opening_balance object, an entry
+// object per entry, then a closing_balance object.
+type JSONLWriter struct {
+	encoder *json.Encoder
+	summary db.StatementSummary
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{encoder: json.NewEncoder(w)}
+}
+
+type jsonlOpening struct {
+	Type        string    `json:"type"`
+	AccountID   int64     `json:"account_id"`
+	Owner       string    `json:"owner"`
+	Currency    string    `json:"currency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Balance     int64     `json:"balance"`
+}
+
+type jsonlEntry struct {
+	Type                  string    `json:"type"`
+	EntryID               int64     `json:"entry_id"`
+	CreatedAt             time.Time `json:"created_at"`
+	Kind                  string    `json:"kind"`
+	Description           string    `json:"description"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id,omitempty"`
+	CounterpartyOwner     string    `json:"counterparty_owner,omitempty"`
+	ReferenceID           int64     `json:"reference_id,omitempty"`
+	Amount                int64     `json:"amount"`
+	Balance               int64     `json:"balance"`
+}
+
+type jsonlClosing struct {
+	Type         string `json:"type"`
+	Balance      int64  `json:"balance"`
+	TotalCredits int64  `json:"total_credits"`
+	TotalDebits  int64  `json:"total_debits"`
+	EntryCount   int64  `json:"entry_count"`
+}
+
+func (writer *JSONLWriter) Begin(summary db.StatementSummary) error {
+	writer.summary = summary
+	return writer.encoder.Encode(jsonlOpening{
+		Type:        "opening_balance",
+		AccountID:   summary.Account.ID,
+		Owner:       summary.Account.Owner,
+		Currency:    summary.Account.Currency,
+		PeriodStart: summary.PeriodStart.UTC(),
+		PeriodEnd:   summary.PeriodEnd.UTC(),
+		Balance:     summary.OpeningBalance,
+	})
+}
+
+func (writer *JSONLWriter) Line(line db.StatementLine) error {
+	return writer.encoder.Encode(jsonlEntry{
+		Type:                  "entry",
+		EntryID:               line.ID,
+		CreatedAt:             line.CreatedAt.UTC(),
+		Kind:                  entryType(line),
+		Description:           Describe(line),
+		CounterpartyAccountID: line.CounterpartyAccountID,
+		CounterpartyOwner:     line.CounterpartyOwner,
+		ReferenceID:           line.ReferenceID,
+		Amount:                line.Amount,
+		Balance:               line.Balance,
+	})
+}
+
+func (writer *JSONLWriter) End() error {
+	return writer.encoder.Encode(jsonlClosing{
+		Type:         "closing_balance",
+		Balance:      writer.summary.ClosingBalance,
+		TotalCredits: writer.summary.TotalCredits,
+		TotalDebits:  writer.summary.TotalDebits,
+		EntryCount:   writer.summary.EntryCount,
+	})
+}
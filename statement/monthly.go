@@ -0,0 +1,195 @@
+package statement
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/techschool/simplebank/blob"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/jobs"
+	"github.com/techschool/simplebank/util"
+)
+
+// MonthlyFormat is the format monthly statements are stored in.
+const MonthlyFormat = FormatPDF
+
+// How many pending statements are added per transaction.
+const monthlyBatchSize = 500
+
+var errBlobStoreStopped = errors.New("blob store stopped reading the statement")
+
+// GeneratePayload names the statement a job generates.
+type GeneratePayload struct {
+	StatementID int64 `json:"statement_id"`
+}
+
+// Generate writes a pending statement to blob storage. Its retries come from the jobs queue.
+var Generate = jobs.NewKind[GeneratePayload]("statement.generate")
+
+// StorageKey is where a statement's file is kept in blob storage.
+func StorageKey(statement db.Statement) string {
+	arg := Params{
+		AccountID:   statement.AccountID,
+		PeriodStart: statement.PeriodStart,
+		PeriodEnd:   statement.PeriodEnd,
+	}
+	return fmt.Sprintf("statements/%d/%s", statement.AccountID, Filename(arg, Format(statement.Format)))
+}
+
+// GenerateHandler writes the statement to blob storage, then marks it ready with the
+// file's path and checksum. A retry after a failure writes the whole file again.
+func GenerateHandler(store db.Store, blobs blob.Store) func(ctx context.Context, payload GeneratePayload) error {
+	return func(ctx context.Context, payload GeneratePayload) error {
+		statement, err := store.GetStatement(ctx, payload.StatementID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return jobs.Permanent(err)
+		}
+		if err != nil {
+			return err
+		}
+		if statement.Status != db.StatementStatusPending {
+			return nil
+		}
+
+		key := StorageKey(statement)
+		checksum, size, err := storeStatement(ctx, store, blobs, key, statement)
+		if err != nil {
+			return err
+		}
+
+		_, err = store.CompleteStatement(ctx, db.CompleteStatementParams{
+			StoragePath: key,
+			Checksum:    checksum,
+			SizeBytes:   size,
+			ID:          statement.ID,
+		})
+		// Another run finished it first. Its file is the same.
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+}
+
+// storeStatement streams the statement straight into blob storage through a pipe,
+// hashing it on the way, so the file is never held in memory. Returns the hex SHA-256
+// and size of what was stored.
+func storeStatement(ctx context.Context, store db.Store, blobs blob.Store, key string, statement db.Statement) (string, int64, error) {
+	pr, pw := io.Pipe()
+	writer, err := NewWriter(Format(statement.Format), pw)
+	if err != nil {
+		return "", 0, jobs.Permanent(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := Write(ctx, store, Params{
+			AccountID:   statement.AccountID,
+			PeriodStart: statement.PeriodStart,
+			PeriodEnd:   statement.PeriodEnd,
+		}, writer)
+		// A nil error closes the pipe normally, so Put sees the end of the file. Any
+		// other error makes Put fail without storing anything.
+		pw.CloseWithError(err)
+	}()
+
+	hash := sha256.New()
+	size, err := blobs.Put(ctx, key, io.TeeReader(pr, hash))
+	// If Put gave up early, this stops the statement being written into nothing.
+	pr.CloseWithError(errBlobStoreStopped)
+	<-done
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// PreviousMonth returns the calendar month, in UTC, before the one t falls in.
+func PreviousMonth(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	end := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, -1, 0), end
+}
+
+// MonthlyWorker makes sure every active account gets a statement for last month. Each
+// poll adds any that are missing, with a job to generate each one, so a month is
+// picked up soon after it ends and an instance that was down catches up when it starts.
+type MonthlyWorker struct {
+	store        db.Store
+	pollInterval time.Duration
+	// The last period every statement was added for, so later polls can skip it.
+	done time.Time
+}
+
+// NewMonthlyWorker creates a worker that checks every pollInterval.
+func NewMonthlyWorker(store db.Store, pollInterval time.Duration) *MonthlyWorker {
+	return &MonthlyWorker{store: store, pollInterval: pollInterval}
+}
+
+// Run polls until ctx is cancelled.
+func (worker *MonthlyWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := worker.CreateDue(ctx, time.Now()); err != nil {
+			util.LoggerFromContext(ctx).ErrorContext(ctx, "cannot create monthly statements", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreateDue adds the statements for the month before now that don't exist yet, and
+// returns how many it added. Several instances can run it at once.
+func (worker *MonthlyWorker) CreateDue(ctx context.Context, now time.Time) (int, error) {
+	periodStart, periodEnd := PreviousMonth(now)
+	if worker.done.Equal(periodStart) {
+		return 0, nil
+	}
+
+	count := 0
+	for {
+		statements, err := worker.store.CreatePendingStatementsTx(ctx, db.CreatePendingStatementsTxParams{
+			CreatePendingStatementsParams: db.CreatePendingStatementsParams{
+				PeriodStart: periodStart,
+				PeriodEnd:   periodEnd,
+				Format:      string(MonthlyFormat),
+				Limit:       monthlyBatchSize,
+			},
+			Job: func(statement db.Statement) db.JobRequest {
+				return Generate.New(GeneratePayload{StatementID: statement.ID})
+			},
+		})
+		if err != nil {
+			return count, err
+		}
+		count += len(statements)
+
+		if len(statements) < monthlyBatchSize {
+			break
+		}
+	}
+
+	worker.done = periodStart
+	if count > 0 {
+		util.LoggerFromContext(ctx).InfoContext(ctx, "created monthly statements",
+			slog.Time("period_start", periodStart),
+			slog.Int("count", count),
+		)
+	}
+	return count, nil
+}
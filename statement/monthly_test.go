@@ -0,0 +1,164 @@
+package statement
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/techschool/simplebank/blob"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// monthlyStore adds the statements table to fakeStore.
+type monthlyStore struct {
+	*fakeStore
+	statement db.Statement
+	completed []db.CompleteStatementParams
+	failWrite bool
+	// Sizes of the batches CreatePendingStatementsTx returns, in turn.
+	batches  []int
+	requests []db.JobRequest
+	periods  []time.Time
+}
+
+func (store *monthlyStore) GetStatement(ctx context.Context, id int64) (db.Statement, error) {
+	if id != store.statement.ID {
+		return db.Statement{}, sql.ErrNoRows
+	}
+	return store.statement, nil
+}
+
+func (store *monthlyStore) CompleteStatement(ctx context.Context, arg db.CompleteStatementParams) (db.Statement, error) {
+	store.completed = append(store.completed, arg)
+	return store.statement, nil
+}
+
+func (store *monthlyStore) StatementTx(ctx context.Context, arg db.StatementTxParams) error {
+	if store.failWrite {
+		if err := arg.Begin(store.summary); err != nil {
+			return err
+		}
+		return errors.New("connection lost")
+	}
+	return store.fakeStore.StatementTx(ctx, arg)
+}
+
+func (store *monthlyStore) CreatePendingStatementsTx(ctx context.Context, arg db.CreatePendingStatementsTxParams) ([]db.Statement, error) {
+	store.periods = append(store.periods, arg.PeriodStart)
+	if len(store.batches) == 0 {
+		return nil, nil
+	}
+	n := store.batches[0]
+	store.batches = store.batches[1:]
+
+	statements := make([]db.Statement, n)
+	for i := range statements {
+		statements[i] = db.Statement{ID: int64(len(store.requests) + 1), PeriodStart: arg.PeriodStart, PeriodEnd: arg.PeriodEnd}
+		store.requests = append(store.requests, arg.Job(statements[i]))
+	}
+	return statements, nil
+}
+
+func newMonthlyStore() *monthlyStore {
+	return &monthlyStore{
+		fakeStore: testStatement(3),
+		statement: db.Statement{
+			ID:          1,
+			AccountID:   7,
+			PeriodStart: periodStart,
+			PeriodEnd:   periodEnd,
+			Format:      string(FormatPDF),
+			Status:      db.StatementStatusPending,
+		},
+	}
+}
+
+func TestGenerateHandler(t *testing.T) {
+	store := newMonthlyStore()
+	blobs, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	handle := GenerateHandler(store, blobs)
+
+	require.NoError(t, handle(context.Background(), GeneratePayload{StatementID: 1}))
+	require.Len(t, store.completed, 1)
+	completed := store.completed[0]
+	require.Equal(t, "statements/7/statement-7-2024-01-01-2024-02-01.pdf", completed.StoragePath)
+
+	// The stored file is a whole statement, and the checksum and size are of it.
+	file, err := blobs.Open(context.Background(), completed.StoragePath)
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	checkPDF(t, data)
+	sum := sha256.Sum256(data)
+	require.Equal(t, hex.EncodeToString(sum[:]), completed.Checksum)
+	require.Equal(t, int64(len(data)), completed.SizeBytes)
+
+	// A ready statement is left alone.
+	store.statement.Status = db.StatementStatusReady
+	require.NoError(t, handle(context.Background(), GeneratePayload{StatementID: 1}))
+	require.Len(t, store.completed, 1)
+}
+
+func TestGenerateHandlerFails(t *testing.T) {
+	store := newMonthlyStore()
+	store.failWrite = true
+	blobs, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	handle := GenerateHandler(store, blobs)
+
+	// The job is retried, and nothing half written is left in storage.
+	err = handle(context.Background(), GeneratePayload{StatementID: 1})
+	require.ErrorContains(t, err, "connection lost")
+	require.Empty(t, store.completed)
+	_, err = blobs.Open(context.Background(), StorageKey(store.statement))
+	require.ErrorIs(t, err, blob.ErrNotFound)
+
+	// A statement that was deleted won't come back, so there is no point retrying.
+	err = handle(context.Background(), GeneratePayload{StatementID: 2})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestPreviousMonth(t *testing.T) {
+	start, end := PreviousMonth(time.Date(2024, 2, 10, 8, 0, 0, 0, time.UTC))
+	require.Equal(t, periodStart, start)
+	require.Equal(t, periodEnd, end)
+
+	start, end = PreviousMonth(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, periodStart, end)
+}
+
+func TestMonthlyWorker(t *testing.T) {
+	store := newMonthlyStore()
+	store.batches = []int{monthlyBatchSize, 3}
+	worker := NewMonthlyWorker(store, time.Hour)
+	now := time.Date(2024, 2, 1, 0, 30, 0, 0, time.UTC)
+
+	// Batches are added until one comes back short.
+	count, err := worker.CreateDue(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, monthlyBatchSize+3, count)
+	require.Equal(t, []time.Time{periodStart, periodStart}, store.periods)
+	require.Equal(t, Generate.Name, store.requests[0].Kind)
+	require.Equal(t, GeneratePayload{StatementID: 1}, store.requests[0].Payload)
+
+	// The month is done, so the next poll doesn't look again.
+	count, err = worker.CreateDue(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Len(t, store.periods, 2)
+
+	// Until the next month ends.
+	_, err = worker.CreateDue(context.Background(), now.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Len(t, store.periods, 3)
+	require.Equal(t, periodEnd, store.periods[2])
+}
@@ -0,0 +1,143 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Limits of the MT940 fields that carry free text.
+const (
+	mt940ReferenceLength  = 16
+	mt940AccountIDLength  = 35
+	mt940InformationLine  = 65
+	mt940InformationLines = 6
+)
+
+// MT940Writer writes a statement as a SWIFT MT940 customer statement message, the text
+// block of it that banks hand out as .sta files. The whole period is one message: the
+// 2,000 character limit only applies to messages sent over the SWIFT network. Amounts
+// are whole units of the account's currency, written with the comma MT940 requires.
+type MT940Writer struct {
+	w       *bufio.Writer
+	summary db.StatementSummary
+	// The first write error. Later writes are skipped.
+	writeErr error
+}
+
+func NewMT940Writer(w io.Writer) *MT940Writer {
+	return &MT940Writer{w: bufio.NewWriter(w)}
+}
+
+func (writer *MT940Writer) Begin(summary db.StatementSummary) error {
+	writer.summary = summary
+	// The statement's reference is the account and the first day, e.g. 7-240101.
+	reference := fmt.Sprintf("%d-%s", summary.Account.ID, summary.PeriodStart.UTC().Format("060102"))
+	writer.field("20", cut(reference, mt940ReferenceLength))
+	writer.field("25", cut(strconv.FormatInt(summary.Account.ID, 10), mt940AccountIDLength))
+	writer.field("28C", "1")
+	writer.field("60F", mt940Balance(summary.OpeningBalance, summary.PeriodStart.UTC(), summary.Account.Currency))
+	return writer.writeErr
+}
+
+func (writer *MT940Writer) Line(line db.StatementLine) error {
+	date := line.CreatedAt.UTC()
+	mark, amount := mt940Signed(line.Amount)
+
+	code := "NMSC"
+	if line.Kind == "transfer" {
+		code = "NTRF"
+	}
+	reference := "NONREF"
+	if line.ReferenceID != 0 {
+		reference = strconv.FormatInt(line.ReferenceID, 10)
+	}
+
+	// Value date, entry date, debit or credit, amount, transaction type, the reference
+	// for the account owner and then, after //, the bank's own reference.
+	writer.field("61", fmt.Sprintf("%s%s%s%s%s%s//%s",
+		date.Format("060102"), date.Format("0102"), mark, amount, code,
+		cut(reference, mt940ReferenceLength),
+		cut(strconv.FormatInt(line.ID, 10), mt940ReferenceLength),
+	))
+	writer.field("86", mt940Information(Describe(line)))
+	return writer.writeErr
+}
+
+func (writer *MT940Writer) End() error {
+	writer.field("62F", mt940Balance(writer.summary.ClosingBalance, lastDay(writer.summary), writer.summary.Account.Currency))
+	writer.line("-")
+	if writer.writeErr != nil {
+		return writer.writeErr
+	}
+	return writer.w.Flush()
+}
+
+// field writes :tag:value. Lines end in CRLF, as SWIFT messages do.
+func (writer *MT940Writer) field(tag, value string) {
+	writer.line(":" + tag + ":" + value)
+}
+
+func (writer *MT940Writer) line(text string) {
+	if writer.writeErr != nil {
+		return
+	}
+	_, writer.writeErr = writer.w.WriteString(text + "\r\n")
+}
+
+// mt940Balance is a balance field: debit or credit, date, currency and amount.
+func mt940Balance(balance int64, date time.Time, currency string) string {
+	mark, amount := mt940Signed(balance)
+	return mark + date.Format("060102") + currency + amount
+}
+
+// mt940Signed splits an amount into the D or C mark and the positive amount, e.g. 30,.
+func mt940Signed(amount int64) (string, string) {
+	if amount < 0 {
+		return "D", strconv.FormatInt(-amount, 10) + ","
+	}
+	return "C", strconv.FormatInt(amount, 10) + ","
+}
+
+// mt940Information fits text into the :86: field, up to six lines of 65 characters,
+// after replacing anything outside the SWIFT character set.
+func mt940Information(text string) string {
+	text = swiftText(text)
+	var lines []string
+	for len(text) > 0 && len(lines) < mt940InformationLines {
+		// A line starting with : would be read as the next field, and - as the end, so
+		// such a line gets a space in front and one character less of the text.
+		prefix, room := "", mt940InformationLine
+		if len(lines) > 0 && (text[0] == ':' || text[0] == '-') {
+			prefix, room = " ", mt940InformationLine-1
+		}
+		n := min(len(text), room)
+		lines = append(lines, prefix+text[:n])
+		text = text[n:]
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// cut shortens an ASCII string to at most n bytes.
+func cut(s string, n int) string {
+	return s[:min(len(s), n)]
+}
+
+// swiftText replaces every character outside the SWIFT X character set with a space.
+// Only ASCII survives, so the result can be cut at any byte.
+func swiftText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case strings.ContainsRune("/-?:().,'+ ", r):
+			return r
+		}
+		return ' '
+	}, text)
+}
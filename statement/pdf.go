@@ -0,0 +1,261 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// The page is A4, measured in points (1/72 inch), with (0, 0) at the bottom left.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 40
+	fontSize   = 8
+	leading    = 11
+)
+
+// The table is set in Courier so its columns line up without measuring any text.
+// Every Courier character is 0.6 of the font size wide, so 104 characters at 8pt
+// fit between the margins.
+const (
+	dateWidth        = 16
+	descriptionWidth = 50
+	amountWidth      = 16
+)
+
+// Objects whose numbers are fixed. The page tree is written last, once every page is
+// known, but the pages can refer to it before then because PDF objects are found
+// through the cross-reference table rather than by position.
+const (
+	catalogObject = iota + 1
+	pagesObject
+	courierObject
+	boldObject
+	firstFreeObject
+)
+
+// PDFWriter writes a statement as a PDF using only the standard fonts every reader
+// has, so nothing needs embedding. Each page is written out as soon as it is full,
+// so only one page is held in memory.
+type PDFWriter struct {
+	w          *countingWriter
+	offsets    []int64
+	pages      []int
+	page       bytes.Buffer
+	pageNumber int
+	y          float64
+	summary    db.StatementSummary
+}
+
+func NewPDFWriter(w io.Writer) *PDFWriter {
+	return &PDFWriter{
+		w:       &countingWriter{w: w},
+		offsets: make([]int64, firstFreeObject),
+	}
+}
+
+func (writer *PDFWriter) Begin(summary db.StatementSummary) error {
+	writer.summary = summary
+
+	// The second line marks the file as binary for tools that guess.
+	writer.w.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	writer.writeObject(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	writer.writeObject(courierObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	writer.writeObject(boldObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	writer.startPage()
+	writer.y -= 6
+	writer.text(boldObject, 14, fmt.Sprintf("Statement for account %d", summary.Account.ID))
+	writer.y -= 2 * leading
+	writer.row(fmt.Sprintf("Owner:    %s", summary.Account.Owner))
+	writer.row(fmt.Sprintf("Currency: %s", summary.Account.Currency))
+	writer.row(fmt.Sprintf("Period:   %s to %s", formatPDFTime(summary.PeriodStart), formatPDFTime(summary.PeriodEnd)))
+	writer.y -= leading
+	writer.tableHeader()
+	writer.row(tableRow(formatPDFTime(summary.PeriodStart), "Opening balance", "", formatAmount(summary.OpeningBalance)))
+	return writer.w.err
+}
+
+func (writer *PDFWriter) Line(line db.StatementLine) error {
+	if writer.y < margin {
+		writer.finishPage()
+		writer.startPage()
+		writer.tableHeader()
+	}
+	writer.row(tableRow(formatPDFTime(line.CreatedAt), Describe(line), formatAmount(line.Amount), formatAmount(line.Balance)))
+	return writer.w.err
+}
+
+func (writer *PDFWriter) End() error {
+	summary := writer.summary
+
+	// The closing balance and totals stay together on one page.
+	if writer.y < margin+4*leading {
+		writer.finishPage()
+		writer.startPage()
+	}
+	writer.row(tableRow(formatPDFTime(summary.PeriodEnd), "Closing balance", "", formatAmount(summary.ClosingBalance)))
+	writer.y -= leading
+	writer.row(fmt.Sprintf("Money in:  %s", formatAmount(summary.TotalCredits)))
+	writer.row(fmt.Sprintf("Money out: %s", formatAmount(summary.TotalDebits)))
+	writer.row(fmt.Sprintf("Entries:   %d", summary.EntryCount))
+	writer.finishPage()
+
+	kids := make([]string, len(writer.pages))
+	for i, page := range writer.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	writer.writeObject(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	info := writer.newObject()
+	writer.writeObject(info, fmt.Sprintf("<< /Title %s /Producer (simplebank) >>",
+		pdfString(fmt.Sprintf("Statement for account %d", summary.Account.ID))))
+
+	// The cross-reference table gives the byte offset of every object. Each line is
+	// exactly 20 bytes, which is why the line ends in a space and a newline.
+	xref := writer.w.n
+	fmt.Fprintf(writer.w, "xref\n0 %d\n", len(writer.offsets))
+	writer.w.WriteString("0000000000 65535 f \n")
+	for _, offset := range writer.offsets[1:] {
+		fmt.Fprintf(writer.w, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(writer.w, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(writer.offsets), catalogObject, info, xref)
+	return writer.w.err
+}
+
+// newObject reserves the next object number.
+func (writer *PDFWriter) newObject() int {
+	writer.offsets = append(writer.offsets, 0)
+	return len(writer.offsets) - 1
+}
+
+func (writer *PDFWriter) writeObject(number int, body string) {
+	writer.offsets[number] = writer.w.n
+	fmt.Fprintf(writer.w, "%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+func (writer *PDFWriter) startPage() {
+	writer.page.Reset()
+	writer.pageNumber++
+	writer.y = pageHeight - margin
+	if writer.pageNumber > 1 {
+		writer.row(fmt.Sprintf("Statement for account %d, %s to %s", writer.summary.Account.ID,
+			formatPDFTime(writer.summary.PeriodStart), formatPDFTime(writer.summary.PeriodEnd)))
+		writer.y -= leading
+	}
+}
+
+// finishPage writes the page being filled as a content stream and a page object.
+func (writer *PDFWriter) finishPage() {
+	writer.y = margin / 2
+	writer.text(courierObject, fontSize, fmt.Sprintf("Page %d", writer.pageNumber))
+
+	content := writer.newObject()
+	writer.offsets[content] = writer.w.n
+	fmt.Fprintf(writer.w, "%d 0 obj\n<< /Length %d >>\nstream\n", content, writer.page.Len())
+	writer.w.Write(writer.page.Bytes())
+	writer.w.WriteString("\nendstream\nendobj\n")
+
+	page := writer.newObject()
+	writer.writeObject(page, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pagesObject, pageWidth, pageHeight, courierObject, boldObject, content))
+	writer.pages = append(writer.pages, page)
+}
+
+func (writer *PDFWriter) tableHeader() {
+	writer.row(tableRow("Date (UTC)", "Description", "Amount", "Balance"))
+	writer.row(strings.Repeat("-", dateWidth+descriptionWidth+2*amountWidth+6))
+}
+
+// row writes a line of Courier text and moves down to the next.
+func (writer *PDFWriter) row(text string) {
+	writer.text(courierObject, fontSize, text)
+	writer.y -= leading
+}
+
+// text writes text at the current height in the given font, without moving down.
+func (writer *PDFWriter) text(font int, size int, text string) {
+	name := "F1"
+	if font == boldObject {
+		name = "F2"
+	}
+	fmt.Fprintf(&writer.page, "BT /%s %d Tf %d %s Td %s Tj ET\n", name, size, margin,
+		strconv.FormatFloat(writer.y, 'f', -1, 64), pdfString(text))
+}
+
+func tableRow(date, description, amount, balance string) string {
+	return fmt.Sprintf("%-*s  %-*s  %*s  %*s",
+		dateWidth, date,
+		descriptionWidth, truncate(description, descriptionWidth),
+		amountWidth, amount,
+		amountWidth, balance,
+	)
+}
+
+// truncate shortens s to at most n characters, ending in ... if anything was cut.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-3]) + "..."
+}
+
+func formatAmount(amount int64) string {
+	return strconv.FormatInt(amount, 10)
+}
+
+func formatPDFTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04")
+}
+
+// pdfString writes s as a PDF literal string. The fonts use WinAnsiEncoding, which
+// matches Latin-1 for the printable characters, so anything outside it becomes ?.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || (r > 0x7e && r < 0xa0) || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// countingWriter counts the bytes written so far, which the cross-reference table
+// needs, and keeps the first error so every write doesn't have to be checked.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	return cw.Write([]byte(s))
+}
@@ -0,0 +1,176 @@
+// Package statement turns an account's entries over a period into a statement file.
+// Statements are written line by line as the entries are read, so even an account
+// with millions of entries never has its statement held in memory.
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Format is a statement file format.
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+	FormatPDF   Format = "pdf"
+	// The bank formats ERP systems import.
+	FormatCAMT053 Format = "camt053"
+	FormatMT940   Format = "mt940"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatCSV, FormatJSONL, FormatPDF, FormatCAMT053, FormatMT940}
+
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+// ContentType is the MIME type of a statement in this format.
+func (format Format) ContentType() string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/jsonl; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatCAMT053:
+		return "application/xml; charset=utf-8"
+	case FormatMT940:
+		return "text/plain; charset=us-ascii"
+	}
+	return "application/octet-stream"
+}
+
+// Extension is the file extension for the format, without the dot.
+func (format Format) Extension() string {
+	switch format {
+	case FormatCAMT053:
+		return "xml"
+	case FormatMT940:
+		return "sta"
+	}
+	return string(format)
+}
+
+// Writer writes one statement. Begin is called once, then Line for each entry, then End.
+type Writer interface {
+	Begin(summary db.StatementSummary) error
+	Line(line db.StatementLine) error
+	End() error
+}
+
+// NewWriter creates a writer for the format that writes to w.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatJSONL:
+		return NewJSONLWriter(w), nil
+	case FormatPDF:
+		return NewPDFWriter(w), nil
+	case FormatCAMT053:
+		return NewCAMT053Writer(w), nil
+	case FormatMT940:
+		return NewMT940Writer(w), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// Params says which account and period a statement covers. The period includes
+// PeriodStart and excludes PeriodEnd.
+type Params struct {
+	AccountID   int64
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Write reads the statement from the store and streams it through the writer.
+func Write(ctx context.Context, store db.Store, arg Params, writer Writer) error {
+	err := store.StatementTx(ctx, db.StatementTxParams{
+		AccountID:   arg.AccountID,
+		PeriodStart: arg.PeriodStart,
+		PeriodEnd:   arg.PeriodEnd,
+		Begin:       writer.Begin,
+		Line:        writer.Line,
+	})
+	if err != nil {
+		return err
+	}
+	return writer.End()
+}
+
+// Filename is a name to save the statement as, e.g. statement-7-2024-01-01-2024-02-01.csv.
+func Filename(arg Params, format Format) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s",
+		arg.AccountID,
+		arg.PeriodStart.UTC().Format(time.DateOnly),
+		arg.PeriodEnd.UTC().Format(time.DateOnly),
+		format.Extension(),
+	)
+}
+
+var ErrInvalidPeriod = errors.New("invalid statement period")
+
+// ParsePeriod reads a period given as two dates (2024-01-31) or RFC 3339 times.
+// A date for to means the end of that day, so from=2024-01-01&to=2024-01-31 is
+// the whole of January. An empty to means now.
+func ParsePeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	start, _, err := parseTime(from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from: %v", ErrInvalidPeriod, err)
+	}
+
+	end := now
+	if to != "" {
+		var isDate bool
+		end, isDate, err = parseTime(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to: %v", ErrInvalidPeriod, err)
+		}
+		if isDate {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be before to", ErrInvalidPeriod)
+	}
+	return start, end, nil
+}
+
+// parseTime accepts a date, as midnight UTC, or an RFC 3339 time.
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// Describe says in words what made an entry, e.g. "Transfer to account 12 (bob)".
+func Describe(line db.StatementLine) string {
+	switch line.Kind {
+	case "transfer":
+		direction := "from"
+		if line.Amount < 0 {
+			direction = "to"
+		}
+		if line.CounterpartyOwner == "" {
+			return fmt.Sprintf("Transfer %s account %d", direction, line.CounterpartyAccountID)
+		}
+		return fmt.Sprintf("Transfer %s account %d (%s)", direction, line.CounterpartyAccountID, line.CounterpartyOwner)
+	case "deposit":
+		return "Cash deposit"
+	case "withdrawal":
+		return "Cash withdrawal"
+	case "adjustment":
+		return fmt.Sprintf("Adjustment (%s)", line.Reason)
+	}
+	return "Entry"
+}
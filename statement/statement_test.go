@@ -0,0 +1,200 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// fakeStore hands out a fixed statement.
+type fakeStore struct {
+	db.Store
+	summary db.StatementSummary
+	lines   []db.StatementLine
+}
+
+func (store *fakeStore) StatementTx(ctx context.Context, arg db.StatementTxParams) error {
+	if err := arg.Begin(store.summary); err != nil {
+		return err
+	}
+	for _, line := range store.lines {
+		if err := arg.Line(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	periodStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd   = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testStatement(lineCount int) *fakeStore {
+	store := &fakeStore{
+		summary: db.StatementSummary{
+			Account:        db.Account{ID: 7, Owner: "alice", Currency: "EUR"},
+			PeriodStart:    periodStart,
+			PeriodEnd:      periodEnd,
+			OpeningBalance: 100,
+		},
+	}
+
+	kinds := []db.ListStatementEntriesRow{
+		{Kind: "transfer", ReferenceID: 3, CounterpartyAccountID: 12, CounterpartyOwner: "bob", Amount: -30},
+		{Kind: "deposit", ReferenceID: 4, CounterpartyAccountID: 1, CounterpartyOwner: "system", Amount: 50},
+		{Kind: "adjustment", ReferenceID: 5, Reason: "fee", CounterpartyAccountID: 2, CounterpartyOwner: "system", Amount: -5},
+	}
+
+	balance := store.summary.OpeningBalance
+	for i := range lineCount {
+		row := kinds[i%len(kinds)]
+		row.ID = int64(i + 1)
+		row.CreatedAt = periodStart.Add(time.Duration(i+1) * time.Hour)
+		balance += row.Amount
+		store.lines = append(store.lines, db.StatementLine{ListStatementEntriesRow: row, Balance: balance})
+
+		if row.Amount > 0 {
+			store.summary.TotalCredits += row.Amount
+		} else {
+			store.summary.TotalDebits -= row.Amount
+		}
+	}
+	store.summary.EntryCount = int64(lineCount)
+	store.summary.ClosingBalance = balance
+	return store
+}
+
+func writeStatement(t *testing.T, format Format, store *fakeStore) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	require.NoError(t, err)
+	err = Write(context.Background(), store, Params{AccountID: 7, PeriodStart: periodStart, PeriodEnd: periodEnd}, writer)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	out := writeStatement(t, FormatCSV, testStatement(3))
+
+	require.Equal(t, `entry_id,date,type,description,counterparty_account_id,counterparty_owner,reference_id,amount,balance
+,2024-01-01T00:00:00Z,opening_balance,Opening balance,,,,,100
+1,2024-01-01T01:00:00Z,transfer,Transfer to account 12 (bob),12,bob,3,-30,70
+2,2024-01-01T02:00:00Z,deposit,Cash deposit,1,system,4,50,120
+3,2024-01-01T03:00:00Z,adjustment,Adjustment (fee),2,system,5,-5,115
+,2024-02-01T00:00:00Z,closing_balance,Closing balance,,,,,115
+`, string(out))
+}
+
+func TestJSONL(t *testing.T) {
+	out := writeStatement(t, FormatJSONL, testStatement(2))
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	require.Len(t, lines, 4)
+	require.JSONEq(t, `{"type":"opening_balance","account_id":7,"owner":"alice","currency":"EUR",
+		"period_start":"2024-01-01T00:00:00Z","period_end":"2024-02-01T00:00:00Z","balance":100}`, lines[0])
+	require.JSONEq(t, `{"type":"entry","entry_id":1,"created_at":"2024-01-01T01:00:00Z","kind":"transfer",
+		"description":"Transfer to account 12 (bob)","counterparty_account_id":12,"counterparty_owner":"bob",
+		"reference_id":3,"amount":-30,"balance":70}`, lines[1])
+	require.JSONEq(t, `{"type":"closing_balance","balance":120,"total_credits":50,"total_debits":30,"entry_count":2}`, lines[3])
+
+	for _, line := range lines {
+		require.True(t, json.Valid([]byte(line)))
+	}
+}
+
+// checkPDF checks the cross-reference table points at every object, which is what
+// a reader uses to find them, and returns the number of pages.
+func checkPDF(t *testing.T, out []byte) int {
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	require.NotNil(t, match)
+	xref, err := strconv.Atoi(string(match[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n")))
+
+	var first, count int
+	_, err = fmt.Sscanf(string(out[xref:]), "xref\n%d %d\n", &first, &count)
+	require.NoError(t, err)
+	table := out[bytes.Index(out[xref:], []byte("0000000000 65535 f \n"))+xref:]
+	for object := 1; object < count; object++ {
+		entry := string(table[object*20 : object*20+20])
+		require.Regexp(t, `^\d{10} 00000 n \n$`, entry)
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(out[offset:], fmt.Appendf(nil, "%d 0 obj\n", object)), "object %d", object)
+	}
+
+	// Every content stream's length matches what is between stream and endstream.
+	for _, m := range regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(out, -1) {
+		length, _ := strconv.Atoi(string(m[1]))
+		require.Len(t, m[2], length)
+	}
+
+	pages := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(out)
+	require.NotNil(t, pages)
+	n, _ := strconv.Atoi(string(pages[1]))
+	return n
+}
+
+func TestPDF(t *testing.T) {
+	out := writeStatement(t, FormatPDF, testStatement(3))
+	require.Equal(t, 1, checkPDF(t, out))
+	require.Contains(t, string(out), "(Statement for account 7)")
+	require.Contains(t, string(out), "Transfer to account 12 \\(bob\\)")
+	require.Contains(t, string(out), "Closing balance")
+}
+
+func TestPDFManyPages(t *testing.T) {
+	out := writeStatement(t, FormatPDF, testStatement(500))
+	pages := checkPDF(t, out)
+	require.Greater(t, pages, 5)
+	require.Contains(t, string(out), fmt.Sprintf("(Page %d)", pages))
+}
+
+func TestPDFString(t *testing.T) {
+	require.Equal(t, `(a \(b\) c\\d)`, pdfString(`a (b) c\d`))
+	require.Equal(t, "(caf\xe9 ?)", pdfString("café €"))
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	_, err := NewWriter("xlsx", &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	start, end, err := ParsePeriod("2024-01-01", "2024-01-31", now)
+	require.NoError(t, err)
+	require.Equal(t, periodStart, start)
+	require.Equal(t, periodEnd, end)
+
+	start, end, err = ParsePeriod("2024-03-01T00:00:00Z", "", now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, now, end)
+
+	_, _, err = ParsePeriod("2024-02-01", "2024-01-01", now)
+	require.ErrorIs(t, err, ErrInvalidPeriod)
+
+	_, _, err = ParsePeriod("last month", "", now)
+	require.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestFilename(t *testing.T) {
+	arg := Params{AccountID: 7, PeriodStart: periodStart, PeriodEnd: periodEnd}
+	require.Equal(t, "statement-7-2024-01-01-2024-02-01.pdf", Filename(arg, FormatPDF))
+	require.Equal(t, "statement-7-2024-01-01-2024-02-01.xml", Filename(arg, FormatCAMT053))
+}
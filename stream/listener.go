@@ -0,0 +1,158 @@
+// Package stream tells the API about entries as they are posted. A Listener holds one
+// LISTEN connection to Postgres and wakes whoever subscribed to the account an entry is
+// for, so the API can read the new entries and push them to the client. The same
+// connection can also hear about every outbox event, for the WebSocket hub.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// How long the listener waits before reconnecting after losing the connection, doubling
+// up to the maximum, and how often it checks an idle connection is still alive.
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = 90 * time.Second
+)
+
+// Listener fans the notifications on db.EntryPostedChannel out to subscribers.
+// Subscribers are only woken up: they read what is new from the entries table, so a
+// notification that is lost, or a wake up that is merged with the next, costs nothing.
+type Listener struct {
+	listener *pq.Listener
+	// Called with the id of each committed outbox event, see ListenForEvents.
+	onEvent func(eventID int64)
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan struct{}]struct{}
+}
+
+func newListener() *Listener {
+	return &Listener{subscribers: map[int64]map[chan struct{}]struct{}{}}
+}
+
+// NewListener starts listening with its own connection to the database at source. It
+// waits until it is connected, so create it once the database is up. Lost connections
+// are retried in the background.
+func NewListener(source string) (*Listener, error) {
+	l := newListener()
+	l.listener = pq.NewListener(source, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("entry listener connection problem", slog.Any("error", err))
+		}
+	})
+	if err := l.listener.Listen(db.EntryPostedChannel); err != nil {
+		l.listener.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// ListenForEvents calls handle with the id of every outbox event once it is committed.
+// handle must not block. It must be called before Run.
+func (l *Listener) ListenForEvents(handle func(eventID int64)) error {
+	l.onEvent = handle
+	return l.listener.Listen(db.OutboxEventChannel)
+}
+
+// Run hands notifications to the subscribers until ctx is cancelled.
+func (l *Listener) Run(ctx context.Context) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-l.listener.NotificationChannel():
+			l.dispatch(notification)
+		case <-ticker.C:
+			// A failed ping makes the listener reconnect.
+			go l.listener.Ping()
+		}
+	}
+}
+
+// Close closes the connection. Run must have returned.
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+// Subscribe returns a channel that receives a value whenever entries were posted to the
+// account, and a function to stop. Wake ups that arrive while one is waiting are merged.
+func (l *Listener) Subscribe(accountID int64) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	l.mu.Lock()
+	if l.subscribers[accountID] == nil {
+		l.subscribers[accountID] = map[chan struct{}]struct{}{}
+	}
+	l.subscribers[accountID][wake] = struct{}{}
+	l.mu.Unlock()
+
+	return wake, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers[accountID], wake)
+		if len(l.subscribers[accountID]) == 0 {
+			delete(l.subscribers, accountID)
+		}
+	}
+}
+
+// dispatch wakes the subscribers a notification is for. pq sends nil after reconnecting,
+// when notifications may have been missed, so then everybody is woken up to catch up.
+func (l *Listener) dispatch(notification *pq.Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if notification == nil {
+		for _, subscribers := range l.subscribers {
+			wakeAll(subscribers)
+		}
+		return
+	}
+
+	if notification.Channel == db.OutboxEventChannel {
+		l.dispatchEvent(notification.Extra)
+		return
+	}
+
+	var posted db.EntryPostedNotification
+	if err := json.Unmarshal([]byte(notification.Extra), &posted); err != nil {
+		slog.Warn("cannot decode entry notification", slog.String("payload", notification.Extra), slog.Any("error", err))
+		return
+	}
+	wakeAll(l.subscribers[posted.AccountID])
+}
+
+// dispatchEvent hands the id of an outbox event to the handler.
+func (l *Listener) dispatchEvent(payload string) {
+	eventID, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		slog.Warn("cannot decode outbox notification", slog.String("payload", payload), slog.Any("error", err))
+		return
+	}
+	if l.onEvent != nil {
+		l.onEvent(eventID)
+	}
+}
+
+func wakeAll(subscribers map[chan struct{}]struct{}) {
+	for wake := range subscribers {
+		select {
+		case wake <- struct{}{}:
+		default:
+			// Already has a wake up waiting.
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+func notification(payload string) *pq.Notification {
+	return &pq.Notification{Channel: db.EntryPostedChannel, Extra: payload}
+}
+
+func TestDispatch(t *testing.T) {
+	l := newListener()
+
+	first, stopFirst := l.Subscribe(1)
+	second, stopSecond := l.Subscribe(1)
+	other, stopOther := l.Subscribe(2)
+	defer stopOther()
+
+	l.dispatch(notification(`{"account_id":1,"entry_id":10}`))
+	// A second notification before the subscriber woke up is merged with the first.
+	l.dispatch(notification(`{"account_id":1,"entry_id":11}`))
+	require.Len(t, first, 1)
+	require.Len(t, second, 1)
+	require.Empty(t, other)
+	<-first
+	<-second
+
+	// Garbage is dropped.
+	l.dispatch(notification(`nope`))
+	require.Empty(t, first)
+
+	stopSecond()
+	l.dispatch(notification(`{"account_id":1,"entry_id":12}`))
+	require.Len(t, first, 1)
+	require.Empty(t, second)
+	<-first
+
+	// After a reconnect everybody catches up.
+	l.dispatch(nil)
+	require.Len(t, first, 1)
+	require.Len(t, other, 1)
+
+	stopFirst()
+	require.NotContains(t, l.subscribers, int64(1))
+}
+
+func TestDispatchOutboxEvents(t *testing.T) {
+	l := newListener()
+	var got []int64
+	l.onEvent = func(eventID int64) { got = append(got, eventID) }
+
+	wake, stop := l.Subscribe(7)
+	defer stop()
+
+	l.dispatch(&pq.Notification{Channel: db.OutboxEventChannel, Extra: "42"})
+	l.dispatch(&pq.Notification{Channel: db.OutboxEventChannel, Extra: "nope"})
+	require.Equal(t, []int64{42}, got)
+	// Entry subscribers only care about entries.
+	require.Empty(t, wake)
+}
@@ -0,0 +1,13 @@
+// Package token creates and verifies the access tokens handed out at login.
+package token
+
+import "time"
+
+// Maker is an interface for managing tokens, so the signing scheme can be swapped out.
+type Maker interface {
+	// CreateToken creates a new token for a specific username, role and duration.
+	// Options change what the token can be used for; by default it is an access token.
+	CreateToken(username string, role string, duration time.Duration, opts ...Option) (string, *Payload, error)
+	// VerifyToken checks if the token is valid and returns the payload stored in it.
+	VerifyToken(token string) (*Payload, error)
+}
@@ -0,0 +1,40 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount is how many recovery codes a user gets when enrolling.
+	RecoveryCodeCount = 10
+	// 10 random bytes give 16 base32 characters, written as four groups of four.
+	recoveryCodeSize = 10
+)
+
+// GenerateRecoveryCodes returns fresh single use codes for when the authenticator is lost.
+// Only their hashes should be stored; the codes themselves are shown to the user once.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash stored for a recovery code. The codes carry 80
+// bits of randomness, so a plain SHA-256 is enough and lets them be looked up directly.
+// Dashes, spaces and case are ignored so the code can be typed back however it was copied.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
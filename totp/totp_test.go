@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The SHA1 test vectors from RFC 6238 appendix B, truncated to 6 digits.
+func TestGenerateCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, want := range vectors {
+		got, err := GenerateCode(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, want, got, unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	step, err := Validate(secret, code, now)
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// One step of clock drift either way is fine.
+	step, err = Validate(secret, code, now.Add(Period))
+	require.NoError(t, err)
+	require.Equal(t, Step(now), step)
+
+	// Further away is not.
+	_, err = Validate(secret, code, now.Add(3*Period))
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	_, err = Validate(secret, "12345", now)
+	require.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("SimpleBank", "alice", "JBSWY3DPEHPK3PXP")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/SimpleBank:alice?"))
+	require.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	require.Contains(t, uri, "issuer=SimpleBank")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	// Formatting doesn't change the hash.
+	code := codes[0]
+	require.Len(t, code, 19)
+	require.Equal(t, HashRecoveryCode(code), HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", " "))))
+	require.NotEqual(t, HashRecoveryCode(codes[0]), HashRecoveryCode(codes[1]))
+}
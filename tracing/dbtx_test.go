@@ -0,0 +1,15 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "CreateTransfer", queryName("-- name: CreateTransfer :one\nINSERT INTO transfers"))
+	require.Equal(t, "DeleteAccount", queryName("-- name: DeleteAccount :exec\nDELETE FROM accounts"))
+	// Hand written queries without the sqlc header still get a span name.
+	require.Equal(t, "query", queryName("SELECT 1"))
+	require.Equal(t, "query", queryName("-- name: "))
+}
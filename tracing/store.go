@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+)
+
+// Store decorates a db.Store with a span around each transaction. Individual
+// queries are traced by the DBTX wrapper instead.
+type Store struct {
+	db.Store
+}
+
+// NewStore wraps store so TransferTx runs inside its own span.
+func NewStore(store db.Store) db.Store {
+	return &Store{Store: store}
+}
+
+// TransferTx starts a span that parents the CreateTransfer, CreateEntry and AddAccountBalance query spans.
+func (store *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	ctx, span := Tracer().Start(ctx, "db.TransferTx")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int64("transfer.from_account_id", arg.FromAccountID),
+		attribute.Int64("transfer.to_account_id", arg.ToAccountID),
+		attribute.Int64("transfer.amount", arg.Amount),
+	)
+
+	result, err := store.Store.TransferTx(ctx, arg)
+	recordError(span, err)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("transfer.id", result.Transfer.ID))
+	}
+	return result, err
+}
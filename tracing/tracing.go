@@ -0,0 +1,85 @@
+// Package tracing sets up OpenTelemetry tracing and provides the span wrappers
+// used around the db store and its queries.
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/techschool/simplebank/util"
+)
+
+// The instrumentation name every simplebank span is created under.
+const tracerName = "github.com/techschool/simplebank"
+
+// Tracer returns the tracer used for simplebank spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Setup installs the global tracer provider and the W3C traceparent/baggage propagators.
+// Spans go to the OTLP/HTTP endpoint in OTEL_EXPORTER_OTLP_ENDPOINT, or to stdout when
+// it is empty so local runs still show traces. The returned function flushes and
+// stops the provider and should be called on shutdown.
+func Setup(ctx context.Context, config util.Config) (shutdown func(context.Context) error, err error) {
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", config.OTelServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Follow the caller's sampling decision if there is one, otherwise sample everything.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config util.Config) (sdktrace.SpanExporter, error) {
+	if config.OTelExporterEndpoint == "" {
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.OTelExporterEndpoint)}
+	if config.OTelExporterInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// recordError marks the span as failed. No rows is an expected result
+// rather than a failure, so it is left alone like a nil error.
+func recordError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
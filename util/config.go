@@ -1,13 +1,109 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 // Stores all configs for the applications.
 type Config struct {
 	// Mapstructure is an Unmarshalling commanded used to map env vars to the struct vars.
 	DBDriver      string `mapstructure:"DB_DRIVER"`
 	DBSource      string `mapstructure:"DB_SOURCE"`
+	// Connection pool settings applied to the *sql.DB.
+	// Most connections open at once. Zero means no limit.
+	DBMaxOpenConns    int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	// Most idle connections kept for reuse, capped at DB_MAX_OPEN_CONNS. Zero keeps none,
+	// so every query opens a new connection.
+	DBMaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	// How long a connection is used before it is closed and replaced. Zero means forever.
+	DBConnMaxLifetime time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	// How long a connection can sit idle before it is closed. Zero means forever.
+	DBConnMaxIdleTime time.Duration `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	// Postgres aborts any statement running longer than this. Zero disables it.
+	DBStatementTimeout time.Duration `mapstructure:"DB_STATEMENT_TIMEOUT"`
+	// How many times to retry the startup ping, and the first wait between tries (doubled each time).
+	DBConnectRetries int           `mapstructure:"DB_CONNECT_RETRIES"`
+	DBConnectBackoff time.Duration `mapstructure:"DB_CONNECT_BACKOFF"`
 	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	// Durations are written like 5s or 1m.
+	HTTPReadTimeout  time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	// Rate limits are written as <count>/<unit>, e.g. 100/m. RATE_LIMIT_ROUTES overrides the
+	// default per route, e.g. "POST /transfers=20/m". The backend is memory or postgres.
+	RateLimitBackend string `mapstructure:"RATE_LIMIT_BACKEND"`
+	RateLimitDefault string `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitRoutes  string `mapstructure:"RATE_LIMIT_ROUTES"`
+	// Access tokens are signed with this key, which must be at least 32 characters.
+	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	// Lifetime of the tokens /oauth/token issues to OAuth2 clients.
+	OAuthAccessTokenDuration time.Duration `mapstructure:"OAUTH_ACCESS_TOKEN_DURATION"`
+	// After each failed login the next one has to wait LOGIN_BASE_DELAY, doubled per failure.
+	// Reaching LOGIN_MAX_FAILED_ATTEMPTS locks the user for LOGIN_LOCKOUT_DURATION.
+	LoginMaxFailedAttempts int32         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginBaseDelay         time.Duration `mapstructure:"LOGIN_BASE_DELAY"`
+	LoginLockoutDuration   time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	// The issuer shown in authenticator apps, and how long the second login step has
+	// to be finished in. Transfers above TOTP_TRANSFER_THRESHOLD (0 turns it off) need a code.
+	TOTPIssuer            string        `mapstructure:"TOTP_ISSUER"`
+	TOTPChallengeDuration time.Duration `mapstructure:"TOTP_CHALLENGE_DURATION"`
+	TOTPTransferThreshold int64         `mapstructure:"TOTP_TRANSFER_THRESHOLD"`
+	// Limits on cash withdrawals: per withdrawal, and in total over 24 hours. 0 means no limit.
+	WithdrawalMaxAmount  int64 `mapstructure:"WITHDRAWAL_MAX_AMOUNT"`
+	WithdrawalDailyLimit int64 `mapstructure:"WITHDRAWAL_DAILY_LIMIT"`
+	// How often the worker looks for due scheduled transfers. A schedule is paused after
+	// SCHEDULED_TRANSFER_MAX_FAILURES failed runs in a row, each retried after SCHEDULED_TRANSFER_RETRY_DELAY.
+	SchedulerPollInterval        time.Duration `mapstructure:"SCHEDULER_POLL_INTERVAL"`
+	ScheduledTransferMaxFailures int32         `mapstructure:"SCHEDULED_TRANSFER_MAX_FAILURES"`
+	ScheduledTransferRetryDelay  time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_DELAY"`
+	// Background job workers. A taken job is hidden from other workers for JOBS_VISIBILITY_TIMEOUT.
+	// Failed jobs are retried after JOBS_RETRY_BACKOFF, doubled per attempt up to JOBS_MAX_RETRY_BACKOFF.
+	JobsConcurrency       int           `mapstructure:"JOBS_CONCURRENCY"`
+	JobsPollInterval      time.Duration `mapstructure:"JOBS_POLL_INTERVAL"`
+	JobsVisibilityTimeout time.Duration `mapstructure:"JOBS_VISIBILITY_TIMEOUT"`
+	JobsRetryBackoff      time.Duration `mapstructure:"JOBS_RETRY_BACKOFF"`
+	JobsMaxRetryBackoff   time.Duration `mapstructure:"JOBS_MAX_RETRY_BACKOFF"`
+	// Where the outbox relay publishes domain events: stdout or nats. NATS subjects are
+	// <OUTBOX_SUBJECT_PREFIX>.<event type>, and a JetStream stream has to capture them.
+	OutboxPublisher     string        `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxNATSURL       string        `mapstructure:"OUTBOX_NATS_URL"`
+	// Optional NATS credentials: a token, a .creds file or an NKey seed file. A user and
+	// password can go in the URL instead. tls:// URLs are encrypted, and OUTBOX_NATS_CA_FILE
+	// trusts a private CA.
+	OutboxNATSToken     string        `mapstructure:"OUTBOX_NATS_TOKEN"`
+	OutboxNATSCredsFile string        `mapstructure:"OUTBOX_NATS_CREDS_FILE"`
+	OutboxNATSNKeyFile  string        `mapstructure:"OUTBOX_NATS_NKEY_FILE"`
+	OutboxNATSCAFile    string        `mapstructure:"OUTBOX_NATS_CA_FILE"`
+	OutboxSubjectPrefix string        `mapstructure:"OUTBOX_SUBJECT_PREFIX"`
+	OutboxPollInterval  time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OutboxBatchSize     int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+	// Webhook deliveries are tried WEBHOOK_MAX_ATTEMPTS times, backing off like other jobs,
+	// and each attempt gets WEBHOOK_TIMEOUT to answer.
+	WebhookMaxAttempts int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookTimeout     time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	// Browser pages allowed to open /ws, as comma separated origins like https://bank.example.com.
+	// Browsers can't send a token on the handshake, so they get a ticket from POST /ws/ticket
+	// first, which is good for WS_TICKET_DURATION.
+	WebSocketAllowedOrigins string        `mapstructure:"WS_ALLOWED_ORIGINS"`
+	WebSocketTicketDuration time.Duration `mapstructure:"WS_TICKET_DURATION"`
+	// Monthly statements are generated for last month's active accounts, checked every
+	// STATEMENT_POLL_INTERVAL, and stored as files under BLOB_STORAGE_DIR.
+	StatementPollInterval time.Duration `mapstructure:"STATEMENT_POLL_INTERVAL"`
+	BlobStorageDir        string        `mapstructure:"BLOB_STORAGE_DIR"`
+	// Hex encoded 32 byte Ed25519 seed that signs ledger checkpoints.
+	LedgerSigningKey string `mapstructure:"LEDGER_SIGNING_KEY"`
+	// How long in-flight requests get to finish after SIGTERM before the server is closed.
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// Log level can be debug, info, warn or error. Format can be text or json.
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+	// OTLP/HTTP collector address (host:port). Traces go to stdout when this is empty.
+	OTelServiceName      string `mapstructure:"OTEL_SERVICE_NAME"`
+	OTelExporterEndpoint string `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelExporterInsecure bool   `mapstructure:"OTEL_EXPORTER_OTLP_INSECURE"`
 }
 
 // LoadCOnfig will read configs from a file or environment variables.
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Context keys are unexported struct types so they cannot collide with keys
+// set by other packages.
+type requestIDKey struct{}
+type loggerKey struct{}
+
+// NewLogger builds a structured logger from the LOG_LEVEL and LOG_FORMAT configs.
+// Unknown levels fall back to info and anything other than "json" is written as text.
+func NewLogger(config Config) *slog.Logger {
+	return newLogger(os.Stdout, config.LogLevel, config.LogFormat)
+}
+
+func newLogger(w io.Writer, level string, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLogLevel(level)}
+
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+func parseLogLevel(level string) slog.Level {
+	var l slog.Level
+	// UnmarshalText understands debug, info, warn and error (case insensitive).
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// ContextWithRequestID stores the request ID so the db layer can tag its logs with it.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID, or an empty string outside of a request.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ContextWithLogger stores a request scoped logger in the context.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the request scoped logger, falling back to the default logger.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
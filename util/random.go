@@ -1,6 +1,7 @@
 package util
 
 import(
+	"fmt"
 	"math/rand"
 	"strings"
 	"time"
@@ -40,9 +41,10 @@ func RandomOwner() string {
 	return RandomString(6)
 }
 
-// Random money generator.
+// Random money generator. It starts at 100, so a test account can always make a
+// few small transfers without being overdrawn.
 func RandomMoney() int64 {
-	return RandomInt(0, 1000)
+	return RandomInt(100, 1000)
 }
 
 // Random Currency
@@ -52,7 +54,7 @@ func RandomCurrency() string {
 	return currencies[rand.Intn(n)]
 }
 
-
-
-
-
+// RandomEmail generates a random email address.
+func RandomEmail() string {
+	return fmt.Sprintf("%s@email.com", RandomString(6))
+}
@@ -0,0 +1,14 @@
+package util
+
+// Roles a user can have. Every new user starts as a depositor. Bankers can see and
+// freeze any account, and admins can do everything bankers can and manage users too.
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+	AdminRole     = "admin"
+)
+
+// IsPrivilegedRole reports whether the role may act on accounts it doesn't own.
+func IsPrivilegedRole(role string) bool {
+	return role == BankerRole || role == AdminRole
+}
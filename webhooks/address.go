@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for webhook URLs that point into the bank's own
+// network, so users can't make the server call, or scan, internal services.
+var ErrForbiddenAddress = errors.New("webhook address is not a public address")
+
+// Carrier-grade NAT addresses, shared inside a provider's network like private ones.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicAddress reports whether deliveries may be sent to addr: anything but loopback,
+// link-local (cloud metadata services live there), private, shared, multicast and
+// unspecified addresses.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsPrivate() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// CheckURL refuses URLs whose host is plainly internal, an IP address that isn't public
+// or localhost, so the mistake shows when the webhook is created. Host names are only
+// resolved when a delivery is sent, where dialPublicOnly checks every address.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddress(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// dialPublicOnly is a net.Dialer Control hook. It runs after the host name has been
+// resolved, for each address tried, so a name that resolves, or is later rebound, to an
+// internal address is refused too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/jobs"
+)
+
+// How much of a response body is read before the connection is dropped. The body
+// itself isn't kept.
+const maxResponseBody = 64 << 10
+
+// DeliverPayload names the delivery a job sends.
+type DeliverPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// Deliver sends one webhook delivery. Its retries and their backoff come from the jobs queue.
+var Deliver = jobs.NewKind[DeliverPayload]("webhook.deliver")
+
+// DeliveryJob returns the job that sends a delivery, tried up to maxAttempts times.
+func DeliveryJob(delivery db.WebhookDelivery, maxAttempts int32) db.JobRequest {
+	job := Deliver.New(DeliverPayload{DeliveryID: delivery.ID})
+	job.MaxAttempts = maxAttempts
+	return job
+}
+
+// NewHTTPClient returns the client deliveries are sent with. Redirects aren't
+// followed, so a 3xx counts as a failure like any other non 2xx answer. It only
+// connects to public addresses, see IsPublicAddress.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return newHTTPClient(timeout, dialPublicOnly)
+}
+
+// newHTTPClient is NewHTTPClient with the dialer's Control hook given, so tests can
+// deliver to a local server.
+func newHTTPClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be dialed instead of the endpoint, getting around the check.
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// DeliverHandler sends a delivery and logs the attempt. It returns an error while the
+// delivery has attempts left, so the job is retried, and a permanent one after the last.
+func DeliverHandler(store db.Store, client *http.Client, maxAttempts int32) func(ctx context.Context, payload DeliverPayload) error {
+	return func(ctx context.Context, payload DeliverPayload) error {
+		delivery, err := store.GetWebhookDelivery(ctx, payload.DeliveryID)
+		if err != nil {
+			return err
+		}
+		// Already sent, or failed and waiting for a replay, which queues its own job.
+		if delivery.Status != db.WebhookDeliveryPending {
+			return nil
+		}
+
+		subscription, err := store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		arg := db.RecordWebhookAttemptTxParams{
+			DeliveryID:  delivery.ID,
+			MaxAttempts: maxAttempts,
+		}
+		if subscription.Active {
+			arg = send(ctx, client, subscription, delivery, arg)
+		} else {
+			arg.Error = "subscription is no longer active"
+			arg.MaxAttempts = 0
+		}
+
+		delivery, err = store.RecordWebhookAttemptTx(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		switch delivery.Status {
+		case db.WebhookDeliverySucceeded:
+			return nil
+		case db.WebhookDeliveryFailed:
+			return jobs.Permanent(fmt.Errorf("webhook delivery %d failed: %s", delivery.ID, arg.Error))
+		default:
+			return fmt.Errorf("webhook delivery %d failed: %s", delivery.ID, arg.Error)
+		}
+	}
+}
+
+// send makes one attempt and fills in its outcome.
+func send(ctx context.Context, client *http.Client, subscription db.WebhookSubscription, delivery db.WebhookDelivery, arg db.RecordWebhookAttemptTxParams) db.RecordWebhookAttemptTxParams {
+	start := time.Now()
+	defer func() {
+		arg.Duration = time.Since(start)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		arg.Error = err.Error()
+		return arg
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SimpleBank-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, now, delivery.Payload))
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		arg.Error = err.Error()
+		return arg
+	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(rsp.Body, maxResponseBody))
+
+	arg.StatusCode = sql.NullInt32{Int32: int32(rsp.StatusCode), Valid: true}
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		arg.Succeeded = true
+	} else {
+		arg.Error = "endpoint answered " + rsp.Status
+	}
+	return arg
+}
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/events"
+)
+
+// payload is the body of a delivery.
+type payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher is an events.Publisher that turns each event into a delivery for every
+// matching subscription of the users it concerns, and queues a job to send each one.
+type Dispatcher struct {
+	store       db.Store
+	maxAttempts int32
+}
+
+// NewDispatcher creates a dispatcher whose deliveries are tried up to maxAttempts times.
+func NewDispatcher(store db.Store, maxAttempts int32) *Dispatcher {
+	return &Dispatcher{store: store, maxAttempts: maxAttempts}
+}
+
+func (dispatcher *Dispatcher) Publish(ctx context.Context, event events.Event) error {
+	owners, err := event.Owners()
+	if err != nil || len(owners) == 0 {
+		// Not about a user's account, so nobody to tell.
+		return nil
+	}
+
+	name := events.Name(event.Type)
+	subscriptions, err := dispatcher.store.ListMatchingWebhookSubscriptions(ctx, db.ListMatchingWebhookSubscriptionsParams{
+		Owners:    owners,
+		EventType: name,
+	})
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	// Each owner gets their own body, without the balances of other users' accounts.
+	byOwner := map[string][]int64{}
+	for _, subscription := range subscriptions {
+		byOwner[subscription.Owner] = append(byOwner[subscription.Owner], subscription.ID)
+	}
+
+	for _, owner := range owners {
+		ids := byOwner[owner]
+		if len(ids) == 0 {
+			continue
+		}
+
+		data, err := event.DataFor(owner)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(payload{
+			ID:        event.ID,
+			Type:      name,
+			CreatedAt: event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = dispatcher.store.CreateWebhookDeliveriesTx(ctx, db.CreateWebhookDeliveriesTxParams{
+			EventID:         event.ID,
+			EventType:       name,
+			Payload:         body,
+			SubscriptionIDs: ids,
+			Job: func(delivery db.WebhookDelivery) db.JobRequest {
+				return DeliveryJob(delivery, dispatcher.maxAttempts)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dispatcher *Dispatcher) Close() error {
+	return nil
+}
@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	db "github.com/techschool/simplebank/db/sqlc"
+	"github.com/techschool/simplebank/events"
+	"github.com/techschool/simplebank/jobs"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":1}`)
+	now := time.Unix(1700000000, 0)
+
+	// Worked out with: printf '1700000000.{"id":1}' | openssl dgst -sha256 -hmac whsec_test
+	signature := Sign(secret, now, body)
+	require.Equal(t, "v1=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8", signature)
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	require.NoError(t, Verify(secret, timestamp, signature, body, 5*time.Minute, now.Add(time.Minute)))
+
+	require.ErrorIs(t, Verify("whsec_other", timestamp, signature, body, 5*time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, timestamp, signature, []byte(`{"id":2}`), 5*time.Minute, now), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, "soon", signature, body, 5*time.Minute, now), ErrInvalidSignature)
+	// A signature captured earlier can't be replayed after the tolerance.
+	require.ErrorIs(t, Verify(secret, timestamp, signature, body, 5*time.Minute, now.Add(time.Hour)), ErrTimestampExpired)
+}
+
+// fakeStore keeps one subscription and the deliveries made for it.
+type fakeStore struct {
+	db.Store
+	subscription db.WebhookSubscription
+	deliveries   map[int64]db.WebhookDelivery
+	attempts     []db.RecordWebhookAttemptTxParams
+	jobs         []db.JobRequest
+}
+
+func newFakeStore(url string) *fakeStore {
+	return &fakeStore{
+		subscription: db.WebhookSubscription{
+			ID:         1,
+			Owner:      "alice",
+			Url:        url,
+			EventTypes: []string{"transfer.posted"},
+			Secret:     "whsec_test",
+			Active:     true,
+		},
+		deliveries: map[int64]db.WebhookDelivery{},
+	}
+}
+
+func (store *fakeStore) ListMatchingWebhookSubscriptions(ctx context.Context, arg db.ListMatchingWebhookSubscriptionsParams) ([]db.WebhookSubscription, error) {
+	for _, owner := range arg.Owners {
+		if owner == store.subscription.Owner && arg.EventType == store.subscription.EventTypes[0] {
+			return []db.WebhookSubscription{store.subscription}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (store *fakeStore) CreateWebhookDeliveriesTx(ctx context.Context, arg db.CreateWebhookDeliveriesTxParams) ([]db.WebhookDelivery, error) {
+	var created []db.WebhookDelivery
+	for _, id := range arg.SubscriptionIDs {
+		delivery := db.WebhookDelivery{
+			ID:             int64(len(store.deliveries) + 1),
+			SubscriptionID: id,
+			EventID:        arg.EventID,
+			EventType:      arg.EventType,
+			Payload:        arg.Payload,
+			Status:         db.WebhookDeliveryPending,
+		}
+		store.deliveries[delivery.ID] = delivery
+		store.jobs = append(store.jobs, arg.Job(delivery))
+		created = append(created, delivery)
+	}
+	return created, nil
+}
+
+func (store *fakeStore) GetWebhookDelivery(ctx context.Context, id int64) (db.WebhookDelivery, error) {
+	delivery, ok := store.deliveries[id]
+	if !ok {
+		return delivery, sql.ErrNoRows
+	}
+	return delivery, nil
+}
+
+func (store *fakeStore) GetWebhookSubscription(ctx context.Context, id int64) (db.WebhookSubscription, error) {
+	return store.subscription, nil
+}
+
+func (store *fakeStore) RecordWebhookAttemptTx(ctx context.Context, arg db.RecordWebhookAttemptTxParams) (db.WebhookDelivery, error) {
+	store.attempts = append(store.attempts, arg)
+
+	delivery := store.deliveries[arg.DeliveryID]
+	delivery.Attempts++
+	delivery.LastStatusCode = arg.StatusCode
+	delivery.LastError = arg.Error
+	switch {
+	case arg.Succeeded:
+		delivery.Status = db.WebhookDeliverySucceeded
+	case delivery.Attempts >= arg.MaxAttempts:
+		delivery.Status = db.WebhookDeliveryFailed
+	}
+	store.deliveries[delivery.ID] = delivery
+	return delivery, nil
+}
+
+func transferEvent() events.Event {
+	return events.Event{
+		ID:         42,
+		Type:       db.EventTransferPosted,
+		OccurredAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:       json.RawMessage(`{"from_account":{"id":1,"owner":"alice","balance":90},"to_account":{"id":2,"owner":"bob","balance":510}}`),
+	}
+}
+
+func TestDispatcherAndDelivery(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan request, 10)
+	status := http.StatusOK
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	defer endpoint.Close()
+
+	store := newFakeStore(endpoint.URL)
+	dispatcher := NewDispatcher(store, 2)
+
+	// Nobody asked for account events.
+	require.NoError(t, dispatcher.Publish(context.Background(), events.Event{
+		ID:   41,
+		Type: db.EventAccountCreated,
+		Data: json.RawMessage(`{"owner":"alice"}`),
+	}))
+	require.Empty(t, store.deliveries)
+
+	require.NoError(t, dispatcher.Publish(context.Background(), transferEvent()))
+	require.Len(t, store.deliveries, 1)
+	require.Len(t, store.jobs, 1)
+	require.Equal(t, Deliver.Name, store.jobs[0].Kind)
+	require.Equal(t, int32(2), store.jobs[0].MaxAttempts)
+
+	delivery := store.deliveries[1]
+	require.Equal(t, "transfer.posted", delivery.EventType)
+	require.JSONEq(t, `{
+		"id": 42,
+		"type": "transfer.posted",
+		"created_at": "2024-01-02T03:04:05.000000Z",
+		"data": {"from_account": {"id": 1, "owner": "alice", "balance": 90}, "to_account": {"id": 2, "owner": "bob"}}
+	}`, string(delivery.Payload))
+
+	handler := DeliverHandler(store, newHTTPClient(time.Second, nil), 2)
+
+	// The endpoint fails, so the job is retried.
+	status = http.StatusInternalServerError
+	err := handler(context.Background(), DeliverPayload{DeliveryID: 1})
+	require.Error(t, err)
+	require.False(t, jobs.IsPermanent(err))
+	require.Equal(t, db.WebhookDeliveryPending, store.deliveries[1].Status)
+	require.Equal(t, int32(500), store.attempts[0].StatusCode.Int32)
+
+	got := <-requests
+	require.Equal(t, delivery.Payload, json.RawMessage(got.body))
+	require.Equal(t, "transfer.posted", got.header.Get(HeaderEvent))
+	require.Equal(t, "1", got.header.Get(HeaderDelivery))
+	require.NoError(t, Verify("whsec_test", got.header.Get(HeaderTimestamp), got.header.Get(HeaderSignature), got.body, time.Minute, time.Now()))
+
+	// The second failure is the last attempt.
+	err = handler(context.Background(), DeliverPayload{DeliveryID: 1})
+	require.True(t, jobs.IsPermanent(err))
+	require.Equal(t, db.WebhookDeliveryFailed, store.deliveries[1].Status)
+	<-requests
+
+	// A replay resets the delivery, and this time it goes through.
+	replayed := store.deliveries[1]
+	replayed.Status = db.WebhookDeliveryPending
+	replayed.Attempts = 0
+	store.deliveries[1] = replayed
+	status = http.StatusNoContent
+	require.NoError(t, handler(context.Background(), DeliverPayload{DeliveryID: 1}))
+	require.Equal(t, db.WebhookDeliverySucceeded, store.deliveries[1].Status)
+	<-requests
+
+	// Jobs left over for a finished delivery don't send it again.
+	require.NoError(t, handler(context.Background(), DeliverPayload{DeliveryID: 1}))
+	require.Len(t, store.attempts, 3)
+	require.Empty(t, requests)
+}
+
+func TestDeliveryToInactiveSubscription(t *testing.T) {
+	store := newFakeStore("http://127.0.0.1:1")
+	store.subscription.Active = false
+	store.deliveries[1] = db.WebhookDelivery{ID: 1, SubscriptionID: 1, Status: db.WebhookDeliveryPending}
+
+	err := DeliverHandler(store, newHTTPClient(time.Second, nil), 5)(context.Background(), DeliverPayload{DeliveryID: 1})
+	require.True(t, jobs.IsPermanent(err))
+	require.Equal(t, db.WebhookDeliveryFailed, store.deliveries[1].Status)
+	require.False(t, store.attempts[0].StatusCode.Valid)
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::":    true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"fd00::1":              false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::":                   false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+		"224.0.0.1":            false,
+	} {
+		require.Equal(t, public, IsPublicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	require.NoError(t, CheckURL("https://example.com/hooks"))
+	require.NoError(t, CheckURL("https://93.184.216.34/hooks"))
+	for _, rawURL := range []string{
+		"http://localhost:8080/",
+		"http://LOCALHOST./",
+		"http://api.localhost/",
+		"http://127.0.0.1:5432/",
+		"http://[::1]/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.7/",
+	} {
+		require.ErrorIs(t, CheckURL(rawURL), ErrForbiddenAddress, rawURL)
+	}
+}
+
+// The delivery client refuses to connect to internal addresses, whatever the URL says.
+func TestHTTPClientRefusesInternalAddress(t *testing.T) {
+	called := false
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer endpoint.Close()
+
+	_, err := NewHTTPClient(time.Second).Post(endpoint.URL, "application/json", nil)
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+	require.False(t, called)
+}